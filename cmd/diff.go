@@ -0,0 +1,272 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sfuruya0612/apf/internal/store"
+	"github.com/urfave/cli/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// diffCollections are the collections priced by the instance hour.
+// The tiered prices of the other collections can not be compared by the On-Demand price.
+var diffCollections = []string{"ec2", "rds", "elasticache"}
+
+var DiffCommand = &cli.Command{
+	Name:      "diff",
+	Usage:     "Show SKUs added, removed or repriced between snapshots",
+	ArgsUsage: "<ec2|rds|elasticache>",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "from",
+			Usage: "Specify a snapshot ID to compare from (default: the snapshot before the latest)",
+		},
+		&cli.StringFlag{
+			Name:  "to",
+			Usage: "Specify a snapshot ID to compare to (default: the latest snapshot)",
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		collection, err := getCollectionArg(ctx)
+		if err != nil {
+			return err
+		}
+
+		if !contains(diffCollections, collection) {
+			return fmt.Errorf("Unsupported collection for diff: %s (e.g. %s)", collection, strings.Join(diffCollections, ", "))
+		}
+
+		return diff(getStoreURI(ctx), collection, ctx.String("from"), ctx.String("to"))
+	},
+}
+
+// skuPrice is the On-Demand price of a SKU in a snapshot.
+type skuPrice struct {
+	sku          string
+	region       string
+	osEngine     string
+	instanceType string
+	price        string
+}
+
+type skuDiff struct {
+	change string
+	old    *skuPrice
+	new    *skuPrice
+}
+
+func diff(storeUri, collection, from, to string) error {
+	st, err := store.Open(storeUri)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	ctx := context.Background()
+
+	ids, err := getSnapshots(st, ctx, collection)
+	if err != nil {
+		return err
+	}
+
+	if to == "" {
+		if len(ids) < 1 {
+			return fmt.Errorf("No snapshot of %s collection", collection)
+		}
+		to = ids[0]
+	}
+
+	if from == "" {
+		for i, id := range ids {
+			if id == to && i+1 < len(ids) {
+				from = ids[i+1]
+			}
+		}
+		if from == "" {
+			return fmt.Errorf("No snapshot of %s collection before %s", collection, to)
+		}
+	}
+
+	for _, id := range []string{from, to} {
+		if !contains(ids, id) {
+			return fmt.Errorf("Snapshot %s of %s collection is not found", id, collection)
+		}
+	}
+
+	oldPrices, err := getSkuPrices(st, ctx, collection, from)
+	if err != nil {
+		return err
+	}
+
+	newPrices, err := getSkuPrices(st, ctx, collection, to)
+	if err != nil {
+		return err
+	}
+
+	return printDiff(compareSkuPrices(oldPrices, newPrices))
+}
+
+func getSkuPrices(st store.Store, ctx context.Context, collection, id string) (map[string]*skuPrice, error) {
+	opt := &store.FindOptions{
+		Projection: []string{
+			"sku",
+			"region",
+			"product.attributes.osengine",
+			"product.attributes.instancetype",
+			"ondemandpriceperusd",
+		},
+	}
+
+	// Snapshots taken before the SKU was stored can not be compared.
+	results, err := st.Find(ctx, fmt.Sprintf("%s_%s", collection, id), bson.M{"sku": bson.M{"$nin": bson.A{nil, ""}}}, opt)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to find snapshot %s: %w", id, err)
+	}
+
+	prices := make(map[string]*skuPrice, len(results))
+	for _, result := range results {
+		p := &skuPrice{}
+		p.sku, _ = result["sku"].(string)
+		p.region, _ = result["region"].(string)
+		p.price, _ = result["ondemandpriceperusd"].(string)
+
+		if product, ok := result["product"].(bson.M); ok {
+			if attr, ok := product["attributes"].(bson.M); ok {
+				p.osEngine, _ = attr["osengine"].(string)
+				p.instanceType, _ = attr["instancetype"].(string)
+			}
+		}
+
+		prices[p.sku] = p
+	}
+
+	return prices, nil
+}
+
+func compareSkuPrices(oldPrices, newPrices map[string]*skuPrice) []*skuDiff {
+	var diffs []*skuDiff
+
+	for sku, n := range newPrices {
+		o, ok := oldPrices[sku]
+		if !ok {
+			diffs = append(diffs, &skuDiff{change: "added", new: n})
+			continue
+		}
+
+		if !samePrice(o.price, n.price) {
+			diffs = append(diffs, &skuDiff{change: "repriced", old: o, new: n})
+		}
+	}
+
+	for sku, o := range oldPrices {
+		if _, ok := newPrices[sku]; !ok {
+			diffs = append(diffs, &skuDiff{change: "removed", old: o})
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		if diffs[i].change != diffs[j].change {
+			return diffs[i].change < diffs[j].change
+		}
+		return diffs[i].sku() < diffs[j].sku()
+	})
+
+	return diffs
+}
+
+func samePrice(a, b string) bool {
+	fa, errA := strconv.ParseFloat(a, 64)
+	fb, errB := strconv.ParseFloat(b, 64)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+
+	return fa == fb
+}
+
+func (d *skuDiff) sku() string {
+	return d.price().sku
+}
+
+// price returns the newer price of the SKU.
+func (d *skuDiff) price() *skuPrice {
+	if d.new != nil {
+		return d.new
+	}
+	return d.old
+}
+
+func (d *skuDiff) percentage() string {
+	if d.old == nil || d.new == nil {
+		return "N/A"
+	}
+
+	o, err := strconv.ParseFloat(d.old.price, 64)
+	if err != nil || o == 0 {
+		return "N/A"
+	}
+
+	n, err := strconv.ParseFloat(d.new.price, 64)
+	if err != nil {
+		return "N/A"
+	}
+
+	return fmt.Sprintf("%+.2f", (n-o)/o*100)
+}
+
+func printDiff(diffs []*skuDiff) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, ' ', 0)
+
+	header := []string{
+		"Change",
+		"SKU",
+		"Region",
+		"OS/Engine",
+		"InstanceType",
+		"OldPrice(USD/hour)",
+		"NewPrice(USD/hour)",
+		"Change(%)",
+	}
+
+	if _, err := fmt.Fprintln(w, strings.Join(header, "\t")); err != nil {
+		return fmt.Errorf("Failed to print header: %w", err)
+	}
+
+	for _, d := range diffs {
+		oldPrice, newPrice := "-", "-"
+		if d.old != nil {
+			oldPrice = d.old.price
+		}
+		if d.new != nil {
+			newPrice = d.new.price
+		}
+
+		p := d.price()
+		fields := []string{
+			d.change,
+			p.sku,
+			p.region,
+			p.osEngine,
+			p.instanceType,
+			oldPrice,
+			newPrice,
+			d.percentage(),
+		}
+
+		if _, err := fmt.Fprintln(w, strings.Join(fields, "\t")); err != nil {
+			return fmt.Errorf("Failed to print result: %w", err)
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("Failed to flush: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSamePrice(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"0.0960000000", "0.096", true},
+		{"0.096", "0.0960000001", false},
+		{"0", "0.0000000000", true},
+		// Prices that are not numbers are compared as strings.
+		{"", "", true},
+		{"", "0.096", false},
+		{"N/A", "N/A", true},
+	}
+
+	for _, tt := range tests {
+		if got := samePrice(tt.a, tt.b); got != tt.want {
+			t.Errorf("samePrice(%q, %q): got %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCompareSkuPrices(t *testing.T) {
+	oldPrices := map[string]*skuPrice{
+		"A": {sku: "A", price: "0.1000000000"},
+		"B": {sku: "B", price: "0.2000000000"},
+		"C": {sku: "C", price: "0.3000000000"},
+		"D": {sku: "D", price: "0.4000000000"},
+	}
+	newPrices := map[string]*skuPrice{
+		// The same price in another format is not repriced.
+		"A": {sku: "A", price: "0.1"},
+		"B": {sku: "B", price: "0.1800000000"},
+		"E": {sku: "E", price: "0.5000000000"},
+		"D": {sku: "D", price: "0.5000000000"},
+	}
+
+	diffs := compareSkuPrices(oldPrices, newPrices)
+
+	var got [][]string
+	for _, d := range diffs {
+		got = append(got, []string{d.change, d.sku(), d.percentage()})
+	}
+
+	want := [][]string{
+		{"added", "E", "N/A"},
+		{"removed", "C", "N/A"},
+		{"repriced", "B", "-10.00"},
+		{"repriced", "D", "+25.00"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
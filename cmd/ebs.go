@@ -0,0 +1,328 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sfuruya0612/apf/internal/aws"
+	"github.com/urfave/cli/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// gp3 includes the baseline IOPS and throughput in the storage price.
+const (
+	gp3BaselineIops       = 3000
+	gp3BaselineThroughput = 125
+)
+
+var ebsCommand = &cli.Command{
+	Name:  "ebs",
+	Usage: "Get EBS volume pricing and estimate the monthly cost",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "volume-type",
+			Aliases: []string{"v"},
+			Value:   "gp3",
+			Usage:   "Specify a valid volume type (e.g. gp2, gp3, io1, io2, st1, sc1)",
+		},
+		&cli.Float64Flag{
+			Name:  "size",
+			Value: 100,
+			Usage: "Specify the volume size in GiB",
+		},
+		&cli.Float64Flag{
+			Name:  "iops",
+			Usage: "Specify the provisioned IOPS of gp3, io1 and io2 (default: 3000 for gp3)",
+		},
+		&cli.Float64Flag{
+			Name:  "throughput",
+			Usage: "Specify the provisioned throughput of gp3 in MiB/s (default: 125)",
+		},
+		&cli.Float64Flag{
+			Name:  "snapshot-size",
+			Usage: "Specify the snapshot storage size in GiB",
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		return getEbsPrice(ctx)
+	},
+}
+
+// ebsVolume is the volume to estimate the monthly cost.
+type ebsVolume struct {
+	volumeType   string
+	size         float64
+	iops         float64
+	throughput   float64
+	snapshotSize float64
+}
+
+// ebsRow is the prices of the region in USD, and the monthly cost of the volume.
+type ebsRow struct {
+	Region            string  `json:"region" yaml:"region"`
+	VolumeType        string  `json:"volumeType" yaml:"volumeType"`
+	StoragePrice      float64 `json:"storagePrice" yaml:"storagePrice"`
+	IopsPrice         float64 `json:"iopsPrice,omitempty" yaml:"iopsPrice,omitempty"`
+	ThroughputPrice   float64 `json:"throughputPrice,omitempty" yaml:"throughputPrice,omitempty"`
+	SnapshotPrice     float64 `json:"snapshotPrice" yaml:"snapshotPrice"`
+	StorageMonthly    float64 `json:"storageMonthly" yaml:"storageMonthly"`
+	IopsMonthly       float64 `json:"iopsMonthly" yaml:"iopsMonthly"`
+	ThroughputMonthly float64 `json:"throughputMonthly" yaml:"throughputMonthly"`
+	SnapshotMonthly   float64 `json:"snapshotMonthly" yaml:"snapshotMonthly"`
+	Monthly           float64 `json:"monthly" yaml:"monthly"`
+
+	hasVolume  bool
+	hasIops    bool
+	iops       []aws.PriceTier
+	throughput []aws.PriceTier
+}
+
+func getEbsPrice(ctx *cli.Context) error {
+	if ctx.String("term") != "ondemand" {
+		return fmt.Errorf("Unsupported term for ebs: %s", ctx.String("term"))
+	}
+
+	out, err := newOutput(ctx)
+	if err != nil {
+		return err
+	}
+
+	cond, err := getCondition(ctx)
+	if err != nil {
+		return err
+	}
+
+	volume, err := getEbsVolume(ctx)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{
+		"$or": bson.A{
+			bson.M{"product.attributes.volumeapiname": volume.volumeType},
+			bson.M{"product.productfamily": "Storage Snapshot"},
+		},
+	}
+
+	// A row is made of several products, so the products are not sorted nor limited by the store.
+	out.order, err = getRowOrder("ebs", cond)
+	if err != nil {
+		return err
+	}
+
+	results, err := findProducts(
+		getStoreURI(ctx),
+		"ec2",
+		cond,
+		filter,
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to find: %w", err)
+	}
+
+	rows, err := getEbsRows(results, volume)
+	if err != nil {
+		return err
+	}
+
+	if err := printEbs(rows, volume, out); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func getEbsVolume(ctx *cli.Context) (*ebsVolume, error) {
+	v := &ebsVolume{
+		volumeType:   ctx.String("volume-type"),
+		size:         ctx.Float64("size"),
+		iops:         ctx.Float64("iops"),
+		throughput:   ctx.Float64("throughput"),
+		snapshotSize: ctx.Float64("snapshot-size"),
+	}
+
+	if !contains(aws.EbsVolumeTypes, v.volumeType) {
+		return nil, fmt.Errorf("Unknown volume type: %s (e.g. %s)", v.volumeType, strings.Join(aws.EbsVolumeTypes, ", "))
+	}
+
+	if v.size <= 0 {
+		return nil, fmt.Errorf("Invalid size: %g GiB", v.size)
+	}
+
+	if v.iops < 0 || v.throughput < 0 || v.snapshotSize < 0 {
+		return nil, fmt.Errorf("Invalid IOPS, throughput or snapshot size: must not be negative")
+	}
+
+	switch v.volumeType {
+	case "gp3":
+		if !ctx.IsSet("iops") {
+			v.iops = gp3BaselineIops
+		}
+		if !ctx.IsSet("throughput") {
+			v.throughput = gp3BaselineThroughput
+		}
+	case "io1", "io2":
+		if v.throughput > 0 {
+			return nil, fmt.Errorf("Throughput is not provisioned for %s", v.volumeType)
+		}
+	default:
+		if v.iops > 0 || v.throughput > 0 {
+			return nil, fmt.Errorf("IOPS and throughput are not provisioned for %s", v.volumeType)
+		}
+	}
+
+	return v, nil
+}
+
+// getEbsRows groups the products by region.
+func getEbsRows(results []bson.M, volume *ebsVolume) ([]*ebsRow, error) {
+	rows := map[string]*ebsRow{}
+
+	for _, result := range results {
+		price, err := decodePrice(result)
+		if err != nil {
+			log.Printf("Skip product %v: %v", result["sku"], err)
+			continue
+		}
+
+		row, ok := rows[price.Region]
+		if !ok {
+			row = &ebsRow{Region: price.Region, VolumeType: volume.volumeType}
+			rows[price.Region] = row
+		}
+
+		if err := row.addPrice(price); err != nil {
+			log.Printf("Skip product %s: %v", price.Sku, err)
+			continue
+		}
+	}
+
+	var r []*ebsRow
+	for _, row := range rows {
+		// Snapshot prices of the regions without the volume type are not printed.
+		if !row.hasVolume {
+			continue
+		}
+
+		if err := row.estimate(volume); err != nil {
+			return nil, fmt.Errorf("Failed to estimate %s: %w", row.Region, err)
+		}
+		r = append(r, row)
+	}
+
+	sort.Slice(r, func(i, j int) bool {
+		return r[i].Region < r[j].Region
+	})
+
+	return r, nil
+}
+
+// sortPrice returns the monthly cost of the volume.
+func (r *ebsRow) sortPrice() float64 {
+	return r.Monthly
+}
+
+func (r *ebsRow) addPrice(price *aws.Price) error {
+	var p float64
+	if err := parseFloats([]string{price.OnDemandPricePerUSD}, &p); err != nil {
+		return err
+	}
+
+	switch aws.EbsUsage(price.Product.ProductFamily) {
+	case aws.EbsUsageStorage:
+		r.StoragePrice = p
+		r.hasVolume = true
+	case aws.EbsUsageIops:
+		// io2 has a product per tier.
+		for _, t := range price.OnDemandTiers {
+			if t.BeginRange == 0 {
+				r.IopsPrice = t.PricePerUnitUSD
+			}
+		}
+		r.iops = append(r.iops, price.OnDemandTiers...)
+		r.hasIops = true
+	case aws.EbsUsageThroughput:
+		r.ThroughputPrice = p
+		r.throughput = price.OnDemandTiers
+	case aws.EbsUsageSnapshot:
+		r.SnapshotPrice = p
+	default:
+		return fmt.Errorf("Unknown product family: %s", price.Product.ProductFamily)
+	}
+
+	return nil
+}
+
+func (r *ebsRow) estimate(volume *ebsVolume) error {
+	iops := volume.iops
+	throughput := volume.throughput
+	if volume.volumeType == "gp3" {
+		iops = math.Max(0, iops-gp3BaselineIops)
+		throughput = math.Max(0, throughput-gp3BaselineThroughput)
+	}
+
+	if iops > 0 && !r.hasIops {
+		return fmt.Errorf("IOPS price of %s is missing", volume.volumeType)
+	}
+
+	r.IopsMonthly = aws.TieredCostUSD(r.iops, iops)
+
+	// The throughput of gp3 may be priced per GiBps-mo.
+	if len(r.throughput) > 0 && strings.HasPrefix(r.throughput[0].Unit, "GiBps") {
+		throughput /= 1024
+	}
+	r.ThroughputMonthly = aws.TieredCostUSD(r.throughput, throughput)
+
+	r.StorageMonthly = volume.size * r.StoragePrice
+	r.SnapshotMonthly = volume.snapshotSize * r.SnapshotPrice
+	r.Monthly = r.StorageMonthly + r.IopsMonthly + r.ThroughputMonthly + r.SnapshotMonthly
+
+	return nil
+}
+
+func printEbs(rows []*ebsRow, volume *ebsVolume, out *output) error {
+	out.header = getEbsHeader()
+
+	for _, row := range rows {
+		out.add(formatEbs(row, volume), bson.M{}, row)
+	}
+
+	return out.render(os.Stdout)
+}
+
+func getEbsHeader() []string {
+	return []string{
+		"Region",
+		"VolumeType",
+		"Size(GiB)",
+		"IOPS",
+		"Throughput(MiB/s)",
+		"Snapshot(GiB)",
+		"Storage(USD/month)",
+		"IOPS(USD/month)",
+		"Throughput(USD/month)",
+		"Snapshot(USD/month)",
+		"Total(USD/month)",
+	}
+}
+
+func formatEbs(row *ebsRow, volume *ebsVolume) []string {
+	return []string{
+		na(row.Region),
+		na(row.VolumeType),
+		strconv.FormatFloat(volume.size, 'f', -1, 64),
+		strconv.FormatFloat(volume.iops, 'f', -1, 64),
+		strconv.FormatFloat(volume.throughput, 'f', -1, 64),
+		strconv.FormatFloat(volume.snapshotSize, 'f', -1, 64),
+		fmt.Sprintf("%.2f", row.StorageMonthly),
+		fmt.Sprintf("%.2f", row.IopsMonthly),
+		fmt.Sprintf("%.2f", row.ThroughputMonthly),
+		fmt.Sprintf("%.2f", row.SnapshotMonthly),
+		fmt.Sprintf("%.2f", row.Monthly),
+	}
+}
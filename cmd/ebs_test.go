@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"math"
+	"testing"
+
+	"github.com/sfuruya0612/apf/internal/aws"
+)
+
+func TestEbsEstimate(t *testing.T) {
+	// gp3 of us-east-1
+	row := &ebsRow{
+		StoragePrice:  0.08,
+		SnapshotPrice: 0.05,
+		hasIops:       true,
+		iops:          []aws.PriceTier{{PricePerUnitUSD: 0.005}},
+		throughput:    []aws.PriceTier{{Unit: "GiBps-mo", PricePerUnitUSD: 40.96}},
+	}
+
+	if err := row.estimate(&ebsVolume{volumeType: "gp3", size: 500, iops: 6000, throughput: 250, snapshotSize: 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 3000 IOPS and 125 MiB/s over the baseline
+	want := 500*0.08 + 3000*0.005 + 125.0/1024*40.96 + 100*0.05
+	if math.Abs(row.Monthly-want) > 1e-9 || row.sortPrice() != row.Monthly {
+		t.Errorf("got %v, want %v", row.Monthly, want)
+	}
+}
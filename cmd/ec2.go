@@ -2,14 +2,12 @@ package cmd
 
 import (
 	"fmt"
+	"log"
 	"os"
-	"strings"
-	"text/tabwriter"
 
-	"github.com/sfuruya0612/apf/internal/utils"
+	"github.com/sfuruya0612/apf/internal/aws"
 	"github.com/urfave/cli/v2"
 	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 var ec2Command = &cli.Command{
@@ -40,6 +38,23 @@ var ec2Command = &cli.Command{
 			Value:   "NA",
 			Usage:   "Specify a valid preInstalled sw (e.g. NA, SQL Web, SQL Std, ...)",
 		},
+		&cli.StringFlag{
+			Name:  "license-model",
+			Usage: "Specify a valid license model (e.g. No License required, Bring your own license) (default: all)",
+		},
+		&cli.BoolFlag{
+			Name:  "spot",
+			Usage: "Compare the Spot prices fetched by fetch spot with the On-Demand prices",
+		},
+		&cli.IntFlag{
+			Name:  "spot-days",
+			Value: 7,
+			Usage: "Specify the days of the Spot price history for the average and max prices",
+		},
+		&cli.StringFlag{
+			Name:  "savings-plan",
+			Usage: "Compare the Savings Plans rates fetched by fetch savings-plans with the On-Demand prices (e.g. compute-1yr-no-upfront, ec2instance-3yr-partial-upfront)",
+		},
 	},
 	Action: func(ctx *cli.Context) error {
 		return getEc2Price(ctx)
@@ -47,48 +62,189 @@ var ec2Command = &cli.Command{
 }
 
 func getEc2Price(ctx *cli.Context) error {
-	filter := bson.M{
-		"product.attributes.osengine":       ctx.String("os"),
-		"product.attributes.tenancy":        ctx.String("tenancy"),
-		"product.attributes.capacitystatus": ctx.String("capacitystatus"),
-		"product.attributes.preinstalledsw": ctx.String("preinstalled-sw"),
+	term, err := getPriceTerm(ctx)
+	if err != nil {
+		return err
 	}
 
-	results, err := findMongo(
-		ctx.String("mongo-uri"),
+	out, err := newOutput(ctx)
+	if err != nil {
+		return err
+	}
+
+	cond, err := getCondition(ctx)
+	if err != nil {
+		return err
+	}
+	out.order = term.rowOrder(cond)
+
+	plan, err := getSavingsPlan(ctx)
+	if err != nil {
+		return err
+	}
+
+	if plan != nil && ctx.Bool("spot") {
+		return fmt.Errorf("--spot and --savings-plan cannot be used together")
+	}
+
+	results, err := findProducts(
+		getStoreURI(ctx),
 		"ec2",
-		ctx.String("instance-type"),
-		ctx.String("vcpu"),
-		ctx.String("memory"),
-		filter,
+		cond,
+		term.condition(getEc2Filter(ctx)),
 	)
 	if err != nil {
 		return fmt.Errorf("Failed to find: %w", err)
 	}
 
-	printEc2(results)
+	if ctx.Bool("spot") {
+		return printEc2Spot(ctx, results, term, cond, out)
+	}
+
+	if plan != nil {
+		return printEc2SavingsPlan(ctx, results, term, cond, plan, out)
+	}
+
+	if err := printEc2(results, term, out); err != nil {
+		return err
+	}
 
 	return nil
 }
 
-func printEc2(results []bson.M) error {
-	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, ' ', 0)
+func getEc2Filter(ctx flagValues) bson.M {
+	filter := bson.M{
+		"product.attributes.osengine":       ctx.String("os"),
+		"product.attributes.tenancy":        ctx.String("tenancy"),
+		"product.attributes.capacitystatus": ctx.String("capacitystatus"),
+		"product.attributes.preinstalledsw": ctx.String("preinstalled-sw"),
+	}
+
+	if l := ctx.String("license-model"); l != "" {
+		filter["product.attributes.licensemodel"] = l
+	}
+
+	return filter
+}
+
+// ec2SpotRow is the On-Demand price with the Spot prices of the instance type.
+type ec2SpotRow struct {
+	*priceRow `yaml:",inline"`
+	Spot      *spotStats `json:"spot" yaml:"spot"`
+	Savings   float64    `json:"savings" yaml:"savings"`
+}
+
+// printEc2Spot prints the current, average and max Spot prices next to the On-Demand price.
+// The savings are of the current Spot price against the On-Demand price.
+func printEc2Spot(ctx *cli.Context, results []bson.M, term *priceTerm, cond *condition, out *output) error {
+	if term.term != "ondemand" {
+		return fmt.Errorf("Unsupported term for Spot prices: %s", term.term)
+	}
+
+	if ctx.Int("spot-days") <= 0 {
+		return fmt.Errorf("Invalid spot days: %d", ctx.Int("spot-days"))
+	}
 
-	if _, err := fmt.Fprintln(w, strings.Join(getEc2Header(), "\t")); err != nil {
-		return fmt.Errorf("Failed to print header: %w", err)
+	productDescription, err := aws.SpotProductDescription(ctx.String("os"))
+	if err != nil {
+		return err
 	}
 
+	var instances []spotInstance
+	seen := map[string]bool{}
 	for _, result := range results {
-		if _, err := fmt.Fprintln(w, formatEc2(result)); err != nil {
-			return fmt.Errorf("Failed to print result: %w", err)
+		price, err := decodePrice(result)
+		if err != nil {
+			continue
+		}
+
+		key := spotKey(price.Region, price.Product.Attributes.InstanceType)
+		if !seen[key] {
+			seen[key] = true
+			instances = append(instances, spotInstance{region: price.Region, instanceType: price.Product.Attributes.InstanceType})
 		}
 	}
 
-	if err := w.Flush(); err != nil {
-		return fmt.Errorf("Failed to flush: %w", err)
+	stats, err := findSpotStats(getStoreURI(ctx), cond, productDescription, ctx.Int("spot-days"), instances)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	out.header = append(getEc2Header(), term.header()...)
+	out.header = append(out.header,
+		"SpotAZ",
+		"SpotCurrent(USD/hour)",
+		"SpotAverage(USD/hour)",
+		"SpotMax(USD/hour)",
+		"Savings(%)",
+	)
+
+	for _, result := range results {
+		price, err := decodePrice(result)
+		if err != nil {
+			log.Printf("Skip product %v: %v", result["sku"], err)
+			continue
+		}
+
+		prices, err := term.prices(price)
+		if err != nil {
+			log.Printf("Skip product %s: %v", price.Sku, err)
+			continue
+		}
+
+		for _, p := range prices {
+			fields := append(formatEc2(price), p.fields...)
+			row := &ec2SpotRow{priceRow: p}
+
+			s, ok := stats[spotKey(price.Region, price.Product.Attributes.InstanceType)]
+			if !ok {
+				// Instance types without the Spot price history.
+				fields = append(fields, na(""), na(""), na(""), na(""), na(""))
+				out.add(fields, result, row)
+				continue
+			}
+
+			row.Spot = s
+			if p.Hourly > 0 {
+				row.Savings = (1 - s.Current/p.Hourly) * 100
+			}
+
+			fields = append(fields,
+				s.AvailabilityZone,
+				formatPrice(s.Current),
+				formatPrice(s.Average),
+				formatPrice(s.Max),
+				fmt.Sprintf("%.1f", row.Savings),
+			)
+			out.add(fields, result, row)
+		}
+	}
+
+	return out.render(os.Stdout)
+}
+
+func printEc2(results []bson.M, term *priceTerm, out *output) error {
+	out.header = append(getEc2Header(), term.header()...)
+
+	for _, result := range results {
+		price, err := decodePrice(result)
+		if err != nil {
+			log.Printf("Skip product %v: %v", result["sku"], err)
+			continue
+		}
+
+		prices, err := term.prices(price)
+		if err != nil {
+			log.Printf("Skip product %s: %v", price.Sku, err)
+			continue
+		}
+
+		for _, p := range prices {
+			out.add(append(formatEc2(price), p.fields...), result, p)
+		}
+	}
+
+	return out.render(os.Stdout)
 }
 
 func getEc2Header() []string {
@@ -105,28 +261,99 @@ func getEc2Header() []string {
 		"CapacityStatus",
 		"PreInstalledSw",
 		"ProcessorArchitecture",
-		"OnDemandPrice(USD/hour)",
-		"OnDemandPrice(USD/month)",
 	}
 }
 
-func formatEc2(result primitive.M) string {
+func formatEc2(price *aws.Price) []string {
+	attr := price.Product.Attributes
+
 	fields := []string{
-		result["servicecode"].(string),
-		result["product"].(bson.M)["attributes"].(bson.M)["regioncode"].(string),
-		result["product"].(bson.M)["attributes"].(bson.M)["osengine"].(string),
-		result["product"].(bson.M)["attributes"].(bson.M)["instancetype"].(string),
-		result["product"].(bson.M)["attributes"].(bson.M)["vcpu"].(string),
-		result["product"].(bson.M)["attributes"].(bson.M)["memory"].(string),
-		result["product"].(bson.M)["attributes"].(bson.M)["physicalprocessor"].(string),
-		result["product"].(bson.M)["attributes"].(bson.M)["clockspeed"].(string),
-		result["product"].(bson.M)["attributes"].(bson.M)["tenancy"].(string),
-		result["product"].(bson.M)["attributes"].(bson.M)["capacitystatus"].(string),
-		result["product"].(bson.M)["attributes"].(bson.M)["preinstalledsw"].(string),
-		result["product"].(bson.M)["attributes"].(bson.M)["processorarchitecture"].(string),
-		result["ondemandpriceperusd"].(string),
-		utils.ConvertHourlyToMonthly(result["ondemandpriceperusd"].(string)),
-	}
-
-	return strings.Join(fields, "\t")
+		na(price.ServiceCode),
+		na(attr.RegionCode),
+		na(attr.OSEngine),
+		na(attr.InstanceType),
+		na(attr.Vcpu),
+		na(attr.Memory),
+		na(attr.PhysicalProcessor),
+		na(attr.ClockSpeed),
+		na(attr.Tenancy),
+		na(attr.Capacitystatus),
+		na(attr.PreInstalledSw),
+		na(attr.ProcessorArchitecture),
+	}
+
+	return fields
+}
+
+// ec2SavingsPlanRow is the On-Demand price with the Savings Plan rate of the instance type.
+type ec2SavingsPlanRow struct {
+	*priceRow          `yaml:",inline"`
+	SavingsPlan        string  `json:"savingsPlan" yaml:"savingsPlan"`
+	SavingsPlanHourly  float64 `json:"savingsPlanHourly" yaml:"savingsPlanHourly"`
+	SavingsPlanMonthly float64 `json:"savingsPlanMonthly" yaml:"savingsPlanMonthly"`
+	Savings            float64 `json:"savings" yaml:"savings"`
+}
+
+// printEc2SavingsPlan prints the effective rate of the Savings Plan next to the On-Demand price.
+func printEc2SavingsPlan(ctx *cli.Context, results []bson.M, term *priceTerm, cond *condition, plan *savingsPlan, out *output) error {
+	if term.term != "ondemand" {
+		return fmt.Errorf("Unsupported term for Savings Plans: %s", term.term)
+	}
+
+	rates, err := findSavingsPlanRates(getStoreURI(ctx), cond, plan, "EC2")
+	if err != nil {
+		return err
+	}
+
+	out.header = append(getEc2Header(), term.header()...)
+	out.header = append(out.header,
+		"SavingsPlan",
+		"SavingsPlanRate(USD/hour)",
+		"SavingsPlan(USD/month)",
+		"Savings(%)",
+	)
+
+	for _, result := range results {
+		price, err := decodePrice(result)
+		if err != nil {
+			log.Printf("Skip product %v: %v", result["sku"], err)
+			continue
+		}
+
+		prices, err := term.prices(price)
+		if err != nil {
+			log.Printf("Skip product %s: %v", price.Sku, err)
+			continue
+		}
+
+		for _, p := range prices {
+			fields := append(formatEc2(price), p.fields...)
+			row := &ec2SavingsPlanRow{priceRow: p, SavingsPlan: plan.name}
+
+			rate, ok := rates.rate(price)
+			if !ok {
+				// Products not covered by the plan (e.g. EC2 Instance Savings Plans of another family).
+				fields = append(fields, plan.name, na(""), na(""), na(""))
+				out.add(fields, result, row)
+				continue
+			}
+
+			row.SavingsPlanHourly = rate
+			// 730 hours in a month
+			row.SavingsPlanMonthly = rate * 730
+			if p.Hourly > 0 {
+				row.Savings = (1 - rate/p.Hourly) * 100
+			}
+
+			fields = append(fields,
+				plan.name,
+				formatPrice(row.SavingsPlanHourly),
+				fmt.Sprintf("%.2f", row.SavingsPlanMonthly),
+				fmt.Sprintf("%.1f", row.Savings),
+			)
+			out.add(fields, result, row)
+		}
+	}
+
+	return out.render(os.Stdout)
 }
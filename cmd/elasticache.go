@@ -2,14 +2,12 @@ package cmd
 
 import (
 	"fmt"
+	"log"
 	"os"
-	"strings"
-	"text/tabwriter"
 
-	"github.com/sfuruya0612/apf/internal/utils"
+	"github.com/sfuruya0612/apf/internal/aws"
 	"github.com/urfave/cli/v2"
 	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 var elasticacheCommand = &cli.Command{
@@ -30,43 +28,65 @@ var elasticacheCommand = &cli.Command{
 }
 
 func getElasticachePrice(ctx *cli.Context) error {
-	filter := bson.M{"product.attributes.osengine": ctx.String("engine")}
+	term, err := getPriceTerm(ctx)
+	if err != nil {
+		return err
+	}
+
+	out, err := newOutput(ctx)
+	if err != nil {
+		return err
+	}
 
-	results, err := findMongo(
-		ctx.String("mongo-uri"),
+	cond, err := getCondition(ctx)
+	if err != nil {
+		return err
+	}
+	out.order = term.rowOrder(cond)
+
+	results, err := findProducts(
+		getStoreURI(ctx),
 		"elasticache",
-		ctx.String("instance-type"),
-		ctx.String("vcpu"),
-		ctx.String("memory"),
-		filter,
+		cond,
+		term.condition(getElasticacheFilter(ctx)),
 	)
 	if err != nil {
 		return fmt.Errorf("Failed to find: %w", err)
 	}
 
-	printElasticache(results)
+	if err := printElasticache(results, term, out); err != nil {
+		return err
+	}
 
 	return nil
 }
 
-func printElasticache(results []bson.M) error {
-	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, ' ', 0)
+func getElasticacheFilter(ctx flagValues) bson.M {
+	return bson.M{"product.attributes.osengine": ctx.String("engine")}
+}
 
-	if _, err := fmt.Fprintln(w, strings.Join(getElasticacheHeader(), "\t")); err != nil {
-		return fmt.Errorf("Failed to print header: %w", err)
-	}
+func printElasticache(results []bson.M, term *priceTerm, out *output) error {
+	out.header = append(getElasticacheHeader(), term.header()...)
 
 	for _, result := range results {
-		if _, err := fmt.Fprintln(w, formatElasticache(result)); err != nil {
-			return fmt.Errorf("Failed to print result: %w", err)
+		price, err := decodePrice(result)
+		if err != nil {
+			log.Printf("Skip product %v: %v", result["sku"], err)
+			continue
+		}
+
+		prices, err := term.prices(price)
+		if err != nil {
+			log.Printf("Skip product %s: %v", price.Sku, err)
+			continue
 		}
-	}
 
-	if err := w.Flush(); err != nil {
-		return fmt.Errorf("Failed to flush: %w", err)
+		for _, p := range prices {
+			out.add(append(formatElasticache(price), p.fields...), result, p)
+		}
 	}
 
-	return nil
+	return out.render(os.Stdout)
 }
 
 func getElasticacheHeader() []string {
@@ -77,22 +97,20 @@ func getElasticacheHeader() []string {
 		"InstanceType",
 		"vCPU",
 		"Memory",
-		"OnDemandPrice(USD/hour)",
-		"OnDemandPrice(USD/month)",
 	}
 }
 
-func formatElasticache(result primitive.M) string {
+func formatElasticache(price *aws.Price) []string {
+	attr := price.Product.Attributes
+
 	fields := []string{
-		result["servicecode"].(string),
-		result["product"].(bson.M)["attributes"].(bson.M)["regioncode"].(string),
-		result["product"].(bson.M)["attributes"].(bson.M)["osengine"].(string),
-		result["product"].(bson.M)["attributes"].(bson.M)["instancetype"].(string),
-		result["product"].(bson.M)["attributes"].(bson.M)["vcpu"].(string),
-		result["product"].(bson.M)["attributes"].(bson.M)["memory"].(string),
-		result["ondemandpriceperusd"].(string),
-		utils.ConvertHourlyToMonthly(result["ondemandpriceperusd"].(string)),
+		na(price.ServiceCode),
+		na(attr.RegionCode),
+		na(attr.OSEngine),
+		na(attr.InstanceType),
+		na(attr.Vcpu),
+		na(attr.Memory),
 	}
 
-	return strings.Join(fields, "\t")
+	return fields
 }
@@ -0,0 +1,377 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sfuruya0612/apf/internal/store"
+	"github.com/urfave/cli/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"gopkg.in/yaml.v3"
+)
+
+var EstimateCommand = &cli.Command{
+	Name:  "estimate",
+	Usage: "Estimate the cost of the resources listed in a YAML or JSON manifest",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "file",
+			Aliases:  []string{"f"},
+			Required: true,
+			Usage:    "Specify a manifest file of the resources (e.g. stack.yaml)",
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		return estimate(ctx)
+	},
+}
+
+// manifest is the bill of materials. Region is the default of the resources.
+type manifest struct {
+	Region    string             `yaml:"region"`
+	Resources []manifestResource `yaml:"resources"`
+}
+
+// manifestResource has one of the services. The parameters of the service are the same as apf serve,
+// with count (default: 1) and deployment as the alias of deploymentOption.
+type manifestResource struct {
+	Name        string                 `yaml:"name"`
+	EC2         map[string]interface{} `yaml:"ec2"`
+	RDS         map[string]interface{} `yaml:"rds"`
+	ElastiCache map[string]interface{} `yaml:"elasticache"`
+}
+
+// estimateRow is the cost of a resource line, or the total of the lines.
+type estimateRow struct {
+	Name         string `json:"name" yaml:"name"`
+	Service      string `json:"service,omitempty" yaml:"service,omitempty"`
+	Region       string `json:"region,omitempty" yaml:"region,omitempty"`
+	InstanceType string `json:"instanceType,omitempty" yaml:"instanceType,omitempty"`
+	Count        int64  `json:"count,omitempty" yaml:"count,omitempty"`
+	*priceRow    `yaml:",inline"`
+	TotalHourly  float64 `json:"totalHourly" yaml:"totalHourly"`
+	TotalMonthly float64 `json:"totalMonthly" yaml:"totalMonthly"`
+	TotalYearly  float64 `json:"totalYearly" yaml:"totalYearly"`
+}
+
+func estimate(ctx *cli.Context) error {
+	out, err := newOutput(ctx)
+	if err != nil {
+		return err
+	}
+
+	m, err := readManifest(ctx.String("file"))
+	if err != nil {
+		return err
+	}
+
+	st, err := store.Open(getStoreURI(ctx))
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	out.header = getEstimateHeader()
+	total := &estimateRow{Name: "Total"}
+
+	var errs []string
+	for i, r := range m.Resources {
+		row, result, err := resolveResource(st, context.Background(), m, r)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("resources[%d] %s: %v", i, r.label(), err))
+			continue
+		}
+
+		total.TotalHourly += row.TotalHourly
+		total.TotalMonthly += row.TotalMonthly
+		total.TotalYearly += row.TotalYearly
+
+		out.add(formatEstimate(row), result, row)
+	}
+
+	// Lines that can't be resolved make the total wrong, so none is printed.
+	if len(errs) > 0 {
+		return fmt.Errorf("Failed to resolve %d of %d resources:\n%s", len(errs), len(m.Resources), strings.Join(errs, "\n"))
+	}
+
+	out.add(formatEstimate(total), bson.M{}, total)
+
+	return out.render(os.Stdout)
+}
+
+func readManifest(path string) (*manifest, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read manifest: %w", err)
+	}
+
+	// JSON is also parsed as YAML.
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+
+	var m manifest
+	if err := dec.Decode(&m); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("Failed to parse manifest %s: %w", path, err)
+	}
+
+	if len(m.Resources) == 0 {
+		return nil, fmt.Errorf("No resources in manifest: %s", path)
+	}
+
+	return &m, nil
+}
+
+// service returns the service name and the parameters of the resource.
+func (r manifestResource) service() (string, map[string]interface{}, error) {
+	var name string
+	var params map[string]interface{}
+
+	for s, p := range map[string]map[string]interface{}{"ec2": r.EC2, "rds": r.RDS, "elasticache": r.ElastiCache} {
+		if p == nil {
+			continue
+		}
+		if name != "" {
+			return "", nil, fmt.Errorf("Specify only one of ec2, rds and elasticache")
+		}
+		name, params = s, p
+	}
+
+	if name == "" {
+		return "", nil, fmt.Errorf("Specify one of ec2, rds and elasticache")
+	}
+
+	return name, params, nil
+}
+
+func (r manifestResource) label() string {
+	if r.Name != "" {
+		return r.Name
+	}
+
+	if s, params, err := r.service(); err == nil {
+		if t, ok := params["instanceType"]; ok {
+			return fmt.Sprintf("%s %v", s, t)
+		}
+		return s
+	}
+
+	return "(unknown)"
+}
+
+// resolveResource finds the single price of the resource with the filters of the price subcommand.
+func resolveResource(st store.Store, ctx context.Context, m *manifest, r manifestResource) (*estimateRow, bson.M, error) {
+	service, params, err := r.service()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var e *apiEndpoint
+	for _, endpoint := range apiEndpoints {
+		if endpoint.collection == service {
+			e = endpoint
+		}
+	}
+
+	count := int64(1)
+	query := map[string][]string{}
+	for k, v := range params {
+		s := fmt.Sprint(v)
+
+		switch k {
+		case "count":
+			count, err = strconv.ParseInt(s, 10, 64)
+			if err != nil || count <= 0 {
+				return nil, nil, fmt.Errorf("Invalid count: %s", s)
+			}
+			continue
+		case "deployment":
+			k = "deploymentOption"
+		}
+
+		query[k] = []string{s}
+	}
+
+	if _, ok := query["region"]; !ok && m.Region != "" {
+		query["region"] = []string{m.Region}
+	}
+
+	q, err := e.parseQuery(query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if q.String("region") == "" || q.String("instance-type") == "" {
+		return nil, nil, fmt.Errorf("region and instanceType are required")
+	}
+
+	term, err := getPriceTerm(q)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cond, err := getCondition(q)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	filter, err := e.filter(q)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results, err := findStore(st, ctx, e.collection, cond, term.condition(filter))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch {
+	case len(results) == 0:
+		return nil, nil, fmt.Errorf("No product matches")
+	case len(results) > 1:
+		if hints := differingAttributes(e, results); len(hints) > 0 {
+			return nil, nil, fmt.Errorf("%d products match (specify more parameters, they differ in %s)", len(results), strings.Join(hints, "; "))
+		}
+		return nil, nil, fmt.Errorf("%d products match (specify more parameters, e.g. tenancy)", len(results))
+	}
+
+	price, err := decodePrice(results[0])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	prices, err := term.prices(price)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch {
+	case len(prices) == 0:
+		return nil, nil, fmt.Errorf("No %s price of %s", term.term, price.Sku)
+	case len(prices) > 1:
+		return nil, nil, fmt.Errorf("%d reserved offers of %s match (specify lease, purchaseOption and offeringClass)", len(prices), price.Sku)
+	}
+
+	row := &estimateRow{
+		Name:         r.Name,
+		Service:      service,
+		Region:       price.Region,
+		InstanceType: price.Product.Attributes.InstanceType,
+		Count:        count,
+		priceRow:     prices[0],
+		TotalHourly:  prices[0].EffectiveHourly * float64(count),
+	}
+	// 730 hours in a month
+	row.TotalMonthly = row.TotalHourly * 730
+	row.TotalYearly = row.TotalMonthly * 12
+
+	return row, results[0], nil
+}
+
+func getEstimateHeader() []string {
+	return []string{
+		"Name",
+		"Service",
+		"Region",
+		"InstanceType",
+		"Term",
+		"Count",
+		"Price(USD/hour)",
+		"Hourly(USD)",
+		"Monthly(USD)",
+		"Yearly(USD)",
+	}
+}
+
+func formatEstimate(row *estimateRow) []string {
+	fields := []string{na(row.Name), na(row.Service), na(row.Region), na(row.InstanceType)}
+
+	if row.priceRow == nil {
+		fields = append(fields, na(""), na(""), na(""))
+	} else {
+		term := row.Term
+		if term == "reserved" {
+			term = strings.Join([]string{term, row.LeaseContractLength, row.PurchaseOption, row.OfferingClass}, " ")
+		}
+
+		fields = append(fields, term, strconv.FormatInt(row.Count, 10), formatPrice(row.EffectiveHourly))
+	}
+
+	return append(fields,
+		formatPrice(row.TotalHourly),
+		fmt.Sprintf("%.2f", row.TotalMonthly),
+		fmt.Sprintf("%.2f", row.TotalYearly),
+	)
+}
+
+// attributeParams are the parameters of the product attributes, which are hinted to tell the matched products apart.
+var attributeParams = map[string]string{
+	"tenancy":          "tenancy",
+	"capacitystatus":   "capacityStatus",
+	"preinstalledsw":   "preInstalledSw",
+	"licensemodel":     "licenseModel",
+	"osengine":         "os",
+	"deploymentoption": "deploymentOption",
+	"storageconfig":    "ioOptimized",
+}
+
+// differingAttributes returns the attributes that differ among the products with their values, e.g. licenseModel (Bring your own license, No License required).
+// The usage type and the operation are left out, since they differ with the other attributes.
+func differingAttributes(e *apiEndpoint, results []bson.M) []string {
+	values := map[string]map[string]bool{}
+	for _, result := range results {
+		price, err := decodePrice(result)
+		if err != nil {
+			continue
+		}
+
+		b, err := bson.Marshal(price.Product.Attributes)
+		if err != nil {
+			continue
+		}
+		var attr bson.M
+		if err := bson.Unmarshal(b, &attr); err != nil {
+			continue
+		}
+
+		for k, v := range attr {
+			if values[k] == nil {
+				values[k] = map[string]bool{}
+			}
+			values[k][fmt.Sprint(v)] = true
+		}
+	}
+
+	params := map[string]bool{}
+	for _, p := range e.allParams() {
+		params[p.name] = true
+	}
+
+	var hints []string
+	for k, vs := range values {
+		if len(vs) < 2 || k == "usagetype" || k == "operation" {
+			continue
+		}
+
+		name := k
+		if p, ok := attributeParams[k]; ok && params[p] {
+			name = p
+		}
+
+		var vals []string
+		for v := range vs {
+			vals = append(vals, na(v))
+		}
+		sort.Strings(vals)
+
+		hints = append(hints, fmt.Sprintf("%s (%s)", name, strings.Join(vals, ", ")))
+	}
+	sort.Strings(hints)
+
+	return hints
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sfuruya0612/apf/internal/aws"
+)
+
+// estimatePrice returns an EC2 instance of m5.large in ap-northeast-1 with the defaults of price ec2.
+func estimatePrice(sku, licenseModel, preInstalledSw string) *aws.Price {
+	p := &aws.Price{Sku: sku, Region: "ap-northeast-1", OnDemandPricePerUSD: "0.124", OnDemandHourlyUSD: 0.124}
+	attr := &p.Product.Attributes
+	attr.InstanceType = "m5.large"
+	attr.OSEngine = "Linux"
+	attr.Tenancy = "Shared"
+	attr.Capacitystatus = "Used"
+	attr.PreInstalledSw = preInstalledSw
+	attr.LicenseModel = licenseModel
+
+	return p
+}
+
+func TestResolveResource(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+
+	docs := []interface{}{
+		document(t, estimatePrice("LICENSE_INCLUDED", "No License required", "NA")),
+		document(t, estimatePrice("BYOL", "Bring your own license", "NA")),
+		// Not matched by the default of preInstalledSw.
+		document(t, estimatePrice("SQL_WEB", "No License required", "SQL Web")),
+	}
+	if err := st.Insert(ctx, "ec2", docs); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &manifest{Region: "ap-northeast-1"}
+
+	tests := []struct {
+		name    string
+		params  map[string]interface{}
+		wantSku string
+		wantErr string
+	}{
+		{
+			name:    "ambiguous",
+			params:  map[string]interface{}{"instanceType": "m5.large"},
+			wantErr: "2 products match (specify more parameters, they differ in licenseModel (Bring your own license, No License required))",
+		},
+		{
+			name:    "license model",
+			params:  map[string]interface{}{"instanceType": "m5.large", "licenseModel": "Bring your own license"},
+			wantSku: "BYOL",
+		},
+		{
+			name:    "pre-installed software",
+			params:  map[string]interface{}{"instanceType": "m5.large", "preInstalledSw": "SQL Web"},
+			wantSku: "SQL_WEB",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, result, err := resolveResource(st, ctx, m, manifestResource{EC2: tt.params})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if result["sku"] != tt.wantSku {
+				t.Errorf("got %v, want %s", result["sku"], tt.wantSku)
+			}
+		})
+	}
+}
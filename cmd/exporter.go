@@ -0,0 +1,376 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sfuruya0612/apf/internal/aws"
+	"github.com/sfuruya0612/apf/internal/store"
+	"github.com/urfave/cli/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"gopkg.in/yaml.v3"
+)
+
+var ExporterCommand = &cli.Command{
+	Name:  "exporter",
+	Usage: "Serve the On-Demand prices of the instances as Prometheus metrics",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "listen",
+			Aliases: []string{"l"},
+			Value:   ":9180",
+			Usage:   "Specify an address to listen on",
+		},
+		&cli.StringSliceFlag{
+			Name:  "service",
+			Value: cli.NewStringSlice("ec2", "rds", "elasticache"),
+			Usage: "Specify services to export (e.g. ec2, rds, elasticache)",
+		},
+		&cli.StringSliceFlag{
+			Name:    "region",
+			Aliases: []string{"r"},
+			Usage:   "Specify region codes to export",
+		},
+		&cli.StringSliceFlag{
+			Name:    "instance-type",
+			Aliases: []string{"i"},
+			Usage:   "Specify instance types to export",
+		},
+		&cli.StringSliceFlag{
+			Name:  "sku",
+			Usage: "Specify SKUs to export. Only the watch-list is exported if set",
+		},
+		&cli.StringFlag{
+			Name:  "license-model",
+			Usage: "Specify a valid license model of EC2 to export (e.g. No License required, Bring your own license) (default: all)",
+		},
+		&cli.StringFlag{
+			Name:    "watch-list",
+			Aliases: []string{"w"},
+			Usage:   "Specify a YAML or JSON file of the SKUs to export (e.g. skus: [ABCDEFGHIJKLMNOP])",
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		e, err := getExporter(ctx)
+		if err != nil {
+			return err
+		}
+
+		return e.serve(getStoreURI(ctx), ctx.String("listen"))
+	},
+}
+
+// exporterServices are the filters of the instances exported without the watch-list.
+// EC2 is limited to the defaults of the price subcommand, since the OSes, the tenancies,
+// the capacity reservations and the pre-installed software make a series per instance type each.
+var exporterServices = map[string]bson.M{
+	"ec2": {
+		"product.attributes.osengine":       flagDefault(ec2Command, "os"),
+		"product.attributes.tenancy":        flagDefault(ec2Command, "tenancy"),
+		"product.attributes.capacitystatus": flagDefault(ec2Command, "capacitystatus"),
+		"product.attributes.preinstalledsw": flagDefault(ec2Command, "preinstalled-sw"),
+	},
+	"rds": {
+		"product.productfamily": "Database Instance",
+	},
+	"elasticache": {
+		"product.attributes.instancetype": bson.M{"$ne": ""},
+	},
+}
+
+// watchList is the file of the SKUs to export.
+type watchList struct {
+	Skus []string `yaml:"skus"`
+}
+
+type exporter struct {
+	st            store.Store
+	services      []string
+	regions       []string
+	instanceTypes []string
+	licenseModel  string
+	skus          []string
+
+	// mu guards the prices rendered for the snapshots of pricesKey, which are reused until a fetch or a rollback.
+	mu        sync.Mutex
+	pricesKey string
+	prices    []byte
+}
+
+func getExporter(ctx *cli.Context) (*exporter, error) {
+	e := &exporter{
+		services:      ctx.StringSlice("service"),
+		regions:       ctx.StringSlice("region"),
+		instanceTypes: ctx.StringSlice("instance-type"),
+		licenseModel:  ctx.String("license-model"),
+		skus:          ctx.StringSlice("sku"),
+	}
+
+	for _, s := range e.services {
+		if _, ok := exporterServices[s]; !ok {
+			return nil, fmt.Errorf("Unsupported service for exporter: %s (e.g. ec2, rds, elasticache)", s)
+		}
+	}
+
+	if path := ctx.String("watch-list"); path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read watch-list: %w", err)
+		}
+
+		// JSON is also parsed as YAML.
+		var w watchList
+		if err := yaml.Unmarshal(b, &w); err != nil {
+			return nil, fmt.Errorf("Failed to parse watch-list %s: %w", path, err)
+		}
+
+		if len(w.Skus) == 0 {
+			return nil, fmt.Errorf("No SKUs in watch-list: %s", path)
+		}
+		e.skus = append(e.skus, w.Skus...)
+	}
+
+	// All the prices of a service are too many to be scraped.
+	if len(e.skus) == 0 && len(e.regions) == 0 && len(e.instanceTypes) == 0 {
+		return nil, fmt.Errorf("Specify --watch-list, --sku, --region or --instance-type to limit the exported prices")
+	}
+
+	return e, nil
+}
+
+func (e *exporter) serve(storeUri, listen string) error {
+	st, err := store.Open(storeUri)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	e.st = st
+
+	if len(e.skus) > 0 {
+		log.Printf("Exporting %d SKUs of the watch-list\n", len(e.skus))
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", e.handleMetrics)
+
+	return listenAndServe(listen, mux)
+}
+
+func (e *exporter) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	if err := e.writeMetrics(r.Context(), &buf); err != nil {
+		log.Printf("Failed to collect metrics: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if _, err := buf.WriteTo(w); err != nil {
+		log.Printf("Failed to write metrics: %v", err)
+	}
+}
+
+// metric is a sample of a gauge in the Prometheus text format.
+type metric struct {
+	labels [][2]string
+	value  float64
+}
+
+// writeGauge writes the samples sorted by the labels.
+func writeGauge(w io.Writer, name, help string, metrics []metric) {
+	if len(metrics) == 0 {
+		return
+	}
+
+	lines := make([]string, 0, len(metrics))
+	for _, m := range metrics {
+		labels := make([]string, 0, len(m.labels))
+		for _, l := range m.labels {
+			labels = append(labels, fmt.Sprintf(`%s="%s"`, l[0], labelEscaper.Replace(l[1])))
+		}
+		lines = append(lines, fmt.Sprintf("%s{%s} %s", name, strings.Join(labels, ","), strconv.FormatFloat(m.value, 'g', -1, 64)))
+	}
+	sort.Strings(lines)
+
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", name)
+	for _, line := range lines {
+		fmt.Fprintln(w, line)
+	}
+}
+
+// labelEscaper escapes the label values of the Prometheus text format.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func (e *exporter) writeMetrics(ctx context.Context, w io.Writer) error {
+	fetched, documents, err := e.collectCollections(ctx)
+	if err != nil {
+		return err
+	}
+
+	prices, err := e.renderPrices(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(prices); err != nil {
+		return err
+	}
+	writeGauge(w, "apf_last_fetch_timestamp_seconds", "Time of the latest fetch of the collection in Unix seconds.", fetched)
+	writeGauge(w, "apf_documents", "Number of the documents in the collection.", documents)
+
+	return nil
+}
+
+// renderPrices returns the price gauge, which is rendered again only if the snapshots of the services are changed.
+func (e *exporter) renderPrices(ctx context.Context) ([]byte, error) {
+	key, err := e.snapshotKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.prices != nil && key == e.pricesKey {
+		return e.prices, nil
+	}
+
+	prices, err := e.collectPrices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writeGauge(&buf, "aws_ondemand_price_usd_per_hour", "On-Demand price of the instance in USD per hour.", prices)
+	e.pricesKey, e.prices = key, buf.Bytes()
+
+	return e.prices, nil
+}
+
+// snapshotKey returns the live snapshot IDs of the services, which are changed by a fetch and a rollback.
+func (e *exporter) snapshotKey(ctx context.Context) (string, error) {
+	keys := make([]string, 0, len(e.services))
+	for _, service := range e.services {
+		ids, err := getSnapshots(e.st, ctx, service)
+		if err != nil {
+			return "", err
+		}
+
+		live, err := getLiveSnapshot(e.st, ctx, service, ids)
+		if err != nil {
+			return "", err
+		}
+
+		keys = append(keys, fmt.Sprintf("%s_%s", service, live))
+	}
+
+	return strings.Join(keys, ","), nil
+}
+
+func (e *exporter) collectPrices(ctx context.Context) ([]metric, error) {
+	var metrics []metric
+
+	for _, service := range e.services {
+		filter := bson.M{}
+		if len(e.skus) > 0 {
+			filter["sku"] = bson.M{"$in": e.skus}
+		} else {
+			for k, v := range exporterServices[service] {
+				filter[k] = v
+			}
+
+			if service == "ec2" && e.licenseModel != "" {
+				filter["product.attributes.licensemodel"] = e.licenseModel
+			}
+		}
+
+		if len(e.regions) > 0 {
+			filter["region"] = bson.M{"$in": e.regions}
+		}
+
+		if len(e.instanceTypes) > 0 {
+			filter["product.attributes.instancetype"] = bson.M{"$in": e.instanceTypes}
+		}
+
+		results, err := findStore(e.st, ctx, service, &condition{}, filter)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to find %s prices: %w", service, err)
+		}
+
+		for _, result := range results {
+			price, err := decodePrice(result)
+			if err != nil {
+				log.Printf("Skip product %v: %v", result["sku"], err)
+				continue
+			}
+
+			attr := price.Product.Attributes
+			metrics = append(metrics, metric{
+				labels: [][2]string{
+					{"service", service},
+					{"region", price.Region},
+					{"instance_type", attr.InstanceType},
+					{"os", attr.OSEngine},
+					{"tenancy", attr.Tenancy},
+					{"sku", price.Sku},
+				},
+				value: price.OnDemandHourlyUSD,
+			})
+		}
+	}
+
+	return metrics, nil
+}
+
+// collectCollections returns the time of the latest fetch and the number of the documents of the collections.
+func (e *exporter) collectCollections(ctx context.Context) ([]metric, []metric, error) {
+	var fetched, documents []metric
+
+	codes := append(append([]string{}, serviceCodes...), aws.SavingsPlansServiceCode)
+	for _, sc := range codes {
+		collection := getCollectionName(sc)
+
+		metadata, err := getSnapshotMetadata(e.st, ctx, collection)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var latest time.Time
+		for _, s := range metadata {
+			if s.FetchedAt.After(latest) {
+				latest = s.FetchedAt
+			}
+		}
+
+		if !latest.IsZero() {
+			fetched = append(fetched, metric{
+				labels: [][2]string{{"collection", collection}},
+				value:  float64(latest.Unix()),
+			})
+		}
+
+		count, err := e.st.Count(ctx, collection)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to count %s collection: %w", collection, err)
+		}
+
+		documents = append(documents, metric{
+			labels: [][2]string{{"collection", collection}},
+			value:  float64(count),
+		})
+	}
+
+	return fetched, documents, nil
+}
@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sfuruya0612/apf/internal/aws"
+	"github.com/urfave/cli/v2"
+)
+
+func TestGetExporter(t *testing.T) {
+	tests := []struct {
+		args    string
+		wantErr bool
+	}{
+		{"", true},
+		{"--service ec2", true},
+		{"--region ap-northeast-1", false},
+		{"--instance-type m5.large", false},
+		{"--sku 2TG2D8R56U4M5KVD", false},
+		{"--region ap-northeast-1 --service s3", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			set := flag.NewFlagSet("exporter", flag.ContinueOnError)
+			for _, f := range ExporterCommand.Flags {
+				if err := f.Apply(set); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := set.Parse(strings.Fields(tt.args)); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := getExporter(cli.NewContext(nil, set, nil))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// exporterPrice returns an EC2 instance of ap-northeast-1 with the On-Demand price.
+func exporterPrice(sku, instanceType string, hourly float64) *aws.Price {
+	p := &aws.Price{Sku: sku, Region: "ap-northeast-1", OnDemandHourlyUSD: hourly}
+	p.Product.Attributes.InstanceType = instanceType
+	p.Product.Attributes.OSEngine = "Linux"
+	p.Product.Attributes.Tenancy = "Shared"
+	p.Product.Attributes.Capacitystatus = "Used"
+	p.Product.Attributes.PreInstalledSw = "NA"
+	p.Product.Attributes.LicenseModel = "No License required"
+
+	return p
+}
+
+func TestExporterEc2Defaults(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+
+	windows := exporterPrice("B", "m5.large", 0.22)
+	windows.Product.Attributes.OSEngine = "Windows"
+	dedicated := exporterPrice("C", "m5.large", 0.136)
+	dedicated.Product.Attributes.Tenancy = "Dedicated"
+	byol := exporterPrice("D", "m5.large", 0.124)
+	byol.Product.Attributes.LicenseModel = "Bring your own license"
+
+	var docs []interface{}
+	for _, p := range []*aws.Price{exporterPrice("A", "m5.large", 0.124), windows, dedicated, byol} {
+		docs = append(docs, document(t, p))
+	}
+	if err := st.Insert(ctx, "ec2", docs); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		licenseModel string
+		want         []string
+	}{
+		// Only the regions are given, but the OSes and the tenancies other than the defaults are not exported.
+		{"", []string{"A", "D"}},
+		{"No License required", []string{"A"}},
+	}
+
+	for _, tt := range tests {
+		e := &exporter{st: st, services: []string{"ec2"}, regions: []string{"ap-northeast-1"}, licenseModel: tt.licenseModel}
+
+		metrics, err := e.collectPrices(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var skus []string
+		for _, m := range metrics {
+			for _, l := range m.labels {
+				if l[0] == "sku" {
+					skus = append(skus, l[1])
+				}
+			}
+		}
+		sort.Strings(skus)
+
+		if !reflect.DeepEqual(skus, tt.want) {
+			t.Errorf("license model %q: got %v, want %v", tt.licenseModel, skus, tt.want)
+		}
+	}
+}
+
+func TestExporterPricesCache(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+
+	e := &exporter{st: st, services: []string{"ec2"}, instanceTypes: []string{"m5.large"}}
+
+	fetch := func(id string, prices ...*aws.Price) {
+		t.Helper()
+
+		var docs []interface{}
+		for _, p := range prices {
+			docs = append(docs, document(t, p))
+		}
+		if err := st.Insert(ctx, "ec2_"+id, docs); err != nil {
+			t.Fatal(err)
+		}
+		if err := swapCollection(st, ctx, "ec2_"+id, "ec2"); err != nil {
+			t.Fatal(err)
+		}
+		if err := recordSnapshot(st, ctx, &snapshot{ID: id, Collection: "ec2", ServiceCode: "AmazonEC2", FetchedAt: time.Now().UTC(), Count: int64(len(docs))}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	metrics := func() string {
+		t.Helper()
+
+		var buf bytes.Buffer
+		if err := e.writeMetrics(ctx, &buf); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	fetch("20230501000000", exporterPrice("A", "m5.large", 0.124), exporterPrice("B", "c5.large", 0.107))
+
+	first := metrics()
+	if !strings.Contains(first, `sku="A"} 0.124`) || strings.Contains(first, `sku="B"`) {
+		t.Errorf("got metrics of the other instance types:\n%s", first)
+	}
+
+	// The prices are cached until the snapshot is changed, so the documents replaced out of a fetch are not queried.
+	if err := st.Insert(ctx, "ec2_tmp", []interface{}{document(t, exporterPrice("A", "m5.large", 0.5)), document(t, exporterPrice("B", "c5.large", 0.107))}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Replace(ctx, "ec2_tmp", "ec2"); err != nil {
+		t.Fatal(err)
+	}
+	if got := metrics(); got != first {
+		t.Errorf("got metrics:\n%s\nwant:\n%s", got, first)
+	}
+
+	fetch("20230601000000", exporterPrice("A", "m5.large", 0.12), exporterPrice("B", "c5.large", 0.107))
+
+	if got := metrics(); !strings.Contains(got, `sku="A"} 0.12`+"\n") {
+		t.Errorf("got metrics of the previous snapshot:\n%s", got)
+	}
+}
@@ -0,0 +1,384 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sfuruya0612/apf/internal/aws"
+	"github.com/urfave/cli/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// fargateFreeEphemeralStorageGB is the ephemeral storage included in the price of a task.
+const fargateFreeEphemeralStorageGB = 20
+
+// fargateMemoryRange is the memory sizes (GB) of a task between min and max in the increments of step.
+// sizes are the sizes instead of the increments if they are not even.
+type fargateMemoryRange struct {
+	min, max, step float64
+	sizes          []float64
+}
+
+// fargateMemoryRanges are the memory ranges supported by each vCPU size of a task.
+var fargateMemoryRanges = map[float64]fargateMemoryRange{
+	0.25: {min: 0.5, max: 2, sizes: []float64{0.5, 1, 2}},
+	0.5:  {min: 1, max: 4, step: 1},
+	1:    {min: 2, max: 8, step: 1},
+	2:    {min: 4, max: 16, step: 1},
+	4:    {min: 8, max: 30, step: 1},
+	8:    {min: 16, max: 60, step: 4},
+	16:   {min: 32, max: 120, step: 8},
+}
+
+// fargateWindowsVcpus are the vCPU sizes supported by Windows tasks.
+var fargateWindowsVcpus = []float64{1, 2, 4}
+
+func (r fargateMemoryRange) valid(memory float64) bool {
+	if len(r.sizes) > 0 {
+		for _, s := range r.sizes {
+			if memory == s {
+				return true
+			}
+		}
+		return false
+	}
+
+	if memory < r.min || memory > r.max {
+		return false
+	}
+
+	steps := (memory - r.min) / r.step
+	return steps == math.Trunc(steps)
+}
+
+func (r fargateMemoryRange) String() string {
+	if len(r.sizes) > 0 {
+		sizes := make([]string, 0, len(r.sizes))
+		for _, s := range r.sizes {
+			sizes = append(sizes, strconv.FormatFloat(s, 'f', -1, 64))
+		}
+		return strings.Join(sizes, ", ")
+	}
+
+	return fmt.Sprintf("%g - %g in %g GB increments", r.min, r.max, r.step)
+}
+
+var fargateCommand = &cli.Command{
+	Name:    "fargate",
+	Aliases: []string{"fg"},
+	Usage:   "Get Fargate pricing and estimate the cost of ECS tasks",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "os",
+			Aliases: []string{"o"},
+			Value:   "Linux",
+			Usage:   "Specify a valid OS (e.g. Linux, Windows)",
+		},
+		&cli.StringFlag{
+			Name:    "architecture",
+			Aliases: []string{"a"},
+			Value:   aws.ArchitectureX86,
+			Usage:   "Specify a valid architecture (e.g. x86_64, arm64)",
+		},
+		&cli.Float64Flag{
+			Name:  "cpu",
+			Value: 0.25,
+			Usage: "Specify the vCPU of a task (e.g. 0.25, 0.5, 1, 2, 4, 8, 16)",
+		},
+		&cli.Float64Flag{
+			Name:  "memory",
+			Value: 0.5,
+			Usage: "Specify the memory of a task in GB",
+		},
+		&cli.Float64Flag{
+			Name:  "ephemeral-storage",
+			Value: fargateFreeEphemeralStorageGB,
+			Usage: "Specify the ephemeral storage of a task in GB (20 - 200)",
+		},
+		&cli.IntFlag{
+			Name:  "tasks",
+			Value: 1,
+			Usage: "Specify the number of running tasks",
+		},
+		&cli.StringFlag{
+			Name:  "savings-plan",
+			Usage: "Use the Compute Savings Plans rates fetched by fetch savings-plans instead of the On-Demand prices (e.g. compute-1yr-no-upfront)",
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		return getFargatePrice(ctx)
+	},
+}
+
+// fargateTask is the task to estimate the cost.
+type fargateTask struct {
+	osEngine         string
+	architecture     string
+	cpu              float64
+	memory           float64
+	ephemeralStorage float64
+	tasks            int
+}
+
+// fargateRow is the prices of the region in USD, and the cost of the tasks.
+type fargateRow struct {
+	Region                string  `json:"region" yaml:"region"`
+	OSEngine              string  `json:"os" yaml:"os"`
+	Architecture          string  `json:"architecture" yaml:"architecture"`
+	VcpuPrice             float64 `json:"vcpuPrice" yaml:"vcpuPrice"`
+	MemoryPrice           float64 `json:"memoryPrice" yaml:"memoryPrice"`
+	WindowsLicensePrice   float64 `json:"windowsLicensePrice,omitempty" yaml:"windowsLicensePrice,omitempty"`
+	EphemeralStoragePrice float64 `json:"ephemeralStoragePrice" yaml:"ephemeralStoragePrice"`
+	Hourly                float64 `json:"hourly" yaml:"hourly"`
+	Monthly               float64 `json:"monthly" yaml:"monthly"`
+
+	hasVcpu   bool
+	hasMemory bool
+}
+
+func getFargatePrice(ctx *cli.Context) error {
+	if ctx.String("term") != "ondemand" {
+		return fmt.Errorf("Unsupported term for fargate: %s", ctx.String("term"))
+	}
+
+	out, err := newOutput(ctx)
+	if err != nil {
+		return err
+	}
+
+	cond, err := getCondition(ctx)
+	if err != nil {
+		return err
+	}
+	// --memory is the memory of a task, not the memory condition of the price command.
+	cond.memory = nil
+
+	task, err := getFargateTask(ctx)
+	if err != nil {
+		return err
+	}
+
+	rates, err := getComputeSavingsPlanRates(ctx, cond, "Fargate")
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{
+		"$or": bson.A{
+			bson.M{
+				"product.attributes.osengine":              task.osEngine,
+				"product.attributes.processorarchitecture": task.architecture,
+			},
+			bson.M{"product.attributes.usagetype": bson.M{"$regex": "Fargate-EphemeralStorage-GB-Hours$"}},
+		},
+	}
+
+	// A row is made of several products, so the products are not sorted nor limited by the store.
+	out.order, err = getRowOrder("fargate", cond)
+	if err != nil {
+		return err
+	}
+
+	results, err := findProducts(
+		getStoreURI(ctx),
+		"fargate",
+		cond,
+		filter,
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to find: %w", err)
+	}
+
+	rows := getFargateRows(results, task, rates)
+
+	if err := printFargate(rows, task, out); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func getFargateTask(ctx *cli.Context) (*fargateTask, error) {
+	t := &fargateTask{
+		osEngine:         ctx.String("os"),
+		architecture:     ctx.String("architecture"),
+		cpu:              ctx.Float64("cpu"),
+		memory:           ctx.Float64("memory"),
+		ephemeralStorage: ctx.Float64("ephemeral-storage"),
+		tasks:            ctx.Int("tasks"),
+	}
+
+	switch t.osEngine {
+	case "Linux":
+		if t.architecture != aws.ArchitectureX86 && t.architecture != aws.ArchitectureArm64 {
+			return nil, fmt.Errorf("Unknown architecture: %s (e.g. %s, %s)", t.architecture, aws.ArchitectureX86, aws.ArchitectureArm64)
+		}
+	case "Windows":
+		if t.architecture != aws.ArchitectureX86 {
+			return nil, fmt.Errorf("Unsupported architecture for Windows: %s", t.architecture)
+		}
+		supported := false
+		for _, cpu := range fargateWindowsVcpus {
+			if t.cpu == cpu {
+				supported = true
+			}
+		}
+		if !supported {
+			return nil, fmt.Errorf("Unsupported vCPU for Windows: %g (e.g. 1, 2, 4)", t.cpu)
+		}
+	default:
+		return nil, fmt.Errorf("Unknown OS: %s (e.g. Linux, Windows)", t.osEngine)
+	}
+
+	r, ok := fargateMemoryRanges[t.cpu]
+	if !ok {
+		return nil, fmt.Errorf("Invalid vCPU: %g (e.g. 0.25, 0.5, 1, 2, 4, 8, 16)", t.cpu)
+	}
+
+	if !r.valid(t.memory) {
+		return nil, fmt.Errorf("Invalid memory for %g vCPU: %g GB (%s)", t.cpu, t.memory, r)
+	}
+
+	if t.ephemeralStorage < fargateFreeEphemeralStorageGB || t.ephemeralStorage > 200 {
+		return nil, fmt.Errorf("Invalid ephemeral storage: %g GB (20 - 200)", t.ephemeralStorage)
+	}
+
+	if t.tasks <= 0 {
+		return nil, fmt.Errorf("Invalid tasks: %d", t.tasks)
+	}
+
+	return t, nil
+}
+
+// getFargateRows groups the products by region. The prices covered by the Savings Plan are replaced with the rates if rates is not nil.
+func getFargateRows(results []bson.M, task *fargateTask, rates *savingsPlanRates) []*fargateRow {
+	rows := map[string]*fargateRow{}
+
+	for _, result := range results {
+		price, err := decodePrice(result)
+		if err != nil {
+			log.Printf("Skip product %v: %v", result["sku"], err)
+			continue
+		}
+
+		if rates != nil {
+			rates.apply(price)
+		}
+
+		row, ok := rows[price.Region]
+		if !ok {
+			row = &fargateRow{Region: price.Region, OSEngine: task.osEngine, Architecture: task.architecture}
+			rows[price.Region] = row
+		}
+
+		if err := row.addPrice(price); err != nil {
+			log.Printf("Skip product %s: %v", price.Sku, err)
+			continue
+		}
+	}
+
+	var r []*fargateRow
+	for _, row := range rows {
+		// Regions without the platform have only the ephemeral storage price.
+		if !row.hasVcpu || !row.hasMemory {
+			continue
+		}
+
+		row.estimate(task)
+		r = append(r, row)
+	}
+
+	sort.Slice(r, func(i, j int) bool {
+		return r[i].Region < r[j].Region
+	})
+
+	return r
+}
+
+// sortPrice returns the hourly cost of the tasks.
+func (r *fargateRow) sortPrice() float64 {
+	return r.Hourly
+}
+
+func (r *fargateRow) addPrice(price *aws.Price) error {
+	var p float64
+	if err := parseFloats([]string{price.OnDemandPricePerUSD}, &p); err != nil {
+		return err
+	}
+
+	switch aws.FargateUsage(price.Product.Attributes.UsageType) {
+	case aws.FargateUsageVcpu:
+		r.VcpuPrice = p
+		r.hasVcpu = true
+	case aws.FargateUsageMemory:
+		r.MemoryPrice = p
+		r.hasMemory = true
+	case aws.FargateUsageWindowsLicense:
+		r.WindowsLicensePrice = p
+	case aws.FargateUsageEphemeralStorage:
+		r.EphemeralStoragePrice = p
+	default:
+		return fmt.Errorf("Unknown usage type: %s", price.Product.Attributes.UsageType)
+	}
+
+	return nil
+}
+
+// estimate calculates the hourly and monthly cost of the tasks.
+// Windows tasks are also charged the OS license per vCPU.
+func (r *fargateRow) estimate(task *fargateTask) {
+	hourly := task.cpu*(r.VcpuPrice+r.WindowsLicensePrice) +
+		task.memory*r.MemoryPrice +
+		math.Max(0, task.ephemeralStorage-fargateFreeEphemeralStorageGB)*r.EphemeralStoragePrice
+
+	r.Hourly = hourly * float64(task.tasks)
+	// 730 hours in a month
+	r.Monthly = r.Hourly * 730
+}
+
+func printFargate(rows []*fargateRow, task *fargateTask, out *output) error {
+	out.header = getFargateHeader()
+
+	for _, row := range rows {
+		out.add(formatFargate(row, task), bson.M{}, row)
+	}
+
+	return out.render(os.Stdout)
+}
+
+func getFargateHeader() []string {
+	return []string{
+		"Region",
+		"OS",
+		"Architecture",
+		"vCPU",
+		"Memory(GB)",
+		"EphemeralStorage(GB)",
+		"Tasks",
+		"vCPUPrice(USD/hour)",
+		"MemoryPrice(USD/GB-hour)",
+		"Price(USD/hour)",
+		"Price(USD/month)",
+	}
+}
+
+func formatFargate(row *fargateRow, task *fargateTask) []string {
+	return []string{
+		na(row.Region),
+		na(row.OSEngine),
+		na(row.Architecture),
+		strconv.FormatFloat(task.cpu, 'f', -1, 64),
+		strconv.FormatFloat(task.memory, 'f', -1, 64),
+		strconv.FormatFloat(task.ephemeralStorage, 'f', -1, 64),
+		strconv.Itoa(task.tasks),
+		formatPrice(row.VcpuPrice + row.WindowsLicensePrice),
+		formatPrice(row.MemoryPrice),
+		formatPrice(row.Hourly),
+		fmt.Sprintf("%.2f", row.Monthly),
+	}
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"flag"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+)
+
+// fargateContext returns the context of the fargate command with the arguments.
+func fargateContext(t *testing.T, args string) *cli.Context {
+	t.Helper()
+
+	set := flag.NewFlagSet("fargate", flag.ContinueOnError)
+	for _, f := range fargateCommand.Flags {
+		if err := f.Apply(set); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := set.Parse(strings.Fields(args)); err != nil {
+		t.Fatal(err)
+	}
+
+	return cli.NewContext(nil, set, nil)
+}
+
+func TestGetFargateTask(t *testing.T) {
+	tests := []struct {
+		args    string
+		wantErr bool
+	}{
+		{"", false},
+		{"--cpu 0.25 --memory 1", false},
+		{"--cpu 0.25 --memory 1.5", true},
+		{"--cpu 0.5 --memory 3", false},
+		{"--cpu 0.5 --memory 2.5", true},
+		{"--cpu 4 --memory 30", false},
+		{"--cpu 4 --memory 31", true},
+		{"--cpu 8 --memory 20", false},
+		{"--cpu 8 --memory 18", true},
+		{"--cpu 16 --memory 120", false},
+		{"--cpu 16 --memory 36", true},
+		{"--cpu 3 --memory 8", true},
+		{"--os Windows --cpu 1 --memory 2", false},
+		{"--os Windows --cpu 4 --memory 30", false},
+		{"--os Windows --cpu 0.5 --memory 1", true},
+		{"--os Windows --cpu 8 --memory 16", true},
+		{"--os Windows --cpu 16 --memory 32", true},
+		{"--os Windows --architecture arm64 --cpu 1 --memory 2", true},
+		{"--architecture arm64 --cpu 16 --memory 32", false},
+		{"--ephemeral-storage 201", true},
+		{"--tasks 0", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			_, err := getFargateTask(fargateContext(t, tt.args))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFargateEstimate(t *testing.T) {
+	// The prices per vCPU-hour and GB-hour of Linux x86_64 in ap-northeast-1.
+	row := &fargateRow{VcpuPrice: 0.05056, MemoryPrice: 0.00553, EphemeralStoragePrice: 0.000123}
+
+	tests := []struct {
+		name string
+		task *fargateTask
+		want float64
+	}{
+		{"1 vCPU 2 GB", &fargateTask{cpu: 1, memory: 2, ephemeralStorage: fargateFreeEphemeralStorageGB, tasks: 1}, 0.05056 + 2*0.00553},
+		{"3 tasks", &fargateTask{cpu: 1, memory: 2, ephemeralStorage: fargateFreeEphemeralStorageGB, tasks: 3}, 3 * (0.05056 + 2*0.00553)},
+		// Only the ephemeral storage over the free 20 GB is charged.
+		{"ephemeral storage", &fargateTask{cpu: 1, memory: 2, ephemeralStorage: 50, tasks: 1}, 0.05056 + 2*0.00553 + 30*0.000123},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			row.estimate(tt.task)
+			if math.Abs(row.Hourly-tt.want) > 1e-9 || math.Abs(row.Monthly-tt.want*730) > 1e-6 {
+				t.Errorf("got %v/hour %v/month, want %v/hour", row.Hourly, row.Monthly, tt.want)
+			}
+			if row.sortPrice() != row.Hourly {
+				t.Errorf("got sort price %v, want the hourly cost %v", row.sortPrice(), row.Hourly)
+			}
+		})
+	}
+}
@@ -8,14 +8,12 @@ import (
 	"time"
 
 	"github.com/sfuruya0612/apf/internal/aws"
-	"github.com/sfuruya0612/apf/internal/mongo"
+	"github.com/sfuruya0612/apf/internal/store"
 	"github.com/urfave/cli/v2"
 )
 
 var (
-	serviceCodes = []string{"AmazonEC2", "AmazonRDS", "AmazonElastiCache"}
-	// skuOfferTermCode := fmt.Sprintf("%s.%s", sku, "JRTCKXETXF")
-	// skuOfferTermCodeRateCode := fmt.Sprintf("%s.%s.%s", sku, "JRTCKXETXF", "6YS6EN2CT7")
+	serviceCodes = []string{"AmazonEC2", "AmazonRDS", "AmazonElastiCache", "AWSLambda", "AmazonECS", "AmazonS3", "AmazonES", "AmazonRedshift", "AWSDataTransfer"}
 )
 
 var FetchCommand = &cli.Command{
@@ -35,24 +33,50 @@ var FetchCommand = &cli.Command{
 			Aliases: []string{"r"},
 			EnvVars: []string{"AWS_REGION"},
 			Value:   "us-east-1",
-			Usage:   "Specify a valid AWS region",
+			Usage:   "Specify a valid AWS region of the Price List API endpoint",
+		},
+		&cli.StringSliceFlag{
+			Name:    "pricing-region",
+			Aliases: []string{"regions"},
+			Value:   cli.NewStringSlice("ap-northeast-1"),
+			Usage:   "Specify region codes to fetch prices for (e.g. ap-northeast-1, us-east-1, all)",
+		},
+		&cli.IntFlag{
+			Name:  "batch-size",
+			Value: 1000,
+			Usage: "Specify the number of products inserted into MongoDB at once",
+		},
+		&cli.IntFlag{
+			Name:  "keep",
+			Value: 3,
+			Usage: "Specify the number of snapshots kept for rollback",
 		},
 	},
+	Subcommands: []*cli.Command{
+		fetchSpotCommand,
+		fetchSavingsPlansCommand,
+	},
 	Action: func(ctx *cli.Context) error {
-		return fetch(ctx.String("profile"), ctx.String("region"), ctx.String("mongo-uri"))
+		return fetch(ctx.String("profile"), ctx.String("region"), getStoreURI(ctx), ctx.StringSlice("pricing-region"), ctx.Int("batch-size"), ctx.Int("keep"))
 	},
 }
 
-func fetch(profile, region, mongoUri string) error {
+func fetch(profile, region, storeUri string, pricingRegions []string, batchSize, keep int) error {
 	cfg, err := aws.Config(profile, region)
 	if err != nil {
 		return fmt.Errorf("Fetch: %w", err)
 	}
 
-	// It takes about 20 minutes to get and insert EC2 price
+	// Fetching EC2 prices for all regions takes a long time
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
 	defer cancel()
 
+	st, err := store.Open(storeUri)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
 	errCh := make(chan error, len(serviceCodes))
 	wg := sync.WaitGroup{}
 	wg.Add(len(serviceCodes))
@@ -65,49 +89,25 @@ func fetch(profile, region, mongoUri string) error {
 			sem <- struct{}{}
 			defer func() { <-sem }()
 
-			products, err := aws.GetProducts(cfg, sc)
-			if err != nil {
-				errCh <- fmt.Errorf("Failed to fetch %s products: %w", sc, err)
-				return
-			}
-
-			conn, err := mongo.Connect(mongoUri)
+			w, err := newPriceWriter(st, sc, batchSize)
 			if err != nil {
-				errCh <- fmt.Errorf("Failed to connect to MongoDB: %w", err)
-				return
-			}
-
-			coll := mongo.Collection(conn, getCollectionName(sc))
-
-			log.Printf("Drop %s collection\n", sc)
-
-			if err := mongo.DropCollection(coll, nil); err != nil {
-				errCh <- fmt.Errorf("Failed to remove %s collection: %w", sc, err)
+				errCh <- err
 				return
 			}
 
-			log.Printf("Inserting %d %s products into MongoDB\n", len(products), sc)
-
-			// TODO: Bulk insert
-			var insertErr error
-			for _, product := range products {
-				if _, err := coll.InsertOne(ctx, product); err != nil {
-					insertErr = fmt.Errorf("Failed to insert %s product %s: %w", sc, *product, err)
-					break
-				}
-			}
-
-			if insertErr != nil {
-				errCh <- insertErr
+			if err := aws.GetProducts(cfg, sc, pricingRegions, func(p []*aws.Price) error {
+				return w.Write(ctx, p)
+			}); err != nil {
+				w.Abort(ctx)
+				errCh <- fmt.Errorf("Failed to fetch %s products: %w", sc, err)
 				return
 			}
 
-			if err := mongo.Disconnect(conn); err != nil {
-				errCh <- fmt.Errorf("Failed to disconnect to MongoDB: %w", err)
+			if err := w.Commit(ctx, keep); err != nil {
+				w.Abort(ctx)
+				errCh <- err
 				return
 			}
-
-			log.Printf("Inserted %d %s products\n", len(products), sc)
 		}(sc)
 	}
 
@@ -119,7 +119,7 @@ func fetch(profile, region, mongoUri string) error {
 		}
 	}
 
-	log.Println("Completed saving AWS Price List data to MongoDB")
+	log.Println("Completed saving AWS Price List data to the store")
 
 	return nil
 }
@@ -132,6 +132,20 @@ func getCollectionName(serviceCode string) string {
 		return "rds"
 	case "AmazonElastiCache":
 		return "elasticache"
+	case "AWSLambda":
+		return "lambda"
+	case "AmazonECS":
+		return "fargate"
+	case "AmazonS3":
+		return "s3"
+	case "AmazonES":
+		return "opensearch"
+	case "AmazonRedshift":
+		return "redshift"
+	case "AWSDataTransfer":
+		return "transfer"
+	case aws.SavingsPlansServiceCode:
+		return "savingsplans"
 	default:
 		panic(fmt.Sprintf("Unknown service code: %s", serviceCode))
 	}
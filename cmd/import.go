@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/sfuruya0612/apf/internal/aws"
+	"github.com/sfuruya0612/apf/internal/store"
+	"github.com/urfave/cli/v2"
+)
+
+var ImportCommand = &cli.Command{
+	Name:  "import",
+	Usage: "Import AWS pricing information from offer files of the bulk Price List API",
+	Flags: []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:    "file",
+			Aliases: []string{"f"},
+			Usage:   "Specify offer files to import (e.g. offer.json)",
+		},
+		&cli.StringFlag{
+			Name:    "dir",
+			Aliases: []string{"d"},
+			Usage:   "Specify a directory of offer files to import (*.json)",
+		},
+		&cli.StringSliceFlag{
+			Name:    "pricing-region",
+			Aliases: []string{"regions"},
+			Value:   cli.NewStringSlice("ap-northeast-1"),
+			Usage:   "Specify region codes to import prices for (e.g. ap-northeast-1, us-east-1, all). The products of the regions are held in memory up to --max-products",
+		},
+		&cli.IntFlag{
+			Name:  "max-products",
+			Value: 100000,
+			Usage: "Specify the number of products of an offer file held in memory, over which the import fails (0: unlimited)",
+		},
+		&cli.IntFlag{
+			Name:  "batch-size",
+			Value: 1000,
+			Usage: "Specify the number of products inserted into MongoDB at once",
+		},
+		&cli.IntFlag{
+			Name:  "keep",
+			Value: 3,
+			Usage: "Specify the number of snapshots kept for rollback",
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		files, err := getOfferFiles(ctx.StringSlice("file"), ctx.String("dir"))
+		if err != nil {
+			return err
+		}
+
+		return importOffers(files, getStoreURI(ctx), ctx.StringSlice("pricing-region"), ctx.Int("max-products"), ctx.Int("batch-size"), ctx.Int("keep"))
+	},
+}
+
+func getOfferFiles(files []string, dir string) ([]string, error) {
+	if dir != "" {
+		matches, err := filepath.Glob(filepath.Join(dir, "*.json"))
+		if err != nil {
+			return nil, fmt.Errorf("Failed to list offer files: %w", err)
+		}
+		sort.Strings(matches)
+
+		files = append(files, matches...)
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("No offer files, specify --file or --dir")
+	}
+
+	return files, nil
+}
+
+func importOffers(files []string, storeUri string, pricingRegions []string, maxProducts, batchSize, keep int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	st, err := store.Open(storeUri)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	// Offer files of the same service (e.g. per region files) are saved into one collection.
+	writers := map[string]*priceWriter{}
+
+	for _, file := range files {
+		log.Printf("Reading %s\n", file)
+
+		if err := readOfferFile(file, pricingRegions, maxProducts, func(sc string, p []*aws.Price) error {
+			w, ok := writers[sc]
+			if !ok {
+				if !isServiceCode(sc) {
+					return fmt.Errorf("Unsupported offer code %s", sc)
+				}
+
+				w, err = newPriceWriter(st, sc, batchSize)
+				if err != nil {
+					return err
+				}
+				writers[sc] = w
+			}
+
+			return w.Write(ctx, p)
+		}); err != nil {
+			abortWriters(ctx, writers)
+			return err
+		}
+	}
+
+	for sc, w := range writers {
+		if err := w.Commit(ctx, keep); err != nil {
+			// Services committed before are kept, because each of them is swapped atomically.
+			delete(writers, sc)
+			w.Abort(ctx)
+			abortWriters(ctx, writers)
+			return err
+		}
+		delete(writers, sc)
+	}
+
+	log.Println("Completed importing AWS Price List data to the store")
+
+	return nil
+}
+
+func abortWriters(ctx context.Context, writers map[string]*priceWriter) {
+	for _, w := range writers {
+		w.Abort(ctx)
+	}
+}
+
+func readOfferFile(file string, pricingRegions []string, maxProducts int, handle func(string, []*aws.Price) error) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("Failed to open offer file: %w", err)
+	}
+	defer f.Close()
+
+	if err := aws.ReadOffer(f, pricingRegions, maxProducts, handle); err != nil {
+		return fmt.Errorf("Failed to read offer file %s: %w", file, err)
+	}
+
+	return nil
+}
+
+func isServiceCode(serviceCode string) bool {
+	for _, sc := range serviceCodes {
+		if sc == serviceCode {
+			return true
+		}
+	}
+
+	return false
+}
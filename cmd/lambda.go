@@ -0,0 +1,342 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/sfuruya0612/apf/internal/aws"
+	"github.com/urfave/cli/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// lambdaFreeEphemeralStorageMB is the ephemeral storage included in the price of a function.
+const lambdaFreeEphemeralStorageMB = 512
+
+var lambdaCommand = &cli.Command{
+	Name:    "lambda",
+	Aliases: []string{"l"},
+	Usage:   "Get Lambda pricing and estimate the monthly cost",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "architecture",
+			Aliases: []string{"a"},
+			Usage:   "Specify a valid architecture (e.g. x86_64, arm64). Both are printed if empty",
+		},
+		&cli.Int64Flag{
+			Name:  "invocations",
+			Usage: "Specify the number of invocations per month to estimate the monthly cost",
+		},
+		&cli.IntFlag{
+			Name:  "memory-mb",
+			Value: 128,
+			Usage: "Specify the memory size of the function in MB (128 - 10240)",
+		},
+		&cli.Float64Flag{
+			Name:  "avg-duration-ms",
+			Usage: "Specify the average duration of an invocation in milliseconds",
+		},
+		&cli.IntFlag{
+			Name:  "ephemeral-storage-mb",
+			Value: lambdaFreeEphemeralStorageMB,
+			Usage: "Specify the ephemeral storage size of the function in MB (512 - 10240)",
+		},
+		&cli.StringFlag{
+			Name:  "savings-plan",
+			Usage: "Use the Compute Savings Plans rates fetched by fetch savings-plans instead of the On-Demand prices (e.g. compute-1yr-no-upfront)",
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		return getLambdaPrice(ctx)
+	},
+}
+
+// lambdaUsage is the monthly usage of a function.
+type lambdaUsage struct {
+	invocations        int64
+	memoryMB           int
+	avgDurationMs      float64
+	ephemeralStorageMB int
+}
+
+// gbSeconds returns the GB-seconds of the size in MB per month.
+func (u *lambdaUsage) gbSeconds(sizeMB int) float64 {
+	return float64(u.invocations) * u.avgDurationMs / 1000 * float64(sizeMB) / 1024
+}
+
+// lambdaTier is a price band of the duration.
+type lambdaTier struct {
+	BeginRange   string  `json:"beginRange" yaml:"beginRange"`
+	EndRange     string  `json:"endRange" yaml:"endRange"`
+	PricePerUnit float64 `json:"pricePerUnit" yaml:"pricePerUnit"`
+}
+
+// lambdaRow is the prices of the region and architecture in USD, and the monthly cost if the usage is given.
+type lambdaRow struct {
+	Region                  string       `json:"region" yaml:"region"`
+	Architecture            string       `json:"architecture" yaml:"architecture"`
+	RequestPrice            float64      `json:"requestPrice" yaml:"requestPrice"`
+	DurationPrice           float64      `json:"durationPrice" yaml:"durationPrice"`
+	DurationTiers           []lambdaTier `json:"durationTiers" yaml:"durationTiers"`
+	EphemeralStoragePrice   float64      `json:"ephemeralStoragePrice" yaml:"ephemeralStoragePrice"`
+	RequestsMonthly         float64      `json:"requestsMonthly,omitempty" yaml:"requestsMonthly,omitempty"`
+	DurationMonthly         float64      `json:"durationMonthly,omitempty" yaml:"durationMonthly,omitempty"`
+	EphemeralStorageMonthly float64      `json:"ephemeralStorageMonthly,omitempty" yaml:"ephemeralStorageMonthly,omitempty"`
+	Monthly                 float64      `json:"monthly,omitempty" yaml:"monthly,omitempty"`
+
+	duration []aws.PriceTier
+}
+
+func getLambdaPrice(ctx *cli.Context) error {
+	if ctx.String("term") != "ondemand" {
+		return fmt.Errorf("Unsupported term for lambda: %s", ctx.String("term"))
+	}
+
+	out, err := newOutput(ctx)
+	if err != nil {
+		return err
+	}
+
+	cond, err := getCondition(ctx)
+	if err != nil {
+		return err
+	}
+
+	usage, err := getLambdaUsage(ctx)
+	if err != nil {
+		return err
+	}
+
+	rates, err := getComputeSavingsPlanRates(ctx, cond, "Lambda")
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{}
+
+	switch arch := ctx.String("architecture"); arch {
+	case "":
+	case aws.ArchitectureX86, aws.ArchitectureArm64:
+		filter["product.attributes.processorarchitecture"] = arch
+	default:
+		return fmt.Errorf("Unknown architecture: %s (e.g. %s, %s)", arch, aws.ArchitectureX86, aws.ArchitectureArm64)
+	}
+
+	// A row is made of several products, so the products are not sorted nor limited by the store.
+	out.order, err = getRowOrder("lambda", cond)
+	if err != nil {
+		return err
+	}
+
+	results, err := findProducts(
+		getStoreURI(ctx),
+		"lambda",
+		cond,
+		filter,
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to find: %w", err)
+	}
+
+	rows, err := getLambdaRows(results, usage, rates)
+	if err != nil {
+		return err
+	}
+
+	if err := printLambda(rows, usage, out); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func getLambdaUsage(ctx *cli.Context) (*lambdaUsage, error) {
+	u := &lambdaUsage{
+		invocations:        ctx.Int64("invocations"),
+		memoryMB:           ctx.Int("memory-mb"),
+		avgDurationMs:      ctx.Float64("avg-duration-ms"),
+		ephemeralStorageMB: ctx.Int("ephemeral-storage-mb"),
+	}
+
+	if u.invocations < 0 {
+		return nil, fmt.Errorf("Invalid invocations: %d", u.invocations)
+	}
+
+	if u.memoryMB < 128 || u.memoryMB > 10240 {
+		return nil, fmt.Errorf("Invalid memory size: %d MB (128 - 10240)", u.memoryMB)
+	}
+
+	if u.avgDurationMs < 0 {
+		return nil, fmt.Errorf("Invalid average duration: %g ms", u.avgDurationMs)
+	}
+
+	if u.ephemeralStorageMB < lambdaFreeEphemeralStorageMB || u.ephemeralStorageMB > 10240 {
+		return nil, fmt.Errorf("Invalid ephemeral storage size: %d MB (512 - 10240)", u.ephemeralStorageMB)
+	}
+
+	return u, nil
+}
+
+// getLambdaRows groups the products by region and architecture. The prices covered by the Savings Plan are replaced with the rates if rates is not nil.
+func getLambdaRows(results []bson.M, usage *lambdaUsage, rates *savingsPlanRates) ([]*lambdaRow, error) {
+	rows := map[string]*lambdaRow{}
+
+	for _, result := range results {
+		price, err := decodePrice(result)
+		if err != nil {
+			log.Printf("Skip product %v: %v", result["sku"], err)
+			continue
+		}
+
+		if rates != nil {
+			rates.apply(price)
+		}
+
+		attr := price.Product.Attributes
+		key := price.Region + "/" + attr.ProcessorArchitecture
+
+		row, ok := rows[key]
+		if !ok {
+			row = &lambdaRow{Region: price.Region, Architecture: attr.ProcessorArchitecture}
+			rows[key] = row
+		}
+
+		if err := row.addPrice(price); err != nil {
+			log.Printf("Skip product %s: %v", price.Sku, err)
+			continue
+		}
+	}
+
+	var r []*lambdaRow
+	for _, row := range rows {
+		if err := row.estimate(usage); err != nil {
+			return nil, fmt.Errorf("Failed to estimate %s %s: %w", row.Region, row.Architecture, err)
+		}
+		r = append(r, row)
+	}
+
+	sort.Slice(r, func(i, j int) bool {
+		if r[i].Region != r[j].Region {
+			return r[i].Region < r[j].Region
+		}
+		return r[i].Architecture > r[j].Architecture
+	})
+
+	return r, nil
+}
+
+// sortPrice returns the monthly cost if the usage is given, or the duration price.
+func (r *lambdaRow) sortPrice() float64 {
+	if r.Monthly > 0 {
+		return r.Monthly
+	}
+
+	return r.DurationPrice
+}
+
+func (r *lambdaRow) addPrice(price *aws.Price) error {
+	var p float64
+	if err := parseFloats([]string{price.OnDemandPricePerUSD}, &p); err != nil {
+		return err
+	}
+
+	switch aws.LambdaUsage(price.Product.Attributes.Group) {
+	case aws.LambdaUsageRequests:
+		r.RequestPrice = p
+	case aws.LambdaUsageDuration:
+		r.DurationPrice = p
+		r.duration = price.OnDemandTiers
+		for _, t := range price.OnDemandTiers {
+			tier := lambdaTier{
+				BeginRange:   formatPrice(t.BeginRange),
+				EndRange:     "Inf",
+				PricePerUnit: t.PricePerUnitUSD,
+			}
+			if t.EndRange > 0 {
+				tier.EndRange = formatPrice(t.EndRange)
+			}
+
+			r.DurationTiers = append(r.DurationTiers, tier)
+		}
+	case aws.LambdaUsageEphemeralStorage:
+		r.EphemeralStoragePrice = p
+	default:
+		return fmt.Errorf("Unknown group: %s", price.Product.Attributes.Group)
+	}
+
+	return nil
+}
+
+// estimate calculates the monthly cost. The free tier is not applied.
+func (r *lambdaRow) estimate(usage *lambdaUsage) error {
+	if usage.invocations == 0 {
+		return nil
+	}
+
+	r.RequestsMonthly = float64(usage.invocations) * r.RequestPrice
+	r.DurationMonthly = aws.TieredCostUSD(r.duration, usage.gbSeconds(usage.memoryMB))
+	r.EphemeralStorageMonthly = usage.gbSeconds(usage.ephemeralStorageMB-lambdaFreeEphemeralStorageMB) * r.EphemeralStoragePrice
+	r.Monthly = r.RequestsMonthly + r.DurationMonthly + r.EphemeralStorageMonthly
+
+	return nil
+}
+
+func printLambda(rows []*lambdaRow, usage *lambdaUsage, out *output) error {
+	out.header = getLambdaHeader(usage)
+
+	for _, row := range rows {
+		out.add(formatLambda(row, usage), bson.M{}, row)
+	}
+
+	return out.render(os.Stdout)
+}
+
+func getLambdaHeader(usage *lambdaUsage) []string {
+	header := []string{
+		"Region",
+		"Architecture",
+		"RequestPrice(USD/1M requests)",
+		"DurationPrice(USD/GB-second)",
+		"EphemeralStoragePrice(USD/GB-second)",
+	}
+
+	if usage.invocations > 0 {
+		header = append(header,
+			"Requests(USD/month)",
+			"Duration(USD/month)",
+			"EphemeralStorage(USD/month)",
+			"Total(USD/month)",
+		)
+	}
+
+	return header
+}
+
+func formatLambda(row *lambdaRow, usage *lambdaUsage) []string {
+	fields := []string{
+		na(row.Region),
+		na(row.Architecture),
+		formatPrice(row.RequestPrice * 1000000),
+		formatPrice(row.DurationPrice),
+		formatPrice(row.EphemeralStoragePrice),
+	}
+
+	if usage.invocations > 0 {
+		fields = append(fields,
+			fmt.Sprintf("%.2f", row.RequestsMonthly),
+			fmt.Sprintf("%.2f", row.DurationMonthly),
+			fmt.Sprintf("%.2f", row.EphemeralStorageMonthly),
+			fmt.Sprintf("%.2f", row.Monthly),
+		)
+	}
+
+	return fields
+}
+
+// formatPrice formats the unit price without trailing zeros.
+func formatPrice(v float64) string {
+	s := strings.TrimRight(fmt.Sprintf("%.10f", v), "0")
+	return strings.TrimSuffix(s, ".")
+}
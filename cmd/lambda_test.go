@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"math"
+	"testing"
+
+	"github.com/sfuruya0612/apf/internal/aws"
+)
+
+func TestLambdaSortPrice(t *testing.T) {
+	row := &lambdaRow{DurationPrice: 0.0000166667}
+	if got := row.sortPrice(); got != 0.0000166667 {
+		t.Errorf("got %v without the usage, want the duration price", got)
+	}
+
+	row.Monthly = 12.5
+	if got := row.sortPrice(); got != 12.5 {
+		t.Errorf("got %v with the usage, want the monthly cost", got)
+	}
+}
+
+func TestLambdaEstimate(t *testing.T) {
+	// x86_64 of us-east-1
+	row := &lambdaRow{
+		RequestPrice:          0.0000002,
+		EphemeralStoragePrice: 0.0000000309,
+		duration: []aws.PriceTier{
+			{EndRange: 6e9, PricePerUnitUSD: 0.0000166667},
+			{BeginRange: 6e9, EndRange: 15e9, PricePerUnitUSD: 0.000015},
+			{BeginRange: 15e9, PricePerUnitUSD: 0.0000133334},
+		},
+	}
+
+	// 10M invocations of 200 ms with 1024 MB are 2M GB-seconds, and 1024 MB of the ephemeral storage are 1M GB-seconds over the free 512 MB.
+	usage := &lambdaUsage{invocations: 1e7, memoryMB: 1024, avgDurationMs: 200, ephemeralStorageMB: 1024}
+	if err := row.estimate(usage); err != nil {
+		t.Fatal(err)
+	}
+
+	want := 1e7*0.0000002 + 2e6*0.0000166667 + 1e6*0.0000000309
+	if math.Abs(row.Monthly-want) > 1e-9 || row.sortPrice() != row.Monthly {
+		t.Errorf("got %v, want %v", row.Monthly, want)
+	}
+}
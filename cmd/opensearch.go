@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/sfuruya0612/apf/internal/aws"
+	"github.com/urfave/cli/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var opensearchCommand = &cli.Command{
+	Name:    "opensearch",
+	Aliases: []string{"es"},
+	Usage:   "Get OpenSearch Service pricing",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:    "ultrawarm",
+			Aliases: []string{"u"},
+			Usage:   "Get UltraWarm nodes instead of data nodes",
+		},
+		&cli.StringFlag{
+			Name:  "storage",
+			Usage: "Specify a valid storage type (e.g. EBS, Instance store)",
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		return getOpensearchPrice(ctx)
+	},
+}
+
+func getOpensearchPrice(ctx *cli.Context) error {
+	term, err := getPriceTerm(ctx)
+	if err != nil {
+		return err
+	}
+
+	out, err := newOutput(ctx)
+	if err != nil {
+		return err
+	}
+
+	cond, err := getCondition(ctx)
+	if err != nil {
+		return err
+	}
+	out.order = term.rowOrder(cond)
+
+	filter := bson.M{"product.attributes.ultrawarm": "No"}
+	if ctx.Bool("ultrawarm") {
+		filter["product.attributes.ultrawarm"] = "Yes"
+	}
+
+	if storage := ctx.String("storage"); storage != "" {
+		filter["product.attributes.storagemedia"] = storage
+	}
+
+	results, err := findProducts(
+		getStoreURI(ctx),
+		"opensearch",
+		cond,
+		term.condition(filter),
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to find: %w", err)
+	}
+
+	if err := printOpensearch(results, term, out); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func printOpensearch(results []bson.M, term *priceTerm, out *output) error {
+	out.header = append(getOpensearchHeader(), term.header()...)
+
+	for _, result := range results {
+		price, err := decodePrice(result)
+		if err != nil {
+			log.Printf("Skip product %v: %v", result["sku"], err)
+			continue
+		}
+
+		prices, err := term.prices(price)
+		if err != nil {
+			log.Printf("Skip product %s: %v", price.Sku, err)
+			continue
+		}
+
+		for _, p := range prices {
+			out.add(append(formatOpensearch(price), p.fields...), result, p)
+		}
+	}
+
+	return out.render(os.Stdout)
+}
+
+func getOpensearchHeader() []string {
+	return []string{
+		"Service",
+		"Region",
+		"InstanceType",
+		"vCPU",
+		"Memory",
+		"Storage",
+		"StorageType",
+		"UltraWarm",
+	}
+}
+
+func formatOpensearch(price *aws.Price) []string {
+	attr := price.Product.Attributes
+
+	fields := []string{
+		na(price.ServiceCode),
+		na(attr.RegionCode),
+		na(attr.InstanceType),
+		na(attr.Vcpu),
+		na(attr.Memory),
+		na(attr.Storage),
+		na(attr.StorageMedia),
+		na(attr.UltraWarm),
+	}
+
+	return fields
+}
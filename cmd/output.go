@@ -0,0 +1,260 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"gopkg.in/yaml.v3"
+)
+
+var outputFormats = []string{"table", "json", "jsonl", "csv", "yaml", "markdown"}
+
+// output is the result of a price subcommand.
+// Table formats print the columns, and the others print the records with the full attribute set.
+type output struct {
+	format string
+	// columns are the header names printed by table formats. All columns are printed if empty.
+	columns []string
+
+	header  []string
+	rows    [][]string
+	records []interface{}
+
+	// order sorts and limits the rows before rendering, if the rows are not the products of the store.
+	order *rowOrder
+}
+
+// rowOrder is the order of the rows computed from the products (e.g. the offers of reserved term).
+type rowOrder struct {
+	// byPrice sorts the rows by the prices of the records.
+	byPrice    bool
+	descending bool
+	// limit is the maximum number of rows. All rows are kept if 0.
+	limit int64
+}
+
+// sortablePrice is the price of a record sorted by rowOrder.
+type sortablePrice interface {
+	sortPrice() float64
+}
+
+func newOutput(ctx *cli.Context) (*output, error) {
+	format := ctx.String("output")
+	if !contains(outputFormats, format) {
+		return nil, fmt.Errorf("Unknown output format: %s (e.g. %s)", format, strings.Join(outputFormats, ", "))
+	}
+
+	return &output{
+		format:  format,
+		columns: ctx.StringSlice("columns"),
+	}, nil
+}
+
+// add appends a row of the columns and a record of the result with its price.
+func (o *output) add(fields []string, result bson.M, price interface{}) {
+	record := bson.M{}
+	for k, v := range result {
+		if k == "_id" {
+			continue
+		}
+		record[k] = v
+	}
+	record["price"] = price
+
+	o.rows = append(o.rows, fields)
+	o.records = append(o.records, record)
+}
+
+// sortAndLimit sorts the rows and the records stably by the order, and keeps the first rows of the limit.
+func (o *output) sortAndLimit() {
+	if o.order == nil {
+		return
+	}
+
+	if o.order.byPrice {
+		price := func(record interface{}) float64 {
+			m, _ := record.(bson.M)
+			if p, ok := m["price"].(sortablePrice); ok {
+				return p.sortPrice()
+			}
+			return 0
+		}
+
+		indexes := make([]int, len(o.records))
+		for i := range indexes {
+			indexes[i] = i
+		}
+		sort.SliceStable(indexes, func(i, j int) bool {
+			x, y := price(o.records[indexes[i]]), price(o.records[indexes[j]])
+			if o.order.descending {
+				return x > y
+			}
+			return x < y
+		})
+
+		rows := make([][]string, len(indexes))
+		records := make([]interface{}, len(indexes))
+		for i, index := range indexes {
+			rows[i] = o.rows[index]
+			records[i] = o.records[index]
+		}
+		o.rows, o.records = rows, records
+	}
+
+	if limit := o.order.limit; limit > 0 && int64(len(o.records)) > limit {
+		o.rows, o.records = o.rows[:limit], o.records[:limit]
+	}
+}
+
+func (o *output) render(w io.Writer) error {
+	o.sortAndLimit()
+
+	switch o.format {
+	case "table", "csv", "markdown":
+		if err := o.selectColumns(); err != nil {
+			return err
+		}
+	}
+
+	switch o.format {
+	case "table":
+		return o.renderTable(w)
+	case "csv":
+		return o.renderCsv(w)
+	case "markdown":
+		return o.renderMarkdown(w)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(o.records); err != nil {
+			return fmt.Errorf("Failed to print json: %w", err)
+		}
+	case "jsonl":
+		enc := json.NewEncoder(w)
+		for _, record := range o.records {
+			if err := enc.Encode(record); err != nil {
+				return fmt.Errorf("Failed to print json: %w", err)
+			}
+		}
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(o.records); err != nil {
+			return fmt.Errorf("Failed to print yaml: %w", err)
+		}
+		if err := enc.Close(); err != nil {
+			return fmt.Errorf("Failed to print yaml: %w", err)
+		}
+	default:
+		return fmt.Errorf("Unknown output format: %s", o.format)
+	}
+
+	return nil
+}
+
+// selectColumns keeps the columns in the order of the column names, which are case-insensitive.
+func (o *output) selectColumns() error {
+	if len(o.columns) == 0 {
+		return nil
+	}
+
+	var indexes []int
+	for _, column := range o.columns {
+		index := -1
+		for i, h := range o.header {
+			if strings.EqualFold(h, column) {
+				index = i
+				break
+			}
+		}
+
+		if index < 0 {
+			return fmt.Errorf("Unknown column: %s (e.g. %s)", column, strings.Join(o.header, ", "))
+		}
+		indexes = append(indexes, index)
+	}
+
+	pick := func(cells []string) []string {
+		picked := make([]string, 0, len(indexes))
+		for _, i := range indexes {
+			picked = append(picked, cells[i])
+		}
+		return picked
+	}
+
+	o.header = pick(o.header)
+	for i, row := range o.rows {
+		o.rows[i] = pick(row)
+	}
+
+	return nil
+}
+
+func (o *output) renderTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 1, ' ', 0)
+
+	if _, err := fmt.Fprintln(tw, strings.Join(o.header, "\t")); err != nil {
+		return fmt.Errorf("Failed to print header: %w", err)
+	}
+
+	for _, row := range o.rows {
+		if _, err := fmt.Fprintln(tw, strings.Join(row, "\t")); err != nil {
+			return fmt.Errorf("Failed to print result: %w", err)
+		}
+	}
+
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("Failed to flush: %w", err)
+	}
+
+	return nil
+}
+
+func (o *output) renderCsv(w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write(o.header); err != nil {
+		return fmt.Errorf("Failed to print header: %w", err)
+	}
+
+	if err := cw.WriteAll(o.rows); err != nil {
+		return fmt.Errorf("Failed to print result: %w", err)
+	}
+
+	return nil
+}
+
+func (o *output) renderMarkdown(w io.Writer) error {
+	escape := strings.NewReplacer("|", `\|`, "\n", " ")
+
+	line := func(cells []string) string {
+		escaped := make([]string, len(cells))
+		for i, c := range cells {
+			escaped[i] = escape.Replace(c)
+		}
+		return "| " + strings.Join(escaped, " | ") + " |"
+	}
+
+	separator := make([]string, len(o.header))
+	for i := range separator {
+		separator[i] = "---"
+	}
+
+	lines := []string{line(o.header), line(separator)}
+	for _, row := range o.rows {
+		lines = append(lines, line(row))
+	}
+
+	if _, err := fmt.Fprintln(w, strings.Join(lines, "\n")); err != nil {
+		return fmt.Errorf("Failed to print result: %w", err)
+	}
+
+	return nil
+}
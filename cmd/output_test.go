@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sfuruya0612/apf/internal/aws"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// reservedPrice has the standard offers of m5.large Linux in us-east-1.
+func reservedPrice(sku string) *aws.Price {
+	return &aws.Price{
+		Sku: sku,
+		Reserved: []aws.ReservedTerm{
+			{LeaseContractLength: "1yr", OfferingClass: "standard", PurchaseOption: "All Upfront", UpfrontFeeUSD: "480", HourlyPriceUSD: "0"},
+			{LeaseContractLength: "1yr", OfferingClass: "standard", PurchaseOption: "No Upfront", UpfrontFeeUSD: "0", HourlyPriceUSD: "0.0600000000"},
+			{LeaseContractLength: "3yr", OfferingClass: "standard", PurchaseOption: "All Upfront", UpfrontFeeUSD: "1003", HourlyPriceUSD: "0"},
+			{LeaseContractLength: "3yr", OfferingClass: "standard", PurchaseOption: "Partial Upfront", UpfrontFeeUSD: "532", HourlyPriceUSD: "0.0200000000"},
+		},
+	}
+}
+
+func TestRowOrder(t *testing.T) {
+	tests := []struct {
+		name       string
+		sortBy     string
+		descending bool
+		limit      int64
+		want       []string
+		// wantCond is the sort key and the limit left to the store.
+		wantSortBy string
+	}{
+		{"store order", "", false, 0, []string{"A 1yr All Upfront", "A 1yr No Upfront", "A 3yr All Upfront", "A 3yr Partial Upfront", "B 1yr All Upfront", "B 1yr No Upfront", "B 3yr All Upfront", "B 3yr Partial Upfront"}, ""},
+		{"limit rows", "", false, 3, []string{"A 1yr All Upfront", "A 1yr No Upfront", "A 3yr All Upfront"}, ""},
+		{"effective price", "price", false, 3, []string{"A 3yr All Upfront", "B 3yr All Upfront", "A 3yr Partial Upfront"}, ""},
+		{"effective price desc", "price", true, 2, []string{"A 1yr No Upfront", "B 1yr No Upfront"}, ""},
+		{"product key", "vcpu", false, 2, []string{"A 1yr All Upfront", "A 1yr No Upfront"}, "vcpu"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			term := &priceTerm{term: "reserved"}
+			cond := &condition{sortBy: tt.sortBy, descending: tt.descending, limit: tt.limit}
+
+			out := &output{format: "json", order: term.rowOrder(cond)}
+			if cond.sortBy != tt.wantSortBy || cond.limit != 0 {
+				t.Errorf("got sort by %q and limit %d left to the store", cond.sortBy, cond.limit)
+			}
+
+			for _, sku := range []string{"A", "B"} {
+				prices, err := term.prices(reservedPrice(sku))
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, p := range prices {
+					out.add([]string{sku + " " + p.LeaseContractLength + " " + p.PurchaseOption}, bson.M{"sku": sku}, p)
+				}
+			}
+			out.sortAndLimit()
+
+			var got []string
+			for i, row := range out.rows {
+				got = append(got, row[0])
+
+				// The records follow the rows.
+				record := out.records[i].(bson.M)
+				if p := record["price"].(*priceRow); row[0] != record["sku"].(string)+" "+p.LeaseContractLength+" "+p.PurchaseOption {
+					t.Errorf("record %v does not match row %v", record, row)
+				}
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// On-Demand rows are the products, so the store sorts and limits them.
+	cond := &condition{sortBy: "price", limit: 3}
+	if order := (&priceTerm{term: "ondemand"}).rowOrder(cond); order != nil || cond.sortBy != "price" || cond.limit != 3 {
+		t.Errorf("got order %v and condition %+v for On-Demand", order, cond)
+	}
+}
+
+// labeledRow is a row computed from the products with the label printed as the table row.
+type labeledRow struct {
+	label string
+	price sortablePrice
+}
+
+func TestGetRowOrder(t *testing.T) {
+	lambdaRows := []labeledRow{
+		{"ap-northeast-1/x86_64", &lambdaRow{DurationPrice: 0.0000166667}},
+		{"ap-northeast-1/arm64", &lambdaRow{DurationPrice: 0.0000133334}},
+		{"us-east-1/x86_64", &lambdaRow{DurationPrice: 0.0000166667, Monthly: 0}},
+	}
+	monthlyRows := []labeledRow{
+		{"ap-northeast-1", &ebsRow{Monthly: 48}},
+		{"eu-west-1", &ebsRow{Monthly: 44}},
+		{"us-east-1", &ebsRow{Monthly: 40}},
+	}
+
+	tests := []struct {
+		name    string
+		service string
+		rows    []labeledRow
+		cond    *condition
+		want    []string
+	}{
+		{"lambda region order", "lambda", lambdaRows, &condition{}, []string{"ap-northeast-1/x86_64", "ap-northeast-1/arm64", "us-east-1/x86_64"}},
+		{"lambda price", "lambda", lambdaRows, &condition{sortBy: "price"}, []string{"ap-northeast-1/arm64", "ap-northeast-1/x86_64", "us-east-1/x86_64"}},
+		{"lambda price desc with limit", "lambda", lambdaRows, &condition{sortBy: "price", descending: true, limit: 1}, []string{"ap-northeast-1/x86_64"}},
+		{"ebs monthly with limit", "ebs", monthlyRows, &condition{sortBy: "price", limit: 2}, []string{"us-east-1", "eu-west-1"}},
+		{"limit without sort", "ebs", monthlyRows, &condition{limit: 1}, []string{"ap-northeast-1"}},
+		{
+			"fargate hourly desc", "fargate",
+			[]labeledRow{
+				{"ap-northeast-1", &fargateRow{Hourly: 0.0616}},
+				{"sa-east-1", &fargateRow{Hourly: 0.0848}},
+				{"us-east-1", &fargateRow{Hourly: 0.0494}},
+			},
+			&condition{sortBy: "price", descending: true},
+			[]string{"sa-east-1", "ap-northeast-1", "us-east-1"},
+		},
+		{
+			"s3 storage price without the usage", "s3",
+			[]labeledRow{
+				{"ap-northeast-1", &s3Row{StoragePrice: 0.025}},
+				{"us-east-1", &s3Row{StoragePrice: 0.023}},
+			},
+			&condition{sortBy: "price"},
+			[]string{"us-east-1", "ap-northeast-1"},
+		},
+		{
+			"transfer per-GB price", "transfer",
+			[]labeledRow{
+				{"internet", &transferRow{PricePerGB: 0.114}},
+				{"us-east-1", &transferRow{PricePerGB: 0.09}},
+				{"ap-northeast-1", &transferRow{PricePerGB: 0.01}},
+			},
+			&condition{sortBy: "price", limit: 2},
+			[]string{"ap-northeast-1", "us-east-1"},
+		},
+		{
+			"transfer monthly cost", "transfer",
+			[]labeledRow{
+				{"internet", &transferRow{GB: 101, PricePerGB: 0.114, Monthly: 11.4}},
+				{"us-east-1", &transferRow{GB: 101, PricePerGB: 0.09, Monthly: 9.09}},
+			},
+			&condition{sortBy: "price", descending: true},
+			[]string{"internet", "us-east-1"},
+		},
+		{
+			"rds serverless total", "rds",
+			[]labeledRow{
+				{"ap-northeast-1", &rdsServerlessRow{TotalMonthlyMax: 900}},
+				{"us-east-1", &rdsServerlessRow{TotalMonthlyMax: 700}},
+			},
+			&condition{sortBy: "price"},
+			[]string{"us-east-1", "ap-northeast-1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, err := getRowOrder(tt.service, tt.cond)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The rows are computed from several products, so nothing is left to the store.
+			if tt.cond.sortBy != "" || tt.cond.limit != 0 {
+				t.Errorf("got condition %+v left to the store", tt.cond)
+			}
+
+			out := &output{format: "json", order: order}
+			for _, row := range tt.rows {
+				out.add([]string{row.label}, bson.M{}, row.price)
+			}
+			out.sortAndLimit()
+
+			var got []string
+			for _, row := range out.rows {
+				got = append(got, row[0])
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	for _, key := range []string{"vcpu", "memory", "instance-type"} {
+		if _, err := getRowOrder("lambda", &condition{sortBy: key}); err == nil {
+			t.Errorf("got no error sorting by %s", key)
+		}
+	}
+}
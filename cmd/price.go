@@ -1,11 +1,19 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/sfuruya0612/apf/internal/mongo"
+	"github.com/sfuruya0612/apf/internal/aws"
+	"github.com/sfuruya0612/apf/internal/store"
+	"github.com/sfuruya0612/apf/internal/utils"
 	"github.com/urfave/cli/v2"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 var PriceCommand = &cli.Command{
@@ -13,6 +21,11 @@ var PriceCommand = &cli.Command{
 	Usage:   "Get AWS pricing",
 	Aliases: []string{"p"},
 	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "region",
+			Aliases: []string{"r"},
+			Usage:   "Specify a valid region code (e.g. ap-northeast-1, us-east-1)",
+		},
 		&cli.StringFlag{
 			Name:    "instance-type",
 			Aliases: []string{"i"},
@@ -26,7 +39,65 @@ var PriceCommand = &cli.Command{
 		&cli.StringFlag{
 			Name:    "memory",
 			Aliases: []string{"mem"},
-			Usage:   "Specify a valid memory",
+			Usage:   "Specify a memory in GiB, matched within 1% (e.g. 8, 13.07 GiB, 512 MiB)",
+		},
+		&cli.Float64Flag{
+			Name:  "min-vcpu",
+			Usage: "Specify a minimum number of vCPU",
+		},
+		&cli.Float64Flag{
+			Name:  "max-vcpu",
+			Usage: "Specify a maximum number of vCPU",
+		},
+		&cli.Float64Flag{
+			Name:  "min-memory",
+			Usage: "Specify a minimum memory in GiB",
+		},
+		&cli.Float64Flag{
+			Name:  "max-memory",
+			Usage: "Specify a maximum memory in GiB",
+		},
+		&cli.Float64Flag{
+			Name:  "max-price",
+			Usage: "Specify a maximum price in USD/hour (the effective price for reserved term)",
+		},
+		&cli.StringFlag{
+			Name:  "sort-by",
+			Usage: "Specify a key to sort by (e.g. price, vcpu, memory, instance-type)",
+		},
+		&cli.StringFlag{
+			Name:  "order",
+			Value: "asc",
+			Usage: "Specify a sort order (e.g. asc, desc)",
+		},
+		&cli.Int64Flag{
+			Name:  "limit",
+			Usage: "Specify a maximum number of products (default: no limit)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "columns",
+			Usage: "Specify columns printed by table, csv and markdown output (e.g. InstanceType,OnDemandPrice(USD/hour))",
+		},
+		&cli.StringFlag{
+			Name:  "term",
+			Value: "ondemand",
+			Usage: "Specify a pricing term (e.g. ondemand, reserved)",
+		},
+		&cli.StringFlag{
+			Name:  "lease",
+			Usage: "Specify a lease contract length of reserved term (e.g. 1yr, 3yr)",
+		},
+		&cli.StringFlag{
+			Name:  "purchase-option",
+			Usage: "Specify a purchase option of reserved term (e.g. no, partial, all)",
+		},
+		&cli.StringFlag{
+			Name:  "offering-class",
+			Usage: "Specify an offering class of reserved term (e.g. standard, convertible)",
+		},
+		&cli.StringFlag{
+			Name:  "as-of",
+			Usage: "Specify a date to get the prices effective at (e.g. 2023-03-01, 2023-03-01T09:00:00+09:00)",
 		},
 	},
 	Subcommands: servicesCommand,
@@ -36,47 +107,589 @@ var servicesCommand = []*cli.Command{
 	ec2Command,
 	rdsCommand,
 	elasticacheCommand,
+	lambdaCommand,
+	ebsCommand,
+	fargateCommand,
+	s3Command,
+	opensearchCommand,
+	redshiftCommand,
+	transferCommand,
+}
+
+// condition is the common condition of the price subcommands.
+type condition struct {
+	region       string
+	instanceType string
+	vcpu         string
+	asOf         time.Time
+
+	// Ranges are not applied if nil.
+	memory    *float64
+	minVcpu   *float64
+	maxVcpu   *float64
+	minMemory *float64
+	maxMemory *float64
+
+	sortBy     string
+	descending bool
+	limit      int64
+}
+
+// sortKeys are the keys of --sort-by.
+var sortKeys = map[string]string{
+	"price":         "ondemandhourlyusd",
+	"vcpu":          "vcpucount",
+	"memory":        "memorygib",
+	"instance-type": "product.attributes.instancetype",
+}
+
+// flagValues are the values of the flags given by the command line, or by the query parameters of apf serve.
+type flagValues interface {
+	String(name string) string
+	Int64(name string) int64
+	Float64(name string) float64
+	Bool(name string) bool
+	IsSet(name string) bool
 }
 
-func findMongo(mongoUri, collection, instanceType, vcpu, memory string, filter bson.M) ([]bson.M, error) {
-	conn, err := mongo.Connect(mongoUri)
+func getCondition(ctx flagValues) (*condition, error) {
+	asOf, err := parseAsOf(ctx.String("as-of"))
 	if err != nil {
-		return nil, fmt.Errorf("Failed to connect to MongoDB: %w", err)
+		return nil, err
+	}
+
+	sortBy := ctx.String("sort-by")
+	if _, ok := sortKeys[sortBy]; sortBy != "" && !ok {
+		return nil, fmt.Errorf("Unknown sort key: %s (e.g. price, vcpu, memory, instance-type)", sortBy)
 	}
 
-	coll := mongo.Collection(conn, collection)
+	var descending bool
+	switch ctx.String("order") {
+	case "asc":
+	case "desc":
+		descending = true
+	default:
+		return nil, fmt.Errorf("Unknown order: %s (e.g. asc, desc)", ctx.String("order"))
+	}
 
-	f := appendCondition(filter, instanceType, vcpu, memory)
+	if ctx.Int64("limit") < 0 {
+		return nil, fmt.Errorf("Invalid limit: %d", ctx.Int64("limit"))
+	}
 
-	results, err := mongo.Find(coll, f, nil)
+	memory, err := parseMemory(ctx.String("memory"))
 	if err != nil {
-		return nil, fmt.Errorf("Failed to find: %w", err)
+		return nil, err
+	}
+
+	return &condition{
+		region:       ctx.String("region"),
+		instanceType: ctx.String("instance-type"),
+		vcpu:         ctx.String("vcpu"),
+		memory:       memory,
+		asOf:         asOf,
+		minVcpu:      getFloat(ctx, "min-vcpu"),
+		maxVcpu:      getFloat(ctx, "max-vcpu"),
+		minMemory:    getFloat(ctx, "min-memory"),
+		maxMemory:    getFloat(ctx, "max-memory"),
+		sortBy:       sortBy,
+		descending:   descending,
+		limit:        ctx.Int64("limit"),
+	}, nil
+}
+
+func (c *condition) findOptions() *store.FindOptions {
+	opt := &store.FindOptions{Limit: c.limit}
+
+	if c.sortBy != "" {
+		opt.Sort = []store.SortKey{{Key: sortKeys[c.sortBy], Descending: c.descending}}
+	}
+
+	return opt
+}
+
+func getFloat(ctx flagValues, name string) *float64 {
+	if !ctx.IsSet(name) {
+		return nil
+	}
+
+	v := ctx.Float64(name)
+	return &v
+}
+
+// errNoResults is returned by findProducts if no product is found.
+var errNoResults = errors.New("No results")
+
+// findProducts opens the store of the URI and finds the products, and it is an error if none is found.
+func findProducts(storeUri, collection string, cond *condition, filter bson.M) ([]bson.M, error) {
+	st, err := store.Open(storeUri)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := findStore(st, context.Background(), collection, cond, filter)
+	if err != nil {
+		st.Close()
+		return nil, err
 	}
 
-	if err := mongo.Disconnect(conn); err != nil {
-		return nil, fmt.Errorf("Failed to disconnect to MongoDB: %w", err)
+	if err := st.Close(); err != nil {
+		return nil, fmt.Errorf("Failed to disconnect to store: %w", err)
 	}
 
 	// I'm not sure about returning it with an error
 	if len(results) == 0 {
-		return nil, fmt.Errorf("No results")
+		return nil, errNoResults
+	}
+
+	return results, nil
+}
+
+// findStore finds the products in the opened store, so the connection can be shared (e.g. apf serve).
+func findStore(st store.Store, ctx context.Context, collection string, cond *condition, filter bson.M) ([]bson.M, error) {
+	f := appendCondition(filter, cond)
+
+	var results []bson.M
+	var err error
+	if cond.asOf.IsZero() {
+		results, err = st.Find(ctx, collection, f, cond.findOptions())
+	} else {
+		results, err = findAsOf(st, ctx, collection, f, cond.asOf)
+		results = store.SortAndLimit(results, cond.findOptions())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to find: %w", err)
 	}
 
 	return results, nil
 }
 
-func appendCondition(filter bson.M, instanceType, vcpu, memory string) bson.M {
-	if instanceType != "" {
-		filter["product.attributes.instancetype"] = instanceType
+func appendCondition(filter bson.M, cond *condition) bson.M {
+	if cond.region != "" {
+		filter["region"] = cond.region
+	}
+
+	if cond.instanceType != "" {
+		filter["product.attributes.instancetype"] = cond.instanceType
+	}
+
+	if cond.vcpu != "" {
+		filter["product.attributes.vcpu"] = cond.vcpu
 	}
 
-	if vcpu != "" {
-		filter["product.attributes.vcpu"] = vcpu
+	appendRange(filter, "vcpucount", cond.minVcpu, cond.maxVcpu)
+
+	minMemory, maxMemory := cond.minMemory, cond.maxMemory
+	if cond.memory != nil {
+		// The memory attributes are rounded (e.g. 13.07 GiB), so --memory matches within the tolerance.
+		min := *cond.memory * (1 - memoryTolerance)
+		max := *cond.memory * (1 + memoryTolerance)
+		if minMemory == nil || *minMemory < min {
+			minMemory = &min
+		}
+		if maxMemory == nil || *maxMemory > max {
+			maxMemory = &max
+		}
 	}
+	appendRange(filter, "memorygib", minMemory, maxMemory)
 
-	if memory != "" {
-		filter["product.attributes.memory"] = memory + " GiB"
+	if !cond.asOf.IsZero() {
+		// effectiveDate is formatted in RFC 3339 with UTC, so it can be compared as a string.
+		filter["ondemand.effectivedate"] = bson.M{"$lte": cond.asOf.UTC().Format(time.RFC3339)}
 	}
 
 	return filter
 }
+
+// memoryTolerance is the relative tolerance of --memory. The sizes of the instance types differ more than it.
+const memoryTolerance = 0.01
+
+// parseMemory parses --memory into GiB, and returns nil if it is not given.
+func parseMemory(memory string) (*float64, error) {
+	if memory == "" {
+		return nil, nil
+	}
+
+	v, err := aws.ParseMemoryGiB(memory)
+	if err != nil || v <= 0 {
+		return nil, fmt.Errorf("Invalid memory: %s (e.g. 8, 13.07 GiB, 512 MiB)", memory)
+	}
+
+	return &v, nil
+}
+
+func appendRange(filter bson.M, key string, min, max *float64) {
+	r := bson.M{}
+	if min != nil {
+		r["$gte"] = *min
+	}
+	if max != nil {
+		r["$lte"] = *max
+	}
+
+	if len(r) > 0 {
+		filter[key] = r
+	}
+}
+
+func parseAsOf(asOf string) (time.Time, error) {
+	if asOf == "" {
+		return time.Time{}, nil
+	}
+
+	for _, layout := range []string{"2006-01-02", time.RFC3339} {
+		if t, err := time.Parse(layout, asOf); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("Invalid date: %s (e.g. 2023-03-01, 2023-03-01T09:00:00+09:00)", asOf)
+}
+
+// findAsOf resolves the price of each SKU effective at the date from the snapshots of the collection.
+// The SKUs are those of the latest snapshot fetched at or before the date, and each of them takes the price
+// with the latest effective date up to the date, which may be fetched after the date.
+// Dates before the oldest snapshot kept by --keep of fetch are an error, since the SKUs offered then are unknown.
+func findAsOf(st store.Store, ctx context.Context, collection string, filter bson.M, asOf time.Time) ([]bson.M, error) {
+	ids, err := getSnapshots(st, ctx, collection)
+	if err != nil {
+		return nil, err
+	}
+
+	// Collections fetched before snapshots were taken.
+	if len(ids) == 0 {
+		return st.Find(ctx, collection, filter, nil)
+	}
+
+	var base string
+	var later []string
+	for _, id := range ids {
+		t, err := time.Parse(snapshotTimeFormat, id)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid snapshot ID %s: %w", id, err)
+		}
+
+		if t.After(asOf) {
+			// Oldest first
+			later = append([]string{id}, later...)
+		} else if base == "" {
+			base = id
+		}
+	}
+
+	if base == "" {
+		return nil, fmt.Errorf("%s is before the oldest snapshot %s of %s collection (fetch with a larger --keep to keep older snapshots)",
+			asOf.Format(time.RFC3339), ids[len(ids)-1], collection)
+	}
+
+	results, err := st.Find(ctx, fmt.Sprintf("%s_%s", collection, base), filter, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	prices := map[string]bson.M{}
+	skus := make([]string, 0, len(results))
+	for _, result := range results {
+		sku, _ := result["sku"].(string)
+		if sku == "" {
+			continue
+		}
+
+		prices[sku] = result
+		skus = append(skus, sku)
+	}
+
+	for _, id := range later {
+		results, err := st.Find(ctx, fmt.Sprintf("%s_%s", collection, id), filter, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, result := range results {
+			sku, _ := result["sku"].(string)
+			if sku == "" {
+				continue
+			}
+
+			// SKUs not offered at the date and prices not newer than the current one are skipped.
+			current, ok := prices[sku]
+			if !ok || getEffectiveDate(result) <= getEffectiveDate(current) {
+				continue
+			}
+
+			prices[sku] = result
+		}
+	}
+
+	results = make([]bson.M, 0, len(skus))
+	for _, sku := range skus {
+		results = append(results, prices[sku])
+	}
+
+	return results, nil
+}
+
+// getEffectiveDate returns the latest effective date of the On-Demand terms of the result.
+func getEffectiveDate(result bson.M) string {
+	onDemand, _ := result["ondemand"].(primitive.A)
+
+	var date string
+	for _, d := range onDemand {
+		dimension, ok := d.(primitive.M)
+		if !ok {
+			continue
+		}
+
+		if effectiveDate, _ := dimension["effectivedate"].(string); effectiveDate > date {
+			date = effectiveDate
+		}
+	}
+
+	return date
+}
+
+type priceTerm struct {
+	term           string
+	lease          string
+	purchaseOption string
+	offeringClass  string
+	// maxPrice is the maximum hourly price, the effective price for reserved term.
+	maxPrice *float64
+}
+
+func getPriceTerm(ctx flagValues) (*priceTerm, error) {
+	t := &priceTerm{
+		term:          ctx.String("term"),
+		lease:         ctx.String("lease"),
+		offeringClass: ctx.String("offering-class"),
+		maxPrice:      getFloat(ctx, "max-price"),
+	}
+
+	switch t.term {
+	case "ondemand", "reserved":
+	default:
+		return nil, fmt.Errorf("Unknown term: %s", t.term)
+	}
+
+	switch strings.ToLower(ctx.String("purchase-option")) {
+	case "":
+	case "no", "no upfront":
+		t.purchaseOption = "No Upfront"
+	case "partial", "partial upfront":
+		t.purchaseOption = "Partial Upfront"
+	case "all", "all upfront":
+		t.purchaseOption = "All Upfront"
+	default:
+		return nil, fmt.Errorf("Unknown purchase option: %s", ctx.String("purchase-option"))
+	}
+
+	return t, nil
+}
+
+func (t *priceTerm) condition(filter bson.M) bson.M {
+	if t.term != "reserved" {
+		appendRange(filter, "ondemandhourlyusd", nil, t.maxPrice)
+		return filter
+	}
+
+	elem := bson.M{}
+	if t.lease != "" {
+		elem["leasecontractlength"] = t.lease
+	}
+	if t.purchaseOption != "" {
+		elem["purchaseoption"] = t.purchaseOption
+	}
+	if t.offeringClass != "" {
+		elem["offeringclass"] = t.offeringClass
+	}
+
+	if len(elem) == 0 {
+		filter["reserved.0"] = bson.M{"$exists": true}
+	} else {
+		filter["reserved"] = bson.M{"$elemMatch": elem}
+	}
+
+	return filter
+}
+
+func (t *priceTerm) header() []string {
+	if t.term != "reserved" {
+		return []string{
+			"OnDemandPrice(USD/hour)",
+			"OnDemandPrice(USD/month)",
+		}
+	}
+
+	return []string{
+		"LeaseContractLength",
+		"PurchaseOption",
+		"OfferingClass",
+		"UpfrontFee(USD)",
+		"ReservedPrice(USD/hour)",
+		"EffectivePrice(USD/hour)",
+		"EffectivePrice(USD/month)",
+	}
+}
+
+// rowOrder returns the order of the rows of reserved term, which has a row per offer of a product,
+// and removes the sort by price and the limit from the condition of the store. It returns nil for the other terms.
+func (t *priceTerm) rowOrder(cond *condition) *rowOrder {
+	if t.term != "reserved" {
+		return nil
+	}
+
+	// The other keys are of the products, so the store sorts them and the offers of a product follow in order.
+	order := &rowOrder{byPrice: cond.sortBy == "price", descending: cond.descending, limit: cond.limit}
+	if order.byPrice {
+		cond.sortBy = ""
+	}
+	cond.limit = 0
+
+	return order
+}
+
+// getRowOrder returns the order of the rows computed from several products of the service (e.g. lambda),
+// which are not sorted nor limited by the store. The rows have no instance type, so only price is supported.
+func getRowOrder(service string, cond *condition) (*rowOrder, error) {
+	if cond.sortBy != "" && cond.sortBy != "price" {
+		return nil, fmt.Errorf("Unsupported sort key for %s: %s (e.g. price)", service, cond.sortBy)
+	}
+
+	order := &rowOrder{byPrice: cond.sortBy == "price", descending: cond.descending, limit: cond.limit}
+	cond.sortBy, cond.limit = "", 0
+
+	return order, nil
+}
+
+// priceRow is a price of a result in USD.
+type priceRow struct {
+	Term                string  `json:"term" yaml:"term"`
+	LeaseContractLength string  `json:"leaseContractLength,omitempty" yaml:"leaseContractLength,omitempty"`
+	PurchaseOption      string  `json:"purchaseOption,omitempty" yaml:"purchaseOption,omitempty"`
+	OfferingClass       string  `json:"offeringClass,omitempty" yaml:"offeringClass,omitempty"`
+	UpfrontFee          float64 `json:"upfrontFee" yaml:"upfrontFee"`
+	Hourly              float64 `json:"hourly" yaml:"hourly"`
+	EffectiveHourly     float64 `json:"effectiveHourly" yaml:"effectiveHourly"`
+	Monthly             float64 `json:"monthly" yaml:"monthly"`
+
+	// fields are the price columns of the table.
+	fields []string
+}
+
+// sortPrice returns the effective hourly price, which --sort-by price sorts the offers of reserved term by.
+func (p *priceRow) sortPrice() float64 {
+	return p.EffectiveHourly
+}
+
+// prices returns the prices of the result.
+// A result has a row per reserved offer that matches the term, so multiple rows may be returned.
+func (t *priceTerm) prices(price *aws.Price) ([]*priceRow, error) {
+	if t.term != "reserved" {
+		hourly := price.OnDemandPricePerUSD
+		monthly, err := utils.ConvertHourlyToMonthly(hourly)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to calculate monthly price: %w", err)
+		}
+
+		p := &priceRow{Term: t.term, fields: []string{hourly, monthly}}
+		if err := parseFloats([]string{hourly, hourly, monthly}, &p.Hourly, &p.EffectiveHourly, &p.Monthly); err != nil {
+			return nil, err
+		}
+
+		return []*priceRow{p}, nil
+	}
+
+	var rows []*priceRow
+	for _, offer := range price.Reserved {
+		lease := offer.LeaseContractLength
+		purchaseOption := offer.PurchaseOption
+		offeringClass := offer.OfferingClass
+		upfrontFee := offer.UpfrontFeeUSD
+		hourly := offer.HourlyPriceUSD
+
+		if (t.lease != "" && t.lease != lease) ||
+			(t.purchaseOption != "" && t.purchaseOption != purchaseOption) ||
+			(t.offeringClass != "" && t.offeringClass != offeringClass) {
+			continue
+		}
+
+		effective, err := utils.ConvertReservedToHourly(upfrontFee, hourly, lease)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to calculate effective price: %w", err)
+		}
+		monthly, err := utils.ConvertHourlyToMonthly(effective)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to calculate monthly price: %w", err)
+		}
+
+		p := &priceRow{
+			Term:                t.term,
+			LeaseContractLength: lease,
+			PurchaseOption:      purchaseOption,
+			OfferingClass:       offeringClass,
+			fields: []string{
+				lease,
+				purchaseOption,
+				offeringClass,
+				upfrontFee,
+				hourly,
+				effective,
+				monthly,
+			},
+		}
+		if err := parseFloats([]string{upfrontFee, hourly, effective, monthly}, &p.UpfrontFee, &p.Hourly, &p.EffectiveHourly, &p.Monthly); err != nil {
+			return nil, err
+		}
+
+		if t.maxPrice != nil && p.EffectiveHourly > *t.maxPrice {
+			continue
+		}
+
+		rows = append(rows, p)
+	}
+
+	return rows, nil
+}
+
+// decodePrice decodes a query result into a price.
+func decodePrice(result primitive.M) (*aws.Price, error) {
+	b, err := bson.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal result: %w", err)
+	}
+
+	price := &aws.Price{}
+	if err := bson.Unmarshal(b, price); err != nil {
+		return nil, fmt.Errorf("Failed to decode result: %w", err)
+	}
+
+	// Products fetched before the tiers were stored have only the dimensions.
+	if len(price.OnDemandTiers) == 0 {
+		price.OnDemandTiers, err = aws.ParseTiers(price.OnDemand)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return price, nil
+}
+
+// na returns N/A for a missing attribute.
+func na(v string) string {
+	if v == "" {
+		return "N/A"
+	}
+
+	return v
+}
+
+func parseFloats(values []string, dst ...*float64) error {
+	for i, v := range values {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("Invalid price %q: %w", v, err)
+		}
+		*dst[i] = f
+	}
+
+	return nil
+}
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/sfuruya0612/apf/internal/aws"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// document returns the price as a query result of the store.
+func document(t *testing.T, price *aws.Price) primitive.M {
+	t.Helper()
+
+	b, err := bson.Marshal(price)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var m primitive.M
+	if err := bson.Unmarshal(b, &m); err != nil {
+		t.Fatal(err)
+	}
+
+	return m
+}
+
+func TestDecodePrice(t *testing.T) {
+	want := &aws.Price{Sku: "8VCNEHQMSCQS4P39", Region: "us-east-1", OnDemandPricePerUSD: "0.0960000000", OnDemandHourlyUSD: 0.096, VcpuCount: 2, MemoryGiB: 8}
+	want.Product.Attributes.InstanceType = "m5.large"
+
+	price, err := decodePrice(document(t, want))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(price, want) {
+		t.Errorf("got %+v, want %+v", price, want)
+	}
+}
+
+func TestDecodePriceError(t *testing.T) {
+	tests := []struct {
+		name   string
+		result primitive.M
+	}{
+		{"type mismatch", primitive.M{"sku": "A", "ondemandhourlyusd": "0.096"}},
+		{"attributes are not an object", primitive.M{"product": "m5.large"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodePrice(tt.result); err == nil {
+				t.Error("got no error")
+			}
+		})
+	}
+}
+
+func TestDecodePriceTiers(t *testing.T) {
+	dimensions := []aws.PriceDimension{
+		{Unit: "GB-Mo", BeginRange: "51200", EndRange: "512000", Currency: "USD", PricePerUnit: "0.0220000000"},
+		{Unit: "GB-Mo", BeginRange: "0", EndRange: "51200", Currency: "USD", PricePerUnit: "0.0230000000"},
+		{Unit: "GB-Mo", BeginRange: "512000", EndRange: "Inf", Currency: "USD", PricePerUnit: "0.0210000000"},
+	}
+	tiers := []aws.PriceTier{
+		{BeginRange: 0, EndRange: 51200, Unit: "GB-Mo", PricePerUnitUSD: 0.023},
+		{BeginRange: 51200, EndRange: 512000, Unit: "GB-Mo", PricePerUnitUSD: 0.022},
+		{BeginRange: 512000, EndRange: 0, Unit: "GB-Mo", PricePerUnitUSD: 0.021},
+	}
+
+	tests := []struct {
+		name  string
+		price *aws.Price
+	}{
+		{"stored tiers", &aws.Price{Sku: "A", OnDemand: dimensions, OnDemandTiers: tiers}},
+		// Products fetched before the tiers were stored.
+		{"tiers from dimensions", &aws.Price{Sku: "A", OnDemand: dimensions}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, err := decodePrice(document(t, tt.price))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if price.Sku != "A" {
+				t.Errorf("got sku %q, want A", price.Sku)
+			}
+			if !reflect.DeepEqual(price.OnDemandTiers, tiers) {
+				t.Errorf("got tiers %+v, want %+v", price.OnDemandTiers, tiers)
+			}
+		})
+	}
+}
+
+func TestDecodePriceTiersError(t *testing.T) {
+	result := document(t, &aws.Price{
+		Sku:      "A",
+		OnDemand: []aws.PriceDimension{{Unit: "Hrs", Currency: "USD", PricePerUnit: "N/A"}},
+	})
+
+	if _, err := decodePrice(result); err == nil {
+		t.Error("got no error")
+	}
+}
+
+func TestAppendConditionMemory(t *testing.T) {
+	float := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name     string
+		memory   string
+		min, max *float64
+		want     bson.M
+	}{
+		{"no memory", "", nil, nil, nil},
+		{"number", "8", nil, nil, bson.M{"$gte": 7.92, "$lte": 8.08}},
+		{"rounded size", "13.07 GiB", nil, nil, bson.M{"$gte": 12.9393, "$lte": 13.2007}},
+		{"MiB", "512 MiB", nil, nil, bson.M{"$gte": 0.495, "$lte": 0.505}},
+		{"narrowed by the range", "8", float(8), float(16), bson.M{"$gte": 8.0, "$lte": 8.08}},
+		{"range only", "", float(8), nil, bson.M{"$gte": 8.0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			memory, err := parseMemory(tt.memory)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			filter := appendCondition(bson.M{}, &condition{memory: memory, minMemory: tt.min, maxMemory: tt.max})
+
+			got, ok := filter["memorygib"].(bson.M)
+			if tt.want == nil {
+				if ok {
+					t.Errorf("got %v, want no memory filter", got)
+				}
+				return
+			}
+
+			for _, op := range []string{"$gte", "$lte"} {
+				w, wok := tt.want[op].(float64)
+				g, gok := got[op].(float64)
+				if wok != gok || math.Abs(g-w) > 1e-9 {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	// The products of 13.07 GiB are found by 13.
+	memory, _ := parseMemory("13")
+	filter := appendCondition(bson.M{}, &condition{memory: memory})
+	r := filter["memorygib"].(bson.M)
+	if r["$gte"].(float64) > 13.07 || r["$lte"].(float64) < 13.07 {
+		t.Errorf("13 does not match 13.07 GiB: %v", r)
+	}
+}
+
+func TestParseMemoryError(t *testing.T) {
+	for _, memory := range []string{"large", "8 GB RAM", "0", "-1"} {
+		if _, err := parseMemory(memory); err == nil {
+			t.Errorf("parseMemory(%q) got no error", memory)
+		}
+	}
+}
@@ -1,15 +1,17 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
-	"text/tabwriter"
 
-	"github.com/sfuruya0612/apf/internal/utils"
+	"github.com/sfuruya0612/apf/internal/aws"
 	"github.com/urfave/cli/v2"
 	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 var rdsCommand = &cli.Command{
@@ -28,53 +30,439 @@ var rdsCommand = &cli.Command{
 			Value:   "Single-AZ",
 			Usage:   "Specify a valid deployment option (e.g. Singe-AZ, Multi-AZ)",
 		},
+		&cli.Float64Flag{
+			Name:  "serverless-acu-min",
+			Value: 0.5,
+			Usage: "Specify the minimum ACU of Aurora Serverless v2",
+		},
+		&cli.Float64Flag{
+			Name:  "serverless-acu-max",
+			Usage: "Specify the maximum ACU of Aurora Serverless v2. Serverless v2 is printed instead of instances if set",
+		},
+		&cli.Float64Flag{
+			Name:  "storage-gb",
+			Usage: "Specify the database storage in GB per month",
+		},
+		&cli.Float64Flag{
+			Name:  "io-requests",
+			Usage: "Specify the number of Aurora I/O requests per month (e.g. 1e8)",
+		},
+		&cli.Float64Flag{
+			Name:  "backup-gb",
+			Usage: "Specify the backup storage in GB per month",
+		},
+		&cli.BoolFlag{
+			Name:  "io-optimized",
+			Usage: "Use the Aurora I/O-Optimized storage configuration",
+		},
+		&cli.StringFlag{
+			Name:  "volume-type",
+			Value: "General Purpose",
+			Usage: "Specify a valid storage volume type of the engines other than Aurora (e.g. General Purpose, General Purpose-GP3, Provisioned IOPS)",
+		},
+		&cli.StringFlag{
+			Name:  "license-model",
+			Usage: "Specify a valid license model (e.g. License included, Bring your own license) (default: all)",
+		},
 	},
 	Action: func(ctx *cli.Context) error {
 		return getRdsPrice(ctx)
 	},
 }
 
+// rdsUsage is the Serverless v2 capacity and the storage of a database per month.
+type rdsUsage struct {
+	engine           string
+	deploymentOption string
+	acuMin           float64
+	acuMax           float64
+	storageGB        float64
+	ioRequests       float64
+	backupGB         float64
+	ioOptimized      bool
+	volumeType       string
+	licenseModel     string
+}
+
+func (u *rdsUsage) aurora() bool {
+	return strings.HasPrefix(u.engine, "Aurora")
+}
+
+func (u *rdsUsage) serverless() bool {
+	return u.acuMax > 0
+}
+
+func (u *rdsUsage) hasStorage() bool {
+	return u.storageGB > 0 || u.ioRequests > 0 || u.backupGB > 0
+}
+
+func (u *rdsUsage) storageConfig() string {
+	if u.ioOptimized {
+		return aws.RdsStorageIoOptimized
+	}
+	return aws.RdsStorageStandard
+}
+
+// rdsCost is the prices of the non-instance products of a region.
+type rdsCost struct {
+	acu     []aws.PriceTier
+	storage []aws.PriceTier
+	io      []aws.PriceTier
+	backup  []aws.PriceTier
+}
+
+// rdsStorageRow is the monthly cost of the storage.
+// Missing is the usages whose prices are not stored, and their costs are N/A.
+type rdsStorageRow struct {
+	StorageMonthly float64  `json:"storageMonthly" yaml:"storageMonthly"`
+	IoMonthly      float64  `json:"ioMonthly" yaml:"ioMonthly"`
+	BackupMonthly  float64  `json:"backupMonthly" yaml:"backupMonthly"`
+	Missing        []string `json:"missing,omitempty" yaml:"missing,omitempty"`
+}
+
+func (c *rdsCost) estimate(usage *rdsUsage) rdsStorageRow {
+	var r rdsStorageRow
+
+	cost := func(usageKind string, tiers []aws.PriceTier, quantity float64) float64 {
+		if quantity > 0 && len(tiers) == 0 {
+			r.Missing = append(r.Missing, usageKind)
+		}
+		return aws.TieredCostUSD(tiers, quantity)
+	}
+
+	r.StorageMonthly = cost(aws.RdsUsageStorage, c.storage, usage.storageGB)
+	// I/O-Optimized has no charge for I/O requests.
+	if !usage.ioOptimized {
+		r.IoMonthly = cost(aws.RdsUsageIo, c.io, usage.ioRequests)
+	}
+	r.BackupMonthly = cost(aws.RdsUsageBackup, c.backup, usage.backupGB)
+
+	return r
+}
+
+func (r rdsStorageRow) missing(usageKind string) bool {
+	return contains(r.Missing, usageKind)
+}
+
+// sortPrice returns the total monthly cost of the maximum ACU, which --sort-by price sorts the regions by.
+func (r *rdsServerlessRow) sortPrice() float64 {
+	return r.TotalMonthlyMax
+}
+
+func (r rdsStorageRow) monthly() float64 {
+	return r.StorageMonthly + r.IoMonthly + r.BackupMonthly
+}
+
+// rdsPriceRow is a price of an instance with the storage.
+type rdsPriceRow struct {
+	*priceRow     `yaml:",inline"`
+	rdsStorageRow `yaml:",inline"`
+	TotalMonthly  float64 `json:"totalMonthly" yaml:"totalMonthly"`
+}
+
+// rdsServerlessRow is the Serverless v2 prices of the region and the monthly cost from the minimum to the maximum ACU.
+type rdsServerlessRow struct {
+	Region            string  `json:"region" yaml:"region"`
+	Engine            string  `json:"engine" yaml:"engine"`
+	StorageConfig     string  `json:"storageConfig" yaml:"storageConfig"`
+	AcuPrice          float64 `json:"acuPrice" yaml:"acuPrice"`
+	ComputeMonthlyMin float64 `json:"computeMonthlyMin" yaml:"computeMonthlyMin"`
+	ComputeMonthlyMax float64 `json:"computeMonthlyMax" yaml:"computeMonthlyMax"`
+	rdsStorageRow     `yaml:",inline"`
+	TotalMonthlyMin   float64 `json:"totalMonthlyMin" yaml:"totalMonthlyMin"`
+	TotalMonthlyMax   float64 `json:"totalMonthlyMax" yaml:"totalMonthlyMax"`
+}
+
 func getRdsPrice(ctx *cli.Context) error {
-	filter := bson.M{
-		"product.attributes.osengine":         ctx.String("engine"),
-		"product.attributes.deploymentoption": ctx.String("deployment-option"),
+	term, err := getPriceTerm(ctx)
+	if err != nil {
+		return err
+	}
+
+	out, err := newOutput(ctx)
+	if err != nil {
+		return err
 	}
 
-	results, err := findMongo(
-		ctx.String("mongo-uri"),
+	cond, err := getCondition(ctx)
+	if err != nil {
+		return err
+	}
+	usage, err := getRdsUsage(ctx)
+	if err != nil {
+		return err
+	}
+
+	if usage.serverless() {
+		if term.term != "ondemand" {
+			return fmt.Errorf("Unsupported term for Aurora Serverless v2: %s", term.term)
+		}
+
+		// A row is made of the ACU and the storage products of a region, so the products are not sorted nor limited by the store.
+		out.order, err = getRowOrder("rds", cond)
+		if err != nil {
+			return err
+		}
+	} else {
+		out.order = term.rowOrder(cond)
+	}
+
+	var costs map[string]*rdsCost
+	if usage.serverless() || usage.hasStorage() {
+		costs, err = findRdsCosts(getStoreURI(ctx), cond, usage)
+		if err != nil {
+			return err
+		}
+	}
+
+	if usage.serverless() {
+		return printRdsServerless(costs, usage, out)
+	}
+
+	results, err := findProducts(
+		getStoreURI(ctx),
 		"rds",
-		ctx.String("instance-type"),
-		ctx.String("vcpu"),
-		ctx.String("memory"),
-		filter,
+		cond,
+		term.condition(getRdsFilter(usage)),
 	)
 	if err != nil {
 		return fmt.Errorf("Failed to find: %w", err)
 	}
 
-	printRds(results)
+	if err := printRds(results, term, costs, usage, out); err != nil {
+		return err
+	}
 
 	return nil
 }
 
-func printRds(results []bson.M) error {
-	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, ' ', 0)
+// getRdsFilter returns the filter of the database instances.
+func getRdsFilter(usage *rdsUsage) bson.M {
+	filter := bson.M{
+		"product.productfamily":               "Database Instance",
+		"product.attributes.osengine":         usage.engine,
+		"product.attributes.deploymentoption": usage.deploymentOption,
+	}
+
+	if usage.licenseModel != "" {
+		filter["product.attributes.licensemodel"] = usage.licenseModel
+	}
+
+	if usage.aurora() {
+		if usage.ioOptimized {
+			filter["product.attributes.storageconfig"] = aws.RdsStorageIoOptimized
+		} else {
+			// Products fetched before the storage configuration was stored are Standard.
+			filter["product.attributes.storageconfig"] = bson.M{"$ne": aws.RdsStorageIoOptimized}
+		}
+	}
+
+	return filter
+}
+
+func getRdsUsage(ctx flagValues) (*rdsUsage, error) {
+	u := &rdsUsage{
+		engine:           ctx.String("engine"),
+		deploymentOption: ctx.String("deployment-option"),
+		acuMin:           ctx.Float64("serverless-acu-min"),
+		acuMax:           ctx.Float64("serverless-acu-max"),
+		storageGB:        ctx.Float64("storage-gb"),
+		ioRequests:       ctx.Float64("io-requests"),
+		backupGB:         ctx.Float64("backup-gb"),
+		ioOptimized:      ctx.Bool("io-optimized"),
+		volumeType:       ctx.String("volume-type"),
+		licenseModel:     ctx.String("license-model"),
+	}
+
+	if u.storageGB < 0 || u.ioRequests < 0 || u.backupGB < 0 {
+		return nil, fmt.Errorf("Invalid storage: must not be negative")
+	}
+
+	if u.ioOptimized && !u.aurora() {
+		return nil, fmt.Errorf("I/O-Optimized is only available for Aurora: %s", u.engine)
+	}
+
+	if u.ioRequests > 0 && !u.aurora() {
+		return nil, fmt.Errorf("I/O requests are only charged for Aurora: %s", u.engine)
+	}
+
+	if u.serverless() {
+		if !u.aurora() {
+			return nil, fmt.Errorf("Serverless v2 is only available for Aurora: %s", u.engine)
+		}
+
+		if u.acuMin < 0 || u.acuMin > u.acuMax {
+			return nil, fmt.Errorf("Invalid ACU: min %g, max %g", u.acuMin, u.acuMax)
+		}
+	}
+
+	return u, nil
+}
+
+// findRdsCosts returns the prices of the non-instance products per region.
+func findRdsCosts(storeUri string, cond *condition, usage *rdsUsage) (map[string]*rdsCost, error) {
+	filter := bson.M{
+		"product.productfamily":       bson.M{"$in": aws.RdsProductFamilies},
+		"product.attributes.osengine": bson.M{"$in": bson.A{usage.engine, "Any"}},
+	}
+
+	costs := map[string]*rdsCost{}
 
-	if _, err := fmt.Fprintln(w, strings.Join(getRdsHeader(), "\t")); err != nil {
-		return fmt.Errorf("Failed to print header: %w", err)
+	// Only the region and the date apply to the non-instance products.
+	// The costs of the missing products are N/A, so the instances are printed without them.
+	results, err := findProducts(storeUri, "rds", &condition{region: cond.region, asOf: cond.asOf}, filter)
+	if errors.Is(err, errNoResults) {
+		return costs, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to find storage prices: %w", err)
 	}
 
 	for _, result := range results {
-		if _, err := fmt.Fprintln(w, formatRds(result)); err != nil {
-			return fmt.Errorf("Failed to print result: %w", err)
+		price, err := decodePrice(result)
+		if err != nil {
+			log.Printf("Skip product %v: %v", result["sku"], err)
+			continue
+		}
+
+		attr := price.Product.Attributes
+		usageKind := aws.RdsUsage(price.Product.ProductFamily, attr.UsageType)
+
+		if usage.aurora() {
+			// Backup storage is charged the same for both storage configurations, and it is always stored as Standard.
+			if usageKind != aws.RdsUsageBackup && attr.StorageConfig != usage.storageConfig() {
+				continue
+			}
+		} else if attr.VolumeType != "" && (attr.VolumeType != usage.volumeType || attr.DeploymentOption != usage.deploymentOption) {
+			continue
+		}
+
+		c, ok := costs[price.Region]
+		if !ok {
+			c = &rdsCost{}
+			costs[price.Region] = c
+		}
+
+		switch usageKind {
+		case aws.RdsUsageServerlessV2:
+			c.acu = price.OnDemandTiers
+		case aws.RdsUsageStorage:
+			c.storage = price.OnDemandTiers
+		case aws.RdsUsageIo:
+			c.io = price.OnDemandTiers
+		case aws.RdsUsageBackup:
+			c.backup = price.OnDemandTiers
 		}
 	}
 
-	if err := w.Flush(); err != nil {
-		return fmt.Errorf("Failed to flush: %w", err)
+	return costs, nil
+}
+
+func printRds(results []bson.M, term *priceTerm, costs map[string]*rdsCost, usage *rdsUsage, out *output) error {
+	out.header = append(getRdsHeader(), term.header()...)
+	if usage.hasStorage() {
+		out.header = append(out.header, getRdsStorageHeader()...)
+		out.header = append(out.header, "Total(USD/month)")
 	}
 
-	return nil
+	for _, result := range results {
+		price, err := decodePrice(result)
+		if err != nil {
+			log.Printf("Skip product %v: %v", result["sku"], err)
+			continue
+		}
+
+		prices, err := term.prices(price)
+		if err != nil {
+			log.Printf("Skip product %s: %v", price.Sku, err)
+			continue
+		}
+
+		for _, p := range prices {
+			fields := append(formatRds(price), p.fields...)
+			if !usage.hasStorage() {
+				out.add(fields, result, p)
+				continue
+			}
+
+			c, ok := costs[price.Region]
+			if !ok {
+				c = &rdsCost{}
+			}
+
+			row := &rdsPriceRow{priceRow: p, rdsStorageRow: c.estimate(usage)}
+			row.TotalMonthly = p.Monthly + row.monthly()
+
+			fields = append(fields, formatRdsStorage(row.rdsStorageRow)...)
+			fields = append(fields, formatRdsTotal(row.rdsStorageRow, row.TotalMonthly))
+			out.add(fields, result, row)
+		}
+	}
+
+	return out.render(os.Stdout)
+}
+
+func printRdsServerless(costs map[string]*rdsCost, usage *rdsUsage, out *output) error {
+	out.header = []string{
+		"Region",
+		"OS/Engine",
+		"StorageConfig",
+		"ACU",
+		"ACUPrice(USD/ACU-hour)",
+		"ComputeMin(USD/month)",
+		"ComputeMax(USD/month)",
+	}
+	out.header = append(out.header, getRdsStorageHeader()...)
+	out.header = append(out.header, "TotalMin(USD/month)", "TotalMax(USD/month)")
+
+	var regions []string
+	for region := range costs {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+
+	for _, region := range regions {
+		c := costs[region]
+		if len(c.acu) == 0 {
+			continue
+		}
+
+		row := &rdsServerlessRow{
+			Region:        region,
+			Engine:        usage.engine,
+			StorageConfig: usage.storageConfig(),
+			AcuPrice:      c.acu[0].PricePerUnitUSD,
+			// 730 hours in a month
+			ComputeMonthlyMin: aws.TieredCostUSD(c.acu, usage.acuMin) * 730,
+			ComputeMonthlyMax: aws.TieredCostUSD(c.acu, usage.acuMax) * 730,
+			rdsStorageRow:     c.estimate(usage),
+		}
+		row.TotalMonthlyMin = row.ComputeMonthlyMin + row.monthly()
+		row.TotalMonthlyMax = row.ComputeMonthlyMax + row.monthly()
+
+		fields := []string{
+			region,
+			usage.engine,
+			row.StorageConfig,
+			strconv.FormatFloat(usage.acuMin, 'f', -1, 64) + "-" + strconv.FormatFloat(usage.acuMax, 'f', -1, 64),
+			formatPrice(row.AcuPrice),
+			fmt.Sprintf("%.2f", row.ComputeMonthlyMin),
+			fmt.Sprintf("%.2f", row.ComputeMonthlyMax),
+		}
+		fields = append(fields, formatRdsStorage(row.rdsStorageRow)...)
+		fields = append(fields,
+			formatRdsTotal(row.rdsStorageRow, row.TotalMonthlyMin),
+			formatRdsTotal(row.rdsStorageRow, row.TotalMonthlyMax),
+		)
+
+		out.add(fields, bson.M{}, row)
+	}
+
+	if len(out.rows) == 0 {
+		return fmt.Errorf("Failed to find Serverless v2 prices: %w", errNoResults)
+	}
+
+	return out.render(os.Stdout)
 }
 
 func getRdsHeader() []string {
@@ -87,24 +475,53 @@ func getRdsHeader() []string {
 		"Memory",
 		"DeploymentOption",
 		"Storage",
-		"OnDemandPrice(USD/hour)",
-		"OnDemandPrice(USD/month)",
 	}
 }
 
-func formatRds(result primitive.M) string {
+func getRdsStorageHeader() []string {
+	return []string{
+		"Storage(USD/month)",
+		"IO(USD/month)",
+		"Backup(USD/month)",
+	}
+}
+
+func formatRds(price *aws.Price) []string {
+	attr := price.Product.Attributes
+
 	fields := []string{
-		result["servicecode"].(string),
-		result["product"].(bson.M)["attributes"].(bson.M)["regioncode"].(string),
-		result["product"].(bson.M)["attributes"].(bson.M)["osengine"].(string),
-		result["product"].(bson.M)["attributes"].(bson.M)["instancetype"].(string),
-		result["product"].(bson.M)["attributes"].(bson.M)["vcpu"].(string),
-		result["product"].(bson.M)["attributes"].(bson.M)["memory"].(string),
-		result["product"].(bson.M)["attributes"].(bson.M)["deploymentoption"].(string),
-		result["product"].(bson.M)["attributes"].(bson.M)["storage"].(string),
-		result["ondemandpriceperusd"].(string),
-		utils.ConvertHourlyToMonthly(result["ondemandpriceperusd"].(string)),
-	}
-
-	return strings.Join(fields, "\t")
+		na(price.ServiceCode),
+		na(attr.RegionCode),
+		na(attr.OSEngine),
+		na(attr.InstanceType),
+		na(attr.Vcpu),
+		na(attr.Memory),
+		na(attr.DeploymentOption),
+		na(attr.Storage),
+	}
+
+	return fields
+}
+
+func formatRdsStorage(r rdsStorageRow) []string {
+	monthly := func(usageKind string, v float64) string {
+		if r.missing(usageKind) {
+			return na("")
+		}
+		return fmt.Sprintf("%.2f", v)
+	}
+
+	return []string{
+		monthly(aws.RdsUsageStorage, r.StorageMonthly),
+		monthly(aws.RdsUsageIo, r.IoMonthly),
+		monthly(aws.RdsUsageBackup, r.BackupMonthly),
+	}
+}
+
+// formatRdsTotal returns N/A if any cost of the storage is missing.
+func formatRdsTotal(r rdsStorageRow, total float64) string {
+	if len(r.Missing) > 0 {
+		return na("")
+	}
+	return fmt.Sprintf("%.2f", total)
 }
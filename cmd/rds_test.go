@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sfuruya0612/apf/internal/aws"
+	"github.com/sfuruya0612/apf/internal/store"
+)
+
+// rdsCostPrice returns a non-instance product of Aurora MySQL in us-east-1.
+func rdsCostPrice(sku, productFamily, usageType, storageConfig string, price float64) *aws.Price {
+	p := &aws.Price{
+		Sku:           sku,
+		Region:        "us-east-1",
+		OnDemandTiers: []aws.PriceTier{{Unit: "GB-Mo", PricePerUnitUSD: price}},
+	}
+	p.Product.ProductFamily = productFamily
+	p.Product.Attributes.OSEngine = "Aurora MySQL"
+	p.Product.Attributes.UsageType = usageType
+	p.Product.Attributes.StorageConfig = storageConfig
+
+	return p
+}
+
+func TestFindRdsCosts(t *testing.T) {
+	storeUri := "sqlite://" + filepath.Join(t.TempDir(), "apf.db")
+
+	st, err := store.Open(storeUri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs := []interface{}{
+		document(t, rdsCostPrice("STORAGE", "Database Storage", "Aurora:StorageUsage", aws.RdsStorageStandard, 0.1)),
+		document(t, rdsCostPrice("STORAGE_IO_OPTIMIZED", "Database Storage", "Aurora:IO-OptimizedStorageUsage", aws.RdsStorageIoOptimized, 0.225)),
+		// Backup storage is always Standard.
+		document(t, rdsCostPrice("BACKUP", "Storage Snapshot", "Aurora:BackupUsage", aws.RdsStorageStandard, 0.021)),
+	}
+	if err := st.Insert(context.Background(), "rds", docs); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		usage *rdsUsage
+		want  rdsStorageRow
+	}{
+		{
+			"standard",
+			&rdsUsage{engine: "Aurora MySQL", storageGB: 100, ioRequests: 1e6, backupGB: 100},
+			rdsStorageRow{StorageMonthly: 10, BackupMonthly: 2.1, Missing: []string{aws.RdsUsageIo}},
+		},
+		{
+			"io-optimized",
+			&rdsUsage{engine: "Aurora MySQL", storageGB: 100, backupGB: 100, ioOptimized: true},
+			rdsStorageRow{StorageMonthly: 22.5, BackupMonthly: 2.1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			costs, err := findRdsCosts(storeUri, &condition{}, tt.usage)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c, ok := costs["us-east-1"]
+			if !ok {
+				t.Fatalf("got no costs of us-east-1: %v", costs)
+			}
+
+			if got := c.estimate(tt.usage); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("no products", func(t *testing.T) {
+		costs, err := findRdsCosts(storeUri, &condition{region: "ap-northeast-1"}, &rdsUsage{engine: "Aurora MySQL", storageGB: 100})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(costs) != 0 {
+			t.Errorf("got %v, want no costs", costs)
+		}
+	})
+}
+
+func TestFormatRdsStorage(t *testing.T) {
+	r := rdsStorageRow{StorageMonthly: 10, BackupMonthly: 2.1, Missing: []string{aws.RdsUsageIo}}
+
+	want := []string{"10.00", "N/A", "2.10"}
+	if got := formatRdsStorage(r); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := formatRdsTotal(r, 12.1); got != "N/A" {
+		t.Errorf("got %v, want N/A", got)
+	}
+}
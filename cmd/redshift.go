@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/sfuruya0612/apf/internal/aws"
+	"github.com/urfave/cli/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var redshiftCommand = &cli.Command{
+	Name:    "redshift",
+	Aliases: []string{"rs"},
+	Usage:   "Get Redshift pricing",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "node-family",
+			Usage: "Specify a valid node family (e.g. ra3, dc2, ds2)",
+		},
+		&cli.StringFlag{
+			Name:  "storage",
+			Usage: "Specify a valid storage type (e.g. Managed, Local)",
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		return getRedshiftPrice(ctx)
+	},
+}
+
+func getRedshiftPrice(ctx *cli.Context) error {
+	term, err := getPriceTerm(ctx)
+	if err != nil {
+		return err
+	}
+
+	out, err := newOutput(ctx)
+	if err != nil {
+		return err
+	}
+
+	cond, err := getCondition(ctx)
+	if err != nil {
+		return err
+	}
+	out.order = term.rowOrder(cond)
+
+	filter := bson.M{}
+
+	if family := ctx.String("node-family"); family != "" {
+		filter["product.attributes.instancefamily"] = family
+	}
+
+	if storage := ctx.String("storage"); storage != "" {
+		filter["product.attributes.storagemedia"] = storage
+	}
+
+	results, err := findProducts(
+		getStoreURI(ctx),
+		"redshift",
+		cond,
+		term.condition(filter),
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to find: %w", err)
+	}
+
+	if err := printRedshift(results, term, out); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func printRedshift(results []bson.M, term *priceTerm, out *output) error {
+	out.header = append(getRedshiftHeader(), term.header()...)
+
+	for _, result := range results {
+		price, err := decodePrice(result)
+		if err != nil {
+			log.Printf("Skip product %v: %v", result["sku"], err)
+			continue
+		}
+
+		prices, err := term.prices(price)
+		if err != nil {
+			log.Printf("Skip product %s: %v", price.Sku, err)
+			continue
+		}
+
+		for _, p := range prices {
+			out.add(append(formatRedshift(price), p.fields...), result, p)
+		}
+	}
+
+	return out.render(os.Stdout)
+}
+
+func getRedshiftHeader() []string {
+	return []string{
+		"Service",
+		"Region",
+		"NodeType",
+		"vCPU",
+		"Memory",
+		"Storage",
+		"StorageType",
+		"IO",
+	}
+}
+
+func formatRedshift(price *aws.Price) []string {
+	attr := price.Product.Attributes
+
+	fields := []string{
+		na(price.ServiceCode),
+		na(attr.RegionCode),
+		na(attr.InstanceType),
+		na(attr.Vcpu),
+		na(attr.Memory),
+		na(attr.Storage),
+		na(attr.StorageMedia),
+		na(attr.Io),
+	}
+
+	return fields
+}
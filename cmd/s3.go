@@ -0,0 +1,270 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sfuruya0612/apf/internal/aws"
+	"github.com/urfave/cli/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var s3Command = &cli.Command{
+	Name:  "s3",
+	Usage: "Get S3 pricing and estimate the monthly cost",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "class",
+			Aliases: []string{"c"},
+			Value:   "STANDARD",
+			Usage:   "Specify a valid storage class (e.g. STANDARD, STANDARD_IA, ONEZONE_IA, INTELLIGENT_TIERING, GLACIER_IR, GLACIER, DEEP_ARCHIVE)",
+		},
+		&cli.Float64Flag{
+			Name:  "gb",
+			Usage: "Specify the stored data in GB per month",
+		},
+		&cli.Float64Flag{
+			Name:  "put-requests",
+			Usage: "Specify the number of PUT, COPY, POST and LIST requests per month (e.g. 5e6)",
+		},
+		&cli.Float64Flag{
+			Name:  "get-requests",
+			Usage: "Specify the number of GET and other requests per month",
+		},
+		&cli.Float64Flag{
+			Name:  "retrieval-gb",
+			Usage: "Specify the retrieved data in GB per month",
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		return getS3Price(ctx)
+	},
+}
+
+// s3Usage is the monthly usage of a storage class.
+type s3Usage struct {
+	storageClass string
+	gb           float64
+	putRequests  float64
+	getRequests  float64
+	retrievalGB  float64
+}
+
+// s3Row is the prices of the region in USD, and the monthly cost of the usage.
+type s3Row struct {
+	Region           string  `json:"region" yaml:"region"`
+	StorageClass     string  `json:"storageClass" yaml:"storageClass"`
+	StoragePrice     float64 `json:"storagePrice" yaml:"storagePrice"`
+	PutRequestPrice  float64 `json:"putRequestPrice" yaml:"putRequestPrice"`
+	GetRequestPrice  float64 `json:"getRequestPrice" yaml:"getRequestPrice"`
+	RetrievalPrice   float64 `json:"retrievalPrice" yaml:"retrievalPrice"`
+	StorageMonthly   float64 `json:"storageMonthly" yaml:"storageMonthly"`
+	RequestsMonthly  float64 `json:"requestsMonthly" yaml:"requestsMonthly"`
+	RetrievalMonthly float64 `json:"retrievalMonthly" yaml:"retrievalMonthly"`
+	Monthly          float64 `json:"monthly" yaml:"monthly"`
+
+	hasStorage bool
+	storage    []aws.PriceTier
+	put        []aws.PriceTier
+	get        []aws.PriceTier
+	retrieval  []aws.PriceTier
+}
+
+func getS3Price(ctx *cli.Context) error {
+	if ctx.String("term") != "ondemand" {
+		return fmt.Errorf("Unsupported term for s3: %s", ctx.String("term"))
+	}
+
+	out, err := newOutput(ctx)
+	if err != nil {
+		return err
+	}
+
+	cond, err := getCondition(ctx)
+	if err != nil {
+		return err
+	}
+
+	usage, err := getS3Usage(ctx)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{"product.attributes.storageclass": usage.storageClass}
+
+	// A row is made of several products, so the products are not sorted nor limited by the store.
+	out.order, err = getRowOrder("s3", cond)
+	if err != nil {
+		return err
+	}
+
+	results, err := findProducts(
+		getStoreURI(ctx),
+		"s3",
+		cond,
+		filter,
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to find: %w", err)
+	}
+
+	rows := getS3Rows(results, usage)
+
+	if err := printS3(rows, usage, out); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func getS3Usage(ctx *cli.Context) (*s3Usage, error) {
+	u := &s3Usage{
+		storageClass: strings.ToUpper(ctx.String("class")),
+		gb:           ctx.Float64("gb"),
+		putRequests:  ctx.Float64("put-requests"),
+		getRequests:  ctx.Float64("get-requests"),
+		retrievalGB:  ctx.Float64("retrieval-gb"),
+	}
+
+	if !contains(aws.S3StorageClasses(), u.storageClass) {
+		return nil, fmt.Errorf("Unknown storage class: %s (e.g. %s)", ctx.String("class"), strings.Join(aws.S3StorageClasses(), ", "))
+	}
+
+	if u.gb < 0 || u.putRequests < 0 || u.getRequests < 0 || u.retrievalGB < 0 {
+		return nil, fmt.Errorf("Invalid usage: must not be negative")
+	}
+
+	return u, nil
+}
+
+// getS3Rows groups the products by region.
+func getS3Rows(results []bson.M, usage *s3Usage) []*s3Row {
+	rows := map[string]*s3Row{}
+
+	for _, result := range results {
+		price, err := decodePrice(result)
+		if err != nil {
+			log.Printf("Skip product %v: %v", result["sku"], err)
+			continue
+		}
+
+		row, ok := rows[price.Region]
+		if !ok {
+			row = &s3Row{Region: price.Region, StorageClass: usage.storageClass}
+			rows[price.Region] = row
+		}
+
+		if err := row.addPrice(price); err != nil {
+			log.Printf("Skip product %s: %v", price.Sku, err)
+			continue
+		}
+	}
+
+	var r []*s3Row
+	for _, row := range rows {
+		if !row.hasStorage {
+			continue
+		}
+
+		row.estimate(usage)
+		r = append(r, row)
+	}
+
+	sort.Slice(r, func(i, j int) bool {
+		return r[i].Region < r[j].Region
+	})
+
+	return r
+}
+
+// sortPrice returns the monthly cost if the usage is given, or the storage price of the first tier.
+func (r *s3Row) sortPrice() float64 {
+	if r.Monthly > 0 {
+		return r.Monthly
+	}
+
+	return r.StoragePrice
+}
+
+func (r *s3Row) addPrice(price *aws.Price) error {
+	if len(price.OnDemandTiers) == 0 {
+		return fmt.Errorf("OnDemand price in USD is missing")
+	}
+
+	// The price of the first tier, which is the price of the flat products.
+	p := price.OnDemandTiers[0].PricePerUnitUSD
+
+	switch aws.S3Usage(price.Product.Attributes.UsageType) {
+	case aws.S3UsageStorage:
+		r.StoragePrice = p
+		r.storage = price.OnDemandTiers
+		r.hasStorage = true
+	case aws.S3UsageRequestTier1:
+		r.PutRequestPrice = p
+		r.put = price.OnDemandTiers
+	case aws.S3UsageRequestTier2:
+		r.GetRequestPrice = p
+		r.get = price.OnDemandTiers
+	case aws.S3UsageRetrieval:
+		r.RetrievalPrice = p
+		r.retrieval = price.OnDemandTiers
+	default:
+		return fmt.Errorf("Unknown usage type: %s", price.Product.Attributes.UsageType)
+	}
+
+	return nil
+}
+
+// estimate calculates the monthly cost. Storage is charged by the tiers (e.g. first 50 TB, next 450 TB, over 500 TB).
+func (r *s3Row) estimate(usage *s3Usage) {
+	r.StorageMonthly = aws.TieredCostUSD(r.storage, usage.gb)
+	r.RequestsMonthly = aws.TieredCostUSD(r.put, usage.putRequests) + aws.TieredCostUSD(r.get, usage.getRequests)
+	r.RetrievalMonthly = aws.TieredCostUSD(r.retrieval, usage.retrievalGB)
+	r.Monthly = r.StorageMonthly + r.RequestsMonthly + r.RetrievalMonthly
+}
+
+func printS3(rows []*s3Row, usage *s3Usage, out *output) error {
+	out.header = getS3Header()
+
+	for _, row := range rows {
+		out.add(formatS3(row, usage), bson.M{}, row)
+	}
+
+	return out.render(os.Stdout)
+}
+
+func getS3Header() []string {
+	return []string{
+		"Region",
+		"StorageClass",
+		"Storage(GB)",
+		"StoragePrice(USD/GB-month)",
+		"PutPrice(USD/1K requests)",
+		"GetPrice(USD/1K requests)",
+		"RetrievalPrice(USD/GB)",
+		"Storage(USD/month)",
+		"Requests(USD/month)",
+		"Retrieval(USD/month)",
+		"Total(USD/month)",
+	}
+}
+
+func formatS3(row *s3Row, usage *s3Usage) []string {
+	return []string{
+		na(row.Region),
+		na(row.StorageClass),
+		strconv.FormatFloat(usage.gb, 'f', -1, 64),
+		formatPrice(row.StoragePrice),
+		formatPrice(row.PutRequestPrice * 1000),
+		formatPrice(row.GetRequestPrice * 1000),
+		formatPrice(row.RetrievalPrice),
+		fmt.Sprintf("%.2f", row.StorageMonthly),
+		fmt.Sprintf("%.2f", row.RequestsMonthly),
+		fmt.Sprintf("%.2f", row.RetrievalMonthly),
+		fmt.Sprintf("%.2f", row.Monthly),
+	}
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"math"
+	"testing"
+
+	"github.com/sfuruya0612/apf/internal/aws"
+)
+
+func TestS3Estimate(t *testing.T) {
+	// STANDARD of us-east-1
+	row := &s3Row{
+		StoragePrice: 0.023,
+		storage:      []aws.PriceTier{{EndRange: 51200, PricePerUnitUSD: 0.023}, {BeginRange: 51200, PricePerUnitUSD: 0.022}},
+		put:          []aws.PriceTier{{PricePerUnitUSD: 0.000005}},
+		get:          []aws.PriceTier{{PricePerUnitUSD: 0.0000004}},
+	}
+
+	tests := []struct {
+		name      string
+		usage     *s3Usage
+		want      float64
+		sortPrice float64
+	}{
+		// Without the usage, the rows are sorted by the storage price.
+		{"no usage", &s3Usage{}, 0, 0.023},
+		{"storage and requests", &s3Usage{gb: 100, putRequests: 1e6, getRequests: 1e7}, 2.3 + 5 + 4, 2.3 + 5 + 4},
+		// 50 TB at 0.023 and 50 TB at 0.022
+		{"tiered storage", &s3Usage{gb: 102400}, 1177.6 + 1126.4, 1177.6 + 1126.4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			row.estimate(tt.usage)
+			if math.Abs(row.Monthly-tt.want) > 1e-6 {
+				t.Errorf("got %v, want %v", row.Monthly, tt.want)
+			}
+			if math.Abs(row.sortPrice()-tt.sortPrice) > 1e-6 {
+				t.Errorf("got sort price %v, want %v", row.sortPrice(), tt.sortPrice)
+			}
+		})
+	}
+}
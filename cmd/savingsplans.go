@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sfuruya0612/apf/internal/aws"
+	"github.com/sfuruya0612/apf/internal/store"
+	"github.com/urfave/cli/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var fetchSavingsPlansCommand = &cli.Command{
+	Name:    "savings-plans",
+	Aliases: []string{"sp"},
+	Usage:   "Fetch Savings Plans rates of EC2, Fargate and Lambda for the pricing regions",
+	Action: func(ctx *cli.Context) error {
+		return fetchSavingsPlans(ctx.String("profile"), ctx.String("region"), getStoreURI(ctx), ctx.StringSlice("pricing-region"), ctx.Int("batch-size"), ctx.Int("keep"))
+	},
+}
+
+func fetchSavingsPlans(profile, region, storeUri string, pricingRegions []string, batchSize, keep int) error {
+	cfg, err := aws.Config(profile, region)
+	if err != nil {
+		return fmt.Errorf("Fetch: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	st, err := store.Open(storeUri)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	w, err := newPriceWriter(st, aws.SavingsPlansServiceCode, batchSize)
+	if err != nil {
+		return err
+	}
+
+	if err := aws.GetSavingsPlanRates(cfg, pricingRegions, func(r []*aws.SavingsPlanRate) error {
+		return w.WriteRates(ctx, r)
+	}); err != nil {
+		w.Abort(ctx)
+		return fmt.Errorf("Failed to fetch Savings Plans rates: %w", err)
+	}
+
+	if err := w.Commit(ctx, keep); err != nil {
+		w.Abort(ctx)
+		return err
+	}
+
+	log.Println("Completed saving Savings Plans rates to the store")
+
+	return nil
+}
+
+// savingsPlan is a Savings Plans offering like compute-1yr-no-upfront.
+type savingsPlan struct {
+	name          string
+	planType      string
+	lease         string
+	paymentOption string
+}
+
+var savingsPlanTypes = map[string]string{
+	"compute":     aws.SavingsPlanTypeCompute,
+	"ec2":         aws.SavingsPlanTypeEc2Instance,
+	"ec2instance": aws.SavingsPlanTypeEc2Instance,
+}
+
+var savingsPlanPaymentOptions = map[string]string{
+	"no-upfront":      "No Upfront",
+	"partial-upfront": "Partial Upfront",
+	"all-upfront":     "All Upfront",
+}
+
+// getSavingsPlan returns nil if --savings-plan is not set.
+func getSavingsPlan(ctx *cli.Context) (*savingsPlan, error) {
+	name := strings.ToLower(ctx.String("savings-plan"))
+	if name == "" {
+		return nil, nil
+	}
+
+	invalid := fmt.Errorf("Invalid Savings Plan: %s (e.g. compute-1yr-no-upfront, ec2instance-3yr-all-upfront)", ctx.String("savings-plan"))
+
+	s := strings.SplitN(name, "-", 3)
+	if len(s) != 3 {
+		return nil, invalid
+	}
+
+	p := &savingsPlan{
+		name:          name,
+		planType:      savingsPlanTypes[s[0]],
+		lease:         s[1],
+		paymentOption: savingsPlanPaymentOptions[s[2]],
+	}
+
+	if p.planType == "" || (p.lease != "1yr" && p.lease != "3yr") || p.paymentOption == "" {
+		return nil, invalid
+	}
+
+	return p, nil
+}
+
+// savingsPlanRates are the rates of a Savings Plan in USD.
+type savingsPlanRates struct {
+	// byOperation is keyed by the region, the usage type and the operation.
+	byOperation map[string]float64
+	// byUsageType is keyed by the region and the usage type, for the products without the operation (e.g. Fargate, Lambda).
+	byUsageType map[string]float64
+}
+
+// findSavingsPlanRates returns the rates of the plan for the product type (e.g. EC2, Fargate, Lambda).
+func findSavingsPlanRates(storeUri string, cond *condition, plan *savingsPlan, productType string) (*savingsPlanRates, error) {
+	filter := bson.M{
+		"producttype":         productType,
+		"plantype":            plan.planType,
+		"leasecontractlength": plan.lease,
+		"paymentoption":       plan.paymentOption,
+	}
+
+	// Only the region applies to the rates.
+	results, err := findProducts(storeUri, getCollectionName(aws.SavingsPlansServiceCode), &condition{region: cond.region}, filter)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to find Savings Plans rates of %s (run fetch savings-plans): %w", plan.name, err)
+	}
+
+	rates := &savingsPlanRates{byOperation: map[string]float64{}, byUsageType: map[string]float64{}}
+	for _, result := range results {
+		var r aws.SavingsPlanRate
+		b, err := bson.Marshal(result)
+		if err == nil {
+			err = bson.Unmarshal(b, &r)
+		}
+		if err != nil {
+			log.Printf("Skip Savings Plans rate %v: %v", result["_id"], err)
+			continue
+		}
+
+		rates.byOperation[r.Region+"/"+r.UsageType+"/"+r.Operation] = r.RateUSD
+		rates.byUsageType[r.Region+"/"+r.UsageType] = r.RateUSD
+	}
+
+	return rates, nil
+}
+
+// getComputeSavingsPlanRates returns the rates of --savings-plan for the serverless product type (e.g. Fargate, Lambda),
+// or nil if it is not set. EC2 Instance Savings Plans apply only to EC2.
+func getComputeSavingsPlanRates(ctx *cli.Context, cond *condition, productType string) (*savingsPlanRates, error) {
+	plan, err := getSavingsPlan(ctx)
+	if err != nil || plan == nil {
+		return nil, err
+	}
+
+	if plan.planType != aws.SavingsPlanTypeCompute {
+		return nil, fmt.Errorf("Unsupported Savings Plan for %s: %s (e.g. compute-1yr-no-upfront)", productType, plan.name)
+	}
+
+	log.Printf("Prices covered by %s are the Savings Plans rates", plan.name)
+
+	return findSavingsPlanRates(getStoreURI(ctx), cond, plan, productType)
+}
+
+// rate returns the rate of the EC2 product, which is joined by the usage type and the operation (e.g. RunInstances:0002 for Windows).
+func (r *savingsPlanRates) rate(price *aws.Price) (float64, bool) {
+	attr := price.Product.Attributes
+	v, ok := r.byOperation[price.Region+"/"+attr.UsageType+"/"+attr.Operation]
+	return v, ok
+}
+
+// apply replaces the On-Demand price of the product with the rate joined by the usage type.
+// Products not covered by the plan (e.g. Lambda requests) are left as they are.
+func (r *savingsPlanRates) apply(price *aws.Price) {
+	v, ok := r.byUsageType[price.Region+"/"+price.Product.Attributes.UsageType]
+	if !ok {
+		return
+	}
+
+	var unit string
+	if len(price.OnDemandTiers) > 0 {
+		unit = price.OnDemandTiers[0].Unit
+	}
+
+	price.OnDemandTiers = []aws.PriceTier{{BeginRange: 0, Unit: unit, PricePerUnitUSD: v}}
+	price.OnDemandPricePerUSD = strconv.FormatFloat(v, 'f', -1, 64)
+}
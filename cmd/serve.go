@@ -0,0 +1,474 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sfuruya0612/apf/internal/store"
+	"github.com/urfave/cli/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var ServeCommand = &cli.Command{
+	Name:  "serve",
+	Usage: "Serve the price subcommands as JSON REST API (e.g. /v1/ec2?instanceType=m5.large)",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "listen",
+			Aliases: []string{"l"},
+			Value:   ":8080",
+			Usage:   "Specify an address to listen on",
+		},
+		&cli.BoolFlag{
+			Name:  "openapi",
+			Usage: "Print the OpenAPI document of the API and exit",
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		if ctx.Bool("openapi") {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(getOpenAPI(ctx.App.Version))
+		}
+
+		return serve(getStoreURI(ctx), ctx.String("listen"), ctx.App.Version)
+	},
+}
+
+// apiParam is a query parameter of the API, which is given to the price subcommands as the flag.
+type apiParam struct {
+	name string
+	flag string
+	// kind is the type of the OpenAPI schema (e.g. string, number, integer, boolean).
+	kind        string
+	description string
+	def         string
+	enum        []string
+}
+
+// apiEndpoint is the API of a price subcommand.
+type apiEndpoint struct {
+	path        string
+	operationId string
+	summary     string
+	collection  string
+	params      []apiParam
+	filter      func(ctx flagValues) (bson.M, error)
+}
+
+// commonParams are the flags of the price command.
+var commonParams = []apiParam{
+	{name: "region", flag: "region", kind: "string", description: "Region code (e.g. ap-northeast-1, us-east-1)"},
+	{name: "instanceType", flag: "instance-type", kind: "string", description: "Instance type"},
+	{name: "vcpu", flag: "vcpu", kind: "string", description: "vCPU"},
+	{name: "memory", flag: "memory", kind: "string", description: "Memory in GiB, matched within 1% (e.g. 8, 13.07 GiB)"},
+	{name: "minVcpu", flag: "min-vcpu", kind: "number", description: "Minimum number of vCPU"},
+	{name: "maxVcpu", flag: "max-vcpu", kind: "number", description: "Maximum number of vCPU"},
+	{name: "minMemory", flag: "min-memory", kind: "number", description: "Minimum memory in GiB"},
+	{name: "maxMemory", flag: "max-memory", kind: "number", description: "Maximum memory in GiB"},
+	{name: "maxPrice", flag: "max-price", kind: "number", description: "Maximum price in USD/hour (the effective price for reserved term)"},
+	{name: "sortBy", flag: "sort-by", kind: "string", description: "Key to sort by", enum: []string{"price", "vcpu", "memory", "instance-type"}},
+	{name: "order", flag: "order", kind: "string", description: "Sort order", def: "asc", enum: []string{"asc", "desc"}},
+	{name: "limit", flag: "limit", kind: "integer", description: "Maximum number of products (default: no limit)"},
+	{name: "term", flag: "term", kind: "string", description: "Pricing term", def: "ondemand", enum: []string{"ondemand", "reserved"}},
+	{name: "lease", flag: "lease", kind: "string", description: "Lease contract length of reserved term", enum: []string{"1yr", "3yr"}},
+	{name: "purchaseOption", flag: "purchase-option", kind: "string", description: "Purchase option of reserved term", enum: []string{"no", "partial", "all"}},
+	{name: "offeringClass", flag: "offering-class", kind: "string", description: "Offering class of reserved term", enum: []string{"standard", "convertible"}},
+	{name: "asOf", flag: "as-of", kind: "string", description: "Date to get the prices effective at (e.g. 2023-03-01, 2023-03-01T09:00:00+09:00)"},
+}
+
+var apiEndpoints = []*apiEndpoint{
+	{
+		path:        "/v1/ec2",
+		operationId: "getEc2",
+		summary:     "Get EC2 pricing",
+		collection:  "ec2",
+		params: []apiParam{
+			{name: "os", flag: "os", kind: "string", description: "OS (e.g. Linux, RHEL, SUSE, Windows)", def: flagDefault(ec2Command, "os")},
+			{name: "tenancy", flag: "tenancy", kind: "string", description: "Tenancy (e.g. Shared, Dedicated, Host)", def: flagDefault(ec2Command, "tenancy")},
+			{name: "capacityStatus", flag: "capacitystatus", kind: "string", description: "Capacity status (e.g. Used, UnusedCapacityReservation)", def: flagDefault(ec2Command, "capacitystatus")},
+			{name: "preInstalledSw", flag: "preinstalled-sw", kind: "string", description: "Pre-installed software (e.g. NA, SQL Web, SQL Std)", def: flagDefault(ec2Command, "preinstalled-sw")},
+			{name: "licenseModel", flag: "license-model", kind: "string", description: "License model (e.g. No License required, Bring your own license) (default: all)"},
+		},
+		filter: func(ctx flagValues) (bson.M, error) {
+			return getEc2Filter(ctx), nil
+		},
+	},
+	{
+		path:        "/v1/rds",
+		operationId: "getRds",
+		summary:     "Get RDS database instance pricing",
+		collection:  "rds",
+		params: []apiParam{
+			{name: "engine", flag: "engine", kind: "string", description: "Database engine (e.g. Aurora MySQL, MySQL, PostgreSQL)", def: flagDefault(rdsCommand, "engine")},
+			{name: "deploymentOption", flag: "deployment-option", kind: "string", description: "Deployment option (e.g. Single-AZ, Multi-AZ)", def: flagDefault(rdsCommand, "deployment-option")},
+			{name: "ioOptimized", flag: "io-optimized", kind: "boolean", description: "Use the Aurora I/O-Optimized storage configuration"},
+			{name: "licenseModel", flag: "license-model", kind: "string", description: "License model (e.g. License included, Bring your own license) (default: all)"},
+		},
+		filter: func(ctx flagValues) (bson.M, error) {
+			usage, err := getRdsUsage(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return getRdsFilter(usage), nil
+		},
+	},
+	{
+		path:        "/v1/elasticache",
+		operationId: "getElasticache",
+		summary:     "Get ElastiCache pricing",
+		collection:  "elasticache",
+		params: []apiParam{
+			{name: "engine", flag: "engine", kind: "string", description: "Cache engine (e.g. Redis, Memcached)", def: flagDefault(elasticacheCommand, "engine")},
+		},
+		filter: func(ctx flagValues) (bson.M, error) {
+			return getElasticacheFilter(ctx), nil
+		},
+	},
+}
+
+// flagDefault returns the default value of the flag of the price subcommand, so the API has the same defaults.
+func flagDefault(command *cli.Command, name string) string {
+	for _, f := range command.Flags {
+		if g, ok := f.(cli.DocGenerationFlag); ok && contains(f.Names(), name) {
+			return g.GetValue()
+		}
+	}
+
+	return ""
+}
+
+func (e *apiEndpoint) allParams() []apiParam {
+	return append(append([]apiParam{}, commonParams...), e.params...)
+}
+
+// queryValues are the validated query parameters keyed by the flag name.
+// Parameters not given have the default values of the flags.
+type queryValues struct {
+	values map[string]string
+	set    map[string]bool
+}
+
+// parseQuery validates the query parameters by the types and the enums of the endpoint.
+func (e *apiEndpoint) parseQuery(query map[string][]string) (*queryValues, error) {
+	params := map[string]apiParam{}
+	for _, p := range e.allParams() {
+		params[p.name] = p
+	}
+
+	q := &queryValues{values: map[string]string{}, set: map[string]bool{}}
+	for _, p := range params {
+		q.values[p.flag] = p.def
+	}
+
+	for name, values := range query {
+		p, ok := params[name]
+		if !ok {
+			return nil, fmt.Errorf("Unknown parameter: %s", name)
+		}
+
+		if len(values) != 1 {
+			return nil, fmt.Errorf("Parameter must be given once: %s", name)
+		}
+		v := values[0]
+
+		var err error
+		switch p.kind {
+		case "number":
+			_, err = strconv.ParseFloat(v, 64)
+		case "integer":
+			_, err = strconv.ParseInt(v, 10, 64)
+		case "boolean":
+			_, err = strconv.ParseBool(v)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Invalid %s: %s (must be %s)", name, v, p.kind)
+		}
+
+		if len(p.enum) > 0 && !contains(p.enum, v) {
+			return nil, fmt.Errorf("Invalid %s: %s (e.g. %s)", name, v, strings.Join(p.enum, ", "))
+		}
+
+		q.values[p.flag] = v
+		q.set[p.flag] = true
+	}
+
+	return q, nil
+}
+
+func (q *queryValues) String(name string) string {
+	return q.values[name]
+}
+
+func (q *queryValues) Int64(name string) int64 {
+	v, _ := strconv.ParseInt(q.values[name], 10, 64)
+	return v
+}
+
+func (q *queryValues) Float64(name string) float64 {
+	v, _ := strconv.ParseFloat(q.values[name], 64)
+	return v
+}
+
+func (q *queryValues) Bool(name string) bool {
+	v, _ := strconv.ParseBool(q.values[name])
+	return v
+}
+
+func (q *queryValues) IsSet(name string) bool {
+	return q.set[name]
+}
+
+// server shares the store between the requests. The connections are pooled by the store.
+type server struct {
+	st      store.Store
+	version string
+}
+
+func serve(storeUri, listen, version string) error {
+	st, err := store.Open(storeUri)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	s := &server{st: st, version: version}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/openapi.json", s.handleOpenAPI)
+	for _, e := range apiEndpoints {
+		mux.HandleFunc(e.path, s.handlePrice(e))
+	}
+
+	return listenAndServe(listen, mux)
+}
+
+// listenAndServe serves the handler until SIGINT or SIGTERM, and then shuts down gracefully.
+func listenAndServe(listen string, handler http.Handler) error {
+	srv := &http.Server{
+		Addr:              listen,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errCh := make(chan error, 1)
+	go func() {
+		log.Printf("Listening on %s\n", listen)
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("Failed to serve: %w", err)
+	case <-ctx.Done():
+	}
+
+	log.Println("Shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("Failed to shut down: %w", err)
+	}
+
+	return nil
+}
+
+func (s *server) handlePrice(e *apiEndpoint) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("Method not allowed: %s", r.Method))
+			return
+		}
+
+		q, err := e.parseQuery(r.URL.Query())
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		term, err := getPriceTerm(q)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		cond, err := getCondition(q)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		order := term.rowOrder(cond)
+
+		filter, err := e.filter(q)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		results, err := findStore(s.st, r.Context(), e.collection, cond, term.condition(filter))
+		if err != nil {
+			log.Printf("%s %s: %v", r.Method, r.URL, err)
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		// The records are the same as the json output of the price subcommands.
+		out := &output{format: "json", records: []interface{}{}, order: order}
+		for _, result := range results {
+			price, err := decodePrice(result)
+			if err != nil {
+				log.Printf("Skip product %v: %v", result["sku"], err)
+				continue
+			}
+
+			prices, err := term.prices(price)
+			if err != nil {
+				log.Printf("Skip product %s: %v", price.Sku, err)
+				continue
+			}
+
+			for _, p := range prices {
+				out.add(p.fields, result, p)
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := out.render(w); err != nil {
+			log.Printf("%s %s: %v", r.Method, r.URL, err)
+		}
+	}
+}
+
+func (s *server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("Method not allowed: %s", r.Method))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(getOpenAPI(s.version)); err != nil {
+		log.Printf("%s %s: %v", r.Method, r.URL, err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// getOpenAPI returns the OpenAPI 3.0 document generated from the endpoints.
+func getOpenAPI(version string) map[string]interface{} {
+	paths := map[string]interface{}{}
+	for _, e := range apiEndpoints {
+		var params []interface{}
+		for _, p := range e.allParams() {
+			schema := map[string]interface{}{"type": p.kind}
+			if len(p.enum) > 0 {
+				schema["enum"] = p.enum
+			}
+			if p.def != "" {
+				schema["default"] = p.def
+			}
+
+			params = append(params, map[string]interface{}{
+				"name":        p.name,
+				"in":          "query",
+				"description": p.description,
+				"schema":      schema,
+			})
+		}
+
+		paths[e.path] = map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary":     e.summary,
+				"operationId": e.operationId,
+				"parameters":  params,
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "Products with the prices",
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type":  "array",
+									"items": map[string]interface{}{"$ref": "#/components/schemas/Product"},
+								},
+							},
+						},
+					},
+					"400": errorResponse("Invalid parameters"),
+					"500": errorResponse("Failed to find the products"),
+				},
+			},
+		}
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "apf",
+			"version": version,
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": map[string]interface{}{
+				"Product": map[string]interface{}{
+					"type":                 "object",
+					"additionalProperties": true,
+					"properties": map[string]interface{}{
+						"sku":         map[string]interface{}{"type": "string"},
+						"region":      map[string]interface{}{"type": "string"},
+						"servicecode": map[string]interface{}{"type": "string"},
+						"product":     map[string]interface{}{"type": "object"},
+						"price":       map[string]interface{}{"$ref": "#/components/schemas/Price"},
+					},
+				},
+				"Price": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"term":                map[string]interface{}{"type": "string"},
+						"leaseContractLength": map[string]interface{}{"type": "string"},
+						"purchaseOption":      map[string]interface{}{"type": "string"},
+						"offeringClass":       map[string]interface{}{"type": "string"},
+						"upfrontFee":          map[string]interface{}{"type": "number"},
+						"hourly":              map[string]interface{}{"type": "number"},
+						"effectiveHourly":     map[string]interface{}{"type": "number"},
+						"monthly":             map[string]interface{}{"type": "number"},
+					},
+				},
+				"Error": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"error": map[string]interface{}{"type": "string"},
+					},
+				},
+			},
+		},
+	}
+}
+
+func errorResponse(description string) map[string]interface{} {
+	return map[string]interface{}{
+		"description": description,
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{
+				"schema": map[string]interface{}{"$ref": "#/components/schemas/Error"},
+			},
+		},
+	}
+}
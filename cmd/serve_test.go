@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	ec2 := apiEndpoints[0]
+
+	tests := []struct {
+		name    string
+		query   string
+		want    map[string]string
+		set     []string
+		wantErr bool
+	}{
+		{
+			name:  "defaults",
+			query: "",
+			// The defaults are those of the flags of the price subcommand.
+			want: map[string]string{"os": "Linux", "tenancy": "Shared", "capacitystatus": "Used", "preinstalled-sw": "NA", "term": "ondemand", "order": "asc", "license-model": ""},
+		},
+		{
+			name:  "given",
+			query: "os=Windows&instanceType=m5.large&maxPrice=0.5&limit=10&sortBy=price&order=desc",
+			want:  map[string]string{"os": "Windows", "instance-type": "m5.large", "max-price": "0.5", "limit": "10", "sort-by": "price", "order": "desc", "tenancy": "Shared"},
+			set:   []string{"os", "instance-type", "max-price", "limit", "sort-by", "order"},
+		},
+		{name: "unknown", query: "engine=MySQL", wantErr: true},
+		{name: "repeated", query: "region=us-east-1&region=ap-northeast-1", wantErr: true},
+		{name: "not a number", query: "maxPrice=cheap", wantErr: true},
+		{name: "not an integer", query: "limit=1.5", wantErr: true},
+		{name: "not in enum", query: "term=spot", wantErr: true},
+		{name: "enum is case sensitive", query: "order=DESC", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			q, err := ec2.parseQuery(query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			for flag, want := range tt.want {
+				if got := q.String(flag); got != want {
+					t.Errorf("got %s %q, want %q", flag, got, want)
+				}
+			}
+
+			for _, flag := range tt.set {
+				if !q.IsSet(flag) {
+					t.Errorf("got %s not set", flag)
+				}
+			}
+			if q.IsSet("tenancy") {
+				t.Error("got tenancy set by the default")
+			}
+		})
+	}
+
+	// The values are converted by the types.
+	q, err := ec2.parseQuery(url.Values{"maxPrice": {"0.5"}, "limit": {"10"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Float64("max-price") != 0.5 || q.Int64("limit") != 10 {
+		t.Errorf("got max price %v and limit %v, want 0.5 and 10", q.Float64("max-price"), q.Int64("limit"))
+	}
+
+	// Boolean parameters of the other endpoints.
+	rds := apiEndpoints[1]
+	if _, err := rds.parseQuery(url.Values{"ioOptimized": {"yes"}}); err == nil {
+		t.Error("got no error of ioOptimized=yes")
+	}
+	if q, err := rds.parseQuery(url.Values{"ioOptimized": {"true"}}); err != nil || !q.Bool("io-optimized") {
+		t.Errorf("got ioOptimized %v (%v), want true", q, err)
+	}
+}
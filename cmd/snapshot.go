@@ -0,0 +1,311 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sfuruya0612/apf/internal/aws"
+	"github.com/sfuruya0612/apf/internal/store"
+	"github.com/urfave/cli/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// snapshotCollection is the collection of the snapshot metadata.
+const snapshotCollection = "snapshots"
+
+// liveSnapshotCollection is the collection of the snapshots swapped into the live collections.
+const liveSnapshotCollection = "live_snapshots"
+
+// snapshotTimeFormat is the format of the snapshot ID suffixed to the collection name (e.g. ec2_20230601093000).
+const snapshotTimeFormat = "20060102150405"
+
+var SnapshotCommand = &cli.Command{
+	Name:  "snapshot",
+	Usage: "Manage snapshots of AWS pricing information",
+	Subcommands: []*cli.Command{
+		{
+			Name:      "list",
+			Usage:     "List snapshots of the collection",
+			ArgsUsage: "<ec2|rds|elasticache>",
+			Action: func(ctx *cli.Context) error {
+				collection, err := getCollectionArg(ctx)
+				if err != nil {
+					return err
+				}
+
+				return listSnapshots(getStoreURI(ctx), collection)
+			},
+		},
+		{
+			Name:      "rollback",
+			Usage:     "Replace the collection with a previous snapshot",
+			ArgsUsage: "<ec2|rds|elasticache>",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "to",
+					Usage: "Specify a snapshot ID (default: the snapshot before the live one)",
+				},
+			},
+			Action: func(ctx *cli.Context) error {
+				collection, err := getCollectionArg(ctx)
+				if err != nil {
+					return err
+				}
+
+				return rollbackSnapshot(getStoreURI(ctx), collection, ctx.String("to"))
+			},
+		},
+	},
+}
+
+// snapshot is the metadata of a fetch.
+type snapshot struct {
+	ID              string
+	Collection      string
+	ServiceCode     string
+	FetchedAt       time.Time
+	PublicationDate string
+	Count           int64
+}
+
+func recordSnapshot(st store.Store, ctx context.Context, s *snapshot) error {
+	if err := st.Insert(ctx, snapshotCollection, []interface{}{s}); err != nil {
+		return fmt.Errorf("Failed to record snapshot %s of %s collection: %w", s.ID, s.Collection, err)
+	}
+
+	return nil
+}
+
+// getSnapshotMetadata returns the metadata of the snapshots of the collection by ID.
+func getSnapshotMetadata(st store.Store, ctx context.Context, collection string) (map[string]*snapshot, error) {
+	results, err := st.Find(ctx, snapshotCollection, bson.M{"collection": collection}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to find snapshot metadata: %w", err)
+	}
+
+	metadata := map[string]*snapshot{}
+	for _, result := range results {
+		b, err := bson.Marshal(result)
+		if err != nil {
+			return nil, err
+		}
+
+		s := &snapshot{}
+		if err := bson.Unmarshal(b, s); err != nil {
+			return nil, fmt.Errorf("Invalid snapshot metadata: %w", err)
+		}
+		metadata[s.ID] = s
+	}
+
+	return metadata, nil
+}
+
+// liveSnapshot is the snapshot whose documents are in the live collection.
+type liveSnapshot struct {
+	Collection string
+	ID         string
+}
+
+func setLiveSnapshot(st store.Store, ctx context.Context, collection, id string) error {
+	if err := st.Delete(ctx, liveSnapshotCollection, bson.M{"collection": collection}); err != nil {
+		return fmt.Errorf("Failed to remove live snapshot of %s collection: %w", collection, err)
+	}
+
+	if err := st.Insert(ctx, liveSnapshotCollection, []interface{}{&liveSnapshot{Collection: collection, ID: id}}); err != nil {
+		return fmt.Errorf("Failed to record live snapshot %s of %s collection: %w", id, collection, err)
+	}
+
+	return nil
+}
+
+// getLiveSnapshot returns the ID of the snapshot in the live collection.
+// Collections swapped before the live snapshot was recorded are taken to have the latest snapshot.
+func getLiveSnapshot(st store.Store, ctx context.Context, collection string, ids []string) (string, error) {
+	results, err := st.Find(ctx, liveSnapshotCollection, bson.M{"collection": collection}, nil)
+	if err != nil {
+		return "", fmt.Errorf("Failed to find live snapshot of %s collection: %w", collection, err)
+	}
+
+	if len(results) > 0 {
+		if id, ok := results[0]["id"].(string); ok {
+			return id, nil
+		}
+	}
+
+	if len(ids) > 0 {
+		return ids[0], nil
+	}
+
+	return "", nil
+}
+
+func getCollectionArg(ctx *cli.Context) (string, error) {
+	collection := ctx.Args().First()
+
+	// Savings Plans rates are also fetched into snapshots.
+	for _, sc := range append(append([]string{}, serviceCodes...), aws.SavingsPlansServiceCode) {
+		if getCollectionName(sc) == collection {
+			return collection, nil
+		}
+	}
+
+	return "", fmt.Errorf("Specify a valid collection (e.g. ec2, rds, elasticache)")
+}
+
+func getSnapshotName(collection string, t time.Time) string {
+	return fmt.Sprintf("%s_%s", collection, t.UTC().Format(snapshotTimeFormat))
+}
+
+// getSnapshots returns the snapshot IDs of the collection, newest first.
+func getSnapshots(st store.Store, ctx context.Context, collection string) ([]string, error) {
+	names, err := st.ListCollections(ctx, fmt.Sprintf("^%s_[0-9]{14}$", regexp.QuoteMeta(collection)))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list snapshots: %w", err)
+	}
+
+	ids := make([]string, 0, len(names))
+	for _, name := range names {
+		ids = append(ids, strings.TrimPrefix(name, collection+"_"))
+	}
+
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+
+	return ids, nil
+}
+
+// pruneSnapshots removes the snapshots of the collection except for the last keep ones.
+func pruneSnapshots(st store.Store, ctx context.Context, collection string, keep int) error {
+	ids, err := getSnapshots(st, ctx, collection)
+	if err != nil {
+		return err
+	}
+
+	// The latest snapshot has the same data as the live collection, so it is always kept.
+	if keep < 1 {
+		keep = 1
+	}
+
+	if len(ids) <= keep {
+		return nil
+	}
+
+	for _, id := range ids[keep:] {
+		name := fmt.Sprintf("%s_%s", collection, id)
+
+		log.Printf("Drop %s collection\n", name)
+
+		if err := st.Drop(ctx, name); err != nil {
+			return fmt.Errorf("Failed to remove %s collection: %w", name, err)
+		}
+
+		if err := st.Delete(ctx, snapshotCollection, bson.M{"collection": collection, "id": id}); err != nil {
+			return fmt.Errorf("Failed to remove snapshot metadata %s of %s collection: %w", id, collection, err)
+		}
+	}
+
+	return nil
+}
+
+func listSnapshots(storeUri, collection string) error {
+	st, err := store.Open(storeUri)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	ctx := context.Background()
+
+	ids, err := getSnapshots(st, ctx, collection)
+	if err != nil {
+		return err
+	}
+
+	metadata, err := getSnapshotMetadata(st, ctx, collection)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, ' ', 0)
+
+	if _, err := fmt.Fprintln(w, strings.Join([]string{"SnapshotID", "FetchedAt", "PublicationDate", "Products"}, "\t")); err != nil {
+		return fmt.Errorf("Failed to print header: %w", err)
+	}
+
+	for _, id := range ids {
+		s, ok := metadata[id]
+		if !ok {
+			// Snapshots taken before the metadata was recorded.
+			t, err := time.Parse(snapshotTimeFormat, id)
+			if err != nil {
+				return fmt.Errorf("Invalid snapshot ID %s: %w", id, err)
+			}
+
+			count, err := st.Count(ctx, fmt.Sprintf("%s_%s", collection, id))
+			if err != nil {
+				return fmt.Errorf("Failed to count snapshot %s: %w", id, err)
+			}
+
+			s = &snapshot{ID: id, FetchedAt: t, PublicationDate: "unknown", Count: count}
+		}
+
+		if _, err := fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", s.ID, s.FetchedAt.Format(time.RFC3339), s.PublicationDate, s.Count); err != nil {
+			return fmt.Errorf("Failed to print result: %w", err)
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("Failed to flush: %w", err)
+	}
+
+	return nil
+}
+
+func rollbackSnapshot(storeUri, collection, id string) error {
+	st, err := store.Open(storeUri)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	ctx := context.Background()
+
+	ids, err := getSnapshots(st, ctx, collection)
+	if err != nil {
+		return err
+	}
+
+	if id == "" {
+		live, err := getLiveSnapshot(st, ctx, collection, ids)
+		if err != nil {
+			return err
+		}
+
+		// Rolling back again goes back further than the snapshot rolled back to.
+		for i, s := range ids {
+			if s == live && i+1 < len(ids) {
+				id = ids[i+1]
+			}
+		}
+
+		if id == "" {
+			return fmt.Errorf("No snapshot of %s collection before %s", collection, live)
+		}
+	} else if !contains(ids, id) {
+		return fmt.Errorf("Snapshot %s of %s collection is not found", id, collection)
+	}
+
+	if err := swapCollection(st, ctx, fmt.Sprintf("%s_%s", collection, id), collection); err != nil {
+		return err
+	}
+
+	log.Printf("Rolled back %s collection to snapshot %s\n", collection, id)
+
+	return nil
+}
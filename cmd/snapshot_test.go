@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sfuruya0612/apf/internal/store"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// openTestStore returns a SQLite store in a temporary directory.
+func openTestStore(t *testing.T) store.Store {
+	t.Helper()
+
+	st, err := store.Open("sqlite://" + filepath.Join(t.TempDir(), "apf.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+
+	return st
+}
+
+// snapshotPrice returns a document of the SKU with the On-Demand price effective at the date.
+func snapshotPrice(sku, effectiveDate string, price float64) bson.M {
+	return bson.M{
+		"sku":               sku,
+		"ondemandhourlyusd": price,
+		"ondemand":          bson.A{bson.M{"effectivedate": effectiveDate}},
+	}
+}
+
+func TestFindAsOf(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+
+	snapshots := map[string][]interface{}{
+		"ec2_20230301000000": {
+			snapshotPrice("A", "2023-01-01T00:00:00Z", 0.1),
+			snapshotPrice("B", "2023-01-01T00:00:00Z", 0.2),
+		},
+		// A is cut effective from 2023-03-15 and C is added.
+		"ec2_20230401000000": {
+			snapshotPrice("A", "2023-03-15T00:00:00Z", 0.09),
+			snapshotPrice("B", "2023-01-01T00:00:00Z", 0.2),
+			snapshotPrice("C", "2023-04-01T00:00:00Z", 0.3),
+		},
+	}
+	for collection, docs := range snapshots {
+		if err := st.Insert(ctx, collection, docs); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		asOf string
+		want string
+	}{
+		{"first snapshot", "2023-03-10T00:00:00Z", "A:0.1,B:0.2"},
+		// The price effective at the date is fetched in the later snapshot.
+		{"effective in a later snapshot", "2023-03-20T00:00:00Z", "A:0.09,B:0.2"},
+		{"latest snapshot", "2023-05-01T00:00:00Z", "A:0.09,B:0.2,C:0.3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asOf, err := time.Parse(time.RFC3339, tt.asOf)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			filter := appendCondition(bson.M{}, &condition{asOf: asOf})
+			results, err := findAsOf(st, ctx, "ec2", filter, asOf)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, result := range results {
+				got = append(got, fmt.Sprintf("%v:%v", result["sku"], result["ondemandhourlyusd"]))
+			}
+			sort.Strings(got)
+
+			if strings.Join(got, ",") != tt.want {
+				t.Errorf("got %v, want %s", got, tt.want)
+			}
+		})
+	}
+
+	// Dates before the oldest snapshot can't be resolved.
+	if _, err := findAsOf(st, ctx, "ec2", bson.M{}, time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)); err == nil {
+		t.Error("got no error before the oldest snapshot")
+	}
+}
+
+func TestRollbackSnapshot(t *testing.T) {
+	storeUri := "sqlite://" + filepath.Join(t.TempDir(), "apf.db")
+
+	st, err := store.Open(storeUri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	ctx := context.Background()
+
+	ids := []string{"20230401000000", "20230501000000", "20230601000000"}
+	for i, id := range ids {
+		if err := st.Insert(ctx, "ec2_"+id, []interface{}{snapshotPrice("A", "2023-01-01T00:00:00Z", float64(i+1))}); err != nil {
+			t.Fatal(err)
+		}
+		if err := swapCollection(st, ctx, "ec2_"+id, "ec2"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	live := func() (string, float64) {
+		t.Helper()
+
+		id, err := getLiveSnapshot(st, ctx, "ec2", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		results, err := st.Find(ctx, "ec2", bson.M{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 {
+			t.Fatalf("got %d documents, want 1", len(results))
+		}
+		price, _ := results[0]["ondemandhourlyusd"].(float64)
+		return id, price
+	}
+
+	tests := []struct {
+		to      string
+		want    string
+		price   float64
+		wantErr bool
+	}{
+		{"", "20230501000000", 2, false},
+		// Rolled back relative to the live snapshot, not the latest.
+		{"", "20230401000000", 1, false},
+		{"", "", 0, true},
+		{"20230601000000", "20230601000000", 3, false},
+		{"20230101000000", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		err := rollbackSnapshot(storeUri, "ec2", tt.to)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("rollback to %q: got error %v, want error %v", tt.to, err, tt.wantErr)
+		}
+		if tt.wantErr {
+			continue
+		}
+
+		// The snapshots are kept after they are swapped.
+		if got, err := getSnapshots(st, ctx, "ec2"); err != nil || len(got) != len(ids) {
+			t.Errorf("got snapshots %v, want %v", got, ids)
+		}
+
+		if id, price := live(); id != tt.want || price != tt.price {
+			t.Errorf("rollback to %q: got %s with %g, want %s with %g", tt.to, id, price, tt.want, tt.price)
+		}
+	}
+}
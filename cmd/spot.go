@@ -0,0 +1,513 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sfuruya0612/apf/internal/aws"
+	"github.com/sfuruya0612/apf/internal/store"
+	"github.com/urfave/cli/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// spotCollection is the time series collection of the Spot price history.
+const spotCollection = "spot"
+
+// spotFetchCollection is the collection of the filters of the Spot price history fetched per region.
+const spotFetchCollection = "spot_fetches"
+
+// spotIndexes are the keys used by the price subcommands to find the Spot prices.
+var spotIndexes = []string{"meta.region", "meta.instancetype"}
+
+var fetchSpotCommand = &cli.Command{
+	Name:  "spot",
+	Usage: "Fetch EC2 Spot price history of the pricing regions",
+	Flags: []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:    "instance-type",
+			Aliases: []string{"i"},
+			Usage:   "Specify instance types to fetch (default: all)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "product-description",
+			Value: cli.NewStringSlice("Linux/UNIX"),
+			Usage: "Specify product descriptions to fetch (e.g. Linux/UNIX, Windows, Red Hat Enterprise Linux, SUSE Linux)",
+		},
+		&cli.StringSliceFlag{
+			Name:    "availability-zone",
+			Aliases: []string{"az"},
+			Usage:   "Specify Availability Zones to fetch (default: all)",
+		},
+		&cli.IntFlag{
+			Name:  "days",
+			Value: 30,
+			Usage: "Specify the days of the history fetched for a region without the history. Otherwise, the history after the latest stored is fetched",
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		if ctx.Int("days") <= 0 {
+			return fmt.Errorf("Invalid days: %d", ctx.Int("days"))
+		}
+
+		input := &aws.SpotPriceHistoryInput{
+			InstanceTypes:       ctx.StringSlice("instance-type"),
+			ProductDescriptions: ctx.StringSlice("product-description"),
+			AvailabilityZones:   ctx.StringSlice("availability-zone"),
+		}
+
+		return fetchSpot(ctx.String("profile"), ctx.String("region"), getStoreURI(ctx), ctx.StringSlice("pricing-region"), input, ctx.Int("days"))
+	},
+}
+
+func fetchSpot(profile, region, storeUri string, pricingRegions []string, input *aws.SpotPriceHistoryInput, days int) error {
+	cfg, err := aws.Config(profile, region)
+	if err != nil {
+		return fmt.Errorf("Fetch: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	if len(pricingRegions) == 0 || contains(pricingRegions, aws.AllRegions) {
+		pricingRegions, err = aws.SpotRegions(cfg)
+		if err != nil {
+			return err
+		}
+	}
+
+	st, err := store.Open(storeUri)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	if err := st.CreateTimeSeries(ctx, spotCollection, "timestamp", "meta"); err != nil {
+		return fmt.Errorf("Failed to create %s collection: %w", spotCollection, err)
+	}
+
+	if err := st.CreateIndexes(ctx, spotCollection, spotIndexes); err != nil {
+		return fmt.Errorf("Failed to create indexes of %s collection: %w", spotCollection, err)
+	}
+
+	for _, r := range pricingRegions {
+		in := *input
+		in.StartTime, err = getSpotStartTime(st, ctx, r, input, days, time.Now())
+		if err != nil {
+			return err
+		}
+
+		if err := saveSpotPriceHistory(st, ctx, aws.NewSpotClient(cfg, r), r, &in); err != nil {
+			return err
+		}
+	}
+
+	log.Println("Completed saving Spot price history to the store")
+
+	return nil
+}
+
+// spotFetch is the filters of a fetch of the Spot price history of a region.
+type spotFetch struct {
+	Region              string
+	InstanceTypes       []string
+	ProductDescriptions []string
+	AvailabilityZones   []string
+	FetchedAt           time.Time
+}
+
+func (f *spotFetch) sameFilters(input *aws.SpotPriceHistoryInput) bool {
+	return sameStrings(f.InstanceTypes, input.InstanceTypes) &&
+		sameStrings(f.ProductDescriptions, input.ProductDescriptions) &&
+		sameStrings(f.AvailabilityZones, input.AvailabilityZones)
+}
+
+// sameStrings reports whether the slices have the same strings in any order.
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	x, y := append([]string{}, a...), append([]string{}, b...)
+	sort.Strings(x)
+	sort.Strings(y)
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// getSpotStartTime returns the start time of the Spot price history of the region.
+// The history resumes from the latest stored price only if a previous fetch of the region had the same filters,
+// since the series of the other filters are not stored up to the time. Otherwise it starts the days before now.
+func getSpotStartTime(st store.Store, ctx context.Context, region string, input *aws.SpotPriceHistoryInput, days int, now time.Time) (time.Time, error) {
+	start := now.AddDate(0, 0, -days)
+
+	results, err := st.Find(ctx, spotFetchCollection, bson.M{"region": region}, nil)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Failed to find Spot fetches of %s: %w", region, err)
+	}
+
+	resumable := false
+	for _, result := range results {
+		var f spotFetch
+		b, err := bson.Marshal(result)
+		if err == nil {
+			err = bson.Unmarshal(b, &f)
+		}
+		if err != nil {
+			return time.Time{}, fmt.Errorf("Invalid Spot fetch of %s: %w", region, err)
+		}
+
+		if f.sameFilters(input) {
+			resumable = true
+			break
+		}
+	}
+
+	if !resumable {
+		return start, nil
+	}
+
+	latest, err := getLatestSpotTime(st, ctx, spotSeriesFilter(region, input))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if latest.IsZero() {
+		return start, nil
+	}
+
+	return latest, nil
+}
+
+// spotSeriesFilter returns the filter of the Spot prices of the region fetched with the input.
+func spotSeriesFilter(region string, input *aws.SpotPriceHistoryInput) bson.M {
+	filter := bson.M{"meta.region": region}
+
+	if len(input.InstanceTypes) > 0 {
+		filter["meta.instancetype"] = bson.M{"$in": input.InstanceTypes}
+	}
+
+	if len(input.ProductDescriptions) > 0 {
+		filter["meta.productdescription"] = bson.M{"$in": input.ProductDescriptions}
+	}
+
+	if len(input.AvailabilityZones) > 0 {
+		filter["meta.availabilityzone"] = bson.M{"$in": input.AvailabilityZones}
+	}
+
+	return filter
+}
+
+// spotRecordKey identifies a Spot price by the series and the time.
+func spotRecordKey(meta aws.SpotMeta, t time.Time) string {
+	return strings.Join([]string{meta.Region, meta.AvailabilityZone, meta.InstanceType, meta.ProductDescription, strconv.FormatInt(t.UnixMilli(), 10)}, "/")
+}
+
+// saveSpotPriceHistory inserts the Spot price history of the region, and records the filters of the fetch.
+// The history has the price effective at the start time, which may be already stored, so the stored prices are skipped.
+func saveSpotPriceHistory(st store.Store, ctx context.Context, client aws.SpotPriceHistoryAPIClient, region string, input *aws.SpotPriceHistoryInput) error {
+	var inserted int
+
+	if err := aws.GetSpotPriceHistory(ctx, client, region, input, func(prices []*aws.SpotPrice) error {
+		if len(prices) == 0 {
+			return nil
+		}
+
+		timestamps := make([]interface{}, 0, len(prices))
+		for _, p := range prices {
+			timestamps = append(timestamps, p.Timestamp)
+		}
+
+		filter := spotSeriesFilter(region, input)
+		filter["timestamp"] = bson.M{"$in": timestamps}
+
+		stored, err := st.Find(ctx, spotCollection, filter, nil)
+		if err != nil {
+			return fmt.Errorf("Failed to find stored Spot prices of %s: %w", region, err)
+		}
+
+		exists := map[string]bool{}
+		for _, result := range stored {
+			p, err := decodeSpotPrice(result)
+			if err != nil {
+				continue
+			}
+			exists[spotRecordKey(p.Meta, p.Timestamp)] = true
+		}
+
+		var docs []interface{}
+		for _, p := range prices {
+			key := spotRecordKey(p.Meta, p.Timestamp)
+			if exists[key] {
+				continue
+			}
+			exists[key] = true
+			docs = append(docs, p)
+		}
+
+		if len(docs) == 0 {
+			return nil
+		}
+
+		if err := st.Insert(ctx, spotCollection, docs); err != nil {
+			return fmt.Errorf("Failed to insert Spot prices of %s: %w", region, err)
+		}
+		inserted += len(docs)
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf("Failed to fetch Spot prices of %s: %w", region, err)
+	}
+
+	if err := st.Insert(ctx, spotFetchCollection, []interface{}{&spotFetch{
+		Region:              region,
+		InstanceTypes:       input.InstanceTypes,
+		ProductDescriptions: input.ProductDescriptions,
+		AvailabilityZones:   input.AvailabilityZones,
+		FetchedAt:           time.Now().UTC(),
+	}}); err != nil {
+		return fmt.Errorf("Failed to record Spot fetch of %s: %w", region, err)
+	}
+
+	log.Printf("Inserted %d Spot prices of %s\n", inserted, region)
+
+	return nil
+}
+
+// getLatestSpotTime returns the time of the latest Spot price of the filter, or the zero time if it has none.
+func getLatestSpotTime(st store.Store, ctx context.Context, filter bson.M) (time.Time, error) {
+	results, err := st.Find(ctx, spotCollection, filter, &store.FindOptions{
+		Sort:  []store.SortKey{{Key: "timestamp", Descending: true}},
+		Limit: 1,
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Failed to find the latest Spot price of %s: %w", filter["meta.region"], err)
+	}
+
+	if len(results) == 0 {
+		return time.Time{}, nil
+	}
+
+	return spotTime(results[0]["timestamp"]), nil
+}
+
+func spotTime(v interface{}) time.Time {
+	switch t := v.(type) {
+	case primitive.DateTime:
+		return t.Time().UTC()
+	case time.Time:
+		return t.UTC()
+	default:
+		return time.Time{}
+	}
+}
+
+// spotStats is the Spot prices of an instance type in a region during the period.
+type spotStats struct {
+	AvailabilityZone string  `json:"availabilityZone" yaml:"availabilityZone"`
+	Current          float64 `json:"current" yaml:"current"`
+	Average          float64 `json:"average" yaml:"average"`
+	Max              float64 `json:"max" yaml:"max"`
+
+	records map[string][]spotRecord
+}
+
+type spotRecord struct {
+	timestamp time.Time
+	price     float64
+}
+
+func (s *spotStats) add(az string, t time.Time, price float64) {
+	s.records[az] = append(s.records[az], spotRecord{timestamp: t, price: price})
+}
+
+// summarize sets the current price to the cheapest of the latest prices of the Availability Zones,
+// and the average and the max to those of the prices effective from start to end.
+// A price is effective from its timestamp (or start) until the next price of the Availability Zone (or end),
+// so the average is weighted by the time.
+func (s *spotStats) summarize(start, end time.Time) {
+	s.AvailabilityZone, s.Current, s.Average, s.Max = "", 0, 0, 0
+
+	var azs []string
+	for az := range s.records {
+		azs = append(azs, az)
+	}
+	sort.Strings(azs)
+
+	var weighted, total float64
+	for _, az := range azs {
+		records := s.records[az]
+		sort.SliceStable(records, func(i, j int) bool {
+			return records[i].timestamp.Before(records[j].timestamp)
+		})
+
+		for i, r := range records {
+			from, to := r.timestamp, end
+			if from.Before(start) {
+				from = start
+			}
+			if i+1 < len(records) {
+				to = records[i+1].timestamp
+			}
+
+			// A price replaced before the start (or at the same time) is not effective, except the current one.
+			if to.After(from) {
+				d := to.Sub(from).Seconds()
+				weighted += r.price * d
+				total += d
+			} else if i+1 < len(records) {
+				continue
+			}
+
+			if r.price > s.Max {
+				s.Max = r.price
+			}
+		}
+
+		current := records[len(records)-1]
+		if s.AvailabilityZone == "" || current.price < s.Current {
+			s.AvailabilityZone = az
+			s.Current = current.price
+		}
+	}
+
+	if total > 0 {
+		s.Average = weighted / total
+	} else {
+		s.Average = s.Current
+	}
+}
+
+func spotKey(region, instanceType string) string {
+	return region + "/" + instanceType
+}
+
+// spotInstance is an instance type of a region to find the Spot prices of.
+type spotInstance struct {
+	region       string
+	instanceType string
+}
+
+// findSpotStats returns the Spot prices of the days up to the as-of date (or now), keyed by spotKey.
+// The latest price before the days is also found for each Availability Zone of the instances, because it is effective at the start.
+func findSpotStats(storeUri string, cond *condition, productDescription string, days int, instances []spotInstance) (map[string]*spotStats, error) {
+	end := time.Now()
+	if !cond.asOf.IsZero() {
+		end = cond.asOf
+	}
+	start := end.AddDate(0, 0, -days)
+
+	window := bson.M{
+		"meta.productdescription": productDescription,
+		"timestamp": bson.M{
+			"$gte": start.UTC(),
+			"$lte": end.UTC(),
+		},
+	}
+
+	if cond.region != "" {
+		window["meta.region"] = cond.region
+	}
+
+	if cond.instanceType != "" {
+		window["meta.instancetype"] = cond.instanceType
+	}
+
+	st, err := store.Open(storeUri)
+	if err != nil {
+		return nil, err
+	}
+	defer st.Close()
+
+	ctx := context.Background()
+
+	results, err := st.Find(ctx, spotCollection, window, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to find Spot prices: %w", err)
+	}
+
+	stats := map[string]*spotStats{}
+	add := func(p *aws.SpotPrice) {
+		key := spotKey(p.Meta.Region, p.Meta.InstanceType)
+		s, ok := stats[key]
+		if !ok {
+			s = &spotStats{records: map[string][]spotRecord{}}
+			stats[key] = s
+		}
+
+		s.add(p.Meta.AvailabilityZone, p.Timestamp, p.PriceUSD)
+	}
+
+	for _, result := range results {
+		p, err := decodeSpotPrice(result)
+		if err != nil {
+			log.Printf("Skip Spot price %v: %v", result["_id"], err)
+			continue
+		}
+		add(p)
+	}
+
+	// The latest price before the days is found one by one excluding the Availability Zones already found,
+	// so only a price per Availability Zone is read instead of the whole history.
+	for _, in := range instances {
+		var azs []string
+		for {
+			before := bson.M{
+				"meta.productdescription": productDescription,
+				"meta.region":             in.region,
+				"meta.instancetype":       in.instanceType,
+				"timestamp":               bson.M{"$lt": start.UTC()},
+			}
+			if len(azs) > 0 {
+				before["meta.availabilityzone"] = bson.M{"$nin": azs}
+			}
+
+			previous, err := st.Find(ctx, spotCollection, before, &store.FindOptions{
+				Sort:  []store.SortKey{{Key: "timestamp", Descending: true}},
+				Limit: 1,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("Failed to find Spot prices before %s: %w", start.UTC().Format(time.RFC3339), err)
+			}
+			if len(previous) == 0 {
+				break
+			}
+
+			p, err := decodeSpotPrice(previous[0])
+			if err != nil {
+				return nil, fmt.Errorf("Invalid Spot price %v: %w", previous[0]["_id"], err)
+			}
+			azs = append(azs, p.Meta.AvailabilityZone)
+			add(p)
+		}
+	}
+
+	for _, s := range stats {
+		s.summarize(start, end)
+	}
+
+	return stats, nil
+}
+
+func decodeSpotPrice(result bson.M) (*aws.SpotPrice, error) {
+	var p aws.SpotPrice
+	b, err := bson.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	if err := bson.Unmarshal(b, &p); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
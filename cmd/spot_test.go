@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"context"
+	"math"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/sfuruya0612/apf/internal/aws"
+	"github.com/sfuruya0612/apf/internal/store"
+)
+
+// stubSpotClient returns the Spot price history of the instance types after the start time,
+// and the price of each Availability Zone effective at the start time.
+// A page has 2 records at most.
+type stubSpotClient struct {
+	history    []types.SpotPrice
+	startTimes []time.Time
+}
+
+func (c *stubSpotClient) DescribeSpotPriceHistory(ctx context.Context, params *ec2.DescribeSpotPriceHistoryInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSpotPriceHistoryOutput, error) {
+	start := awssdk.ToTime(params.StartTime)
+	if params.NextToken == nil {
+		c.startTimes = append(c.startTimes, start)
+	}
+
+	effective := map[string]types.SpotPrice{}
+	var prices []types.SpotPrice
+	for _, h := range c.history {
+		if len(params.InstanceTypes) > 0 && !contains(instanceTypeStrings(params.InstanceTypes), string(h.InstanceType)) {
+			continue
+		}
+
+		t := awssdk.ToTime(h.Timestamp)
+		if t.After(start) {
+			prices = append(prices, h)
+			continue
+		}
+
+		series := awssdk.ToString(h.AvailabilityZone) + "/" + string(h.InstanceType)
+		if e, ok := effective[series]; !ok || t.After(awssdk.ToTime(e.Timestamp)) {
+			effective[series] = h
+		}
+	}
+	for _, h := range effective {
+		prices = append(prices, h)
+	}
+
+	var page int
+	if params.NextToken != nil {
+		page, _ = strconv.Atoi(awssdk.ToString(params.NextToken))
+	}
+
+	output := &ec2.DescribeSpotPriceHistoryOutput{}
+	for i := page * 2; i < len(prices) && i < page*2+2; i++ {
+		output.SpotPriceHistory = append(output.SpotPriceHistory, prices[i])
+	}
+	if (page+1)*2 < len(prices) {
+		output.NextToken = awssdk.String(strconv.Itoa(page + 1))
+	}
+
+	return output, nil
+}
+
+func instanceTypeStrings(types []types.InstanceType) []string {
+	s := make([]string, 0, len(types))
+	for _, t := range types {
+		s = append(s, string(t))
+	}
+	return s
+}
+
+func spotHistory(az, instanceType, timestamp, price string) types.SpotPrice {
+	t, _ := time.Parse(time.RFC3339, timestamp)
+
+	return types.SpotPrice{
+		AvailabilityZone:   awssdk.String(az),
+		InstanceType:       types.InstanceType(instanceType),
+		ProductDescription: types.RIProductDescriptionLinuxUnix,
+		SpotPrice:          awssdk.String(price),
+		Timestamp:          awssdk.Time(t),
+	}
+}
+
+// fetchTestSpot saves the history of the stub from the start time of fetchSpot.
+func fetchTestSpot(t *testing.T, st store.Store, client *stubSpotClient, instanceTypes ...string) {
+	t.Helper()
+
+	ctx := context.Background()
+	in := &aws.SpotPriceHistoryInput{InstanceTypes: instanceTypes}
+
+	var err error
+	in.StartTime, err = getSpotStartTime(st, ctx, "us-east-1", in, 30, time.Date(2023, 5, 31, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := saveSpotPriceHistory(st, ctx, client, "us-east-1", in); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSaveSpotPriceHistory(t *testing.T) {
+	st := openTestStore(t)
+
+	client := &stubSpotClient{history: []types.SpotPrice{
+		spotHistory("us-east-1a", "m5.large", "2023-05-20T00:00:00Z", "0.04"),
+		spotHistory("us-east-1c", "m5.large", "2023-05-25T00:00:00Z", "0.03"),
+		spotHistory("us-east-1a", "c5.large", "2023-05-15T00:00:00Z", "0.035"),
+	}}
+
+	fetchTestSpot(t, st, client, "m5.large")
+
+	client.history = append(client.history, spotHistory("us-east-1a", "m5.large", "2023-06-04T00:00:00Z", "0.05"))
+	fetchTestSpot(t, st, client, "m5.large")
+
+	// c5.large has not been fetched, so it is fetched from the days before even though m5.large is stored up to 2023-06-04.
+	fetchTestSpot(t, st, client, "c5.large")
+
+	wantStartTimes := []time.Time{
+		time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC),
+		// The fetch of the same filters resumes from the latest stored.
+		time.Date(2023, 5, 25, 0, 0, 0, 0, time.UTC),
+		time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if len(client.startTimes) != len(wantStartTimes) {
+		t.Fatalf("got start times %v, want %v", client.startTimes, wantStartTimes)
+	}
+	for i, want := range wantStartTimes {
+		if !client.startTimes[i].Equal(want) {
+			t.Errorf("got start time %d %v, want %v", i, client.startTimes[i], want)
+		}
+	}
+
+	// The prices effective at the start of the second fetch are not inserted again.
+	docs, err := st.Find(context.Background(), spotCollection, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 4 {
+		t.Errorf("got %d Spot prices, want 4", len(docs))
+	}
+}
+
+func TestSpotStats(t *testing.T) {
+	storeUri := "sqlite://" + filepath.Join(t.TempDir(), "apf.db")
+
+	st, err := store.Open(storeUri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	client := &stubSpotClient{history: []types.SpotPrice{
+		// Replaced before the window, so it is not the max.
+		spotHistory("us-east-1a", "m5.large", "2023-05-10T00:00:00Z", "0.1"),
+		spotHistory("us-east-1a", "m5.large", "2023-05-20T00:00:00Z", "0.04"),
+		spotHistory("us-east-1c", "m5.large", "2023-05-25T00:00:00Z", "0.03"),
+		spotHistory("us-east-1a", "m5.large", "2023-06-04T00:00:00Z", "0.05"),
+	}}
+	fetchTestSpot(t, st, client)
+
+	cond := &condition{region: "us-east-1", asOf: time.Date(2023, 6, 8, 0, 0, 0, 0, time.UTC)}
+	stats, err := findSpotStats(storeUri, cond, "Linux/UNIX", 7, []spotInstance{{region: "us-east-1", instanceType: "m5.large"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, ok := stats[spotKey("us-east-1", "m5.large")]
+	if !ok {
+		t.Fatalf("got no stats of m5.large: %v", stats)
+	}
+
+	// us-east-1c has no price in the window, but the price before the window is current.
+	if s.AvailabilityZone != "us-east-1c" || s.Current != 0.03 {
+		t.Errorf("got current %s %g, want us-east-1c 0.03", s.AvailabilityZone, s.Current)
+	}
+
+	// us-east-1a is 0.04 for 3 days and 0.05 for 4 days, and us-east-1c is 0.03 for 7 days.
+	if want := (0.04*3 + 0.05*4 + 0.03*7) / 14; math.Abs(s.Average-want) > 1e-9 {
+		t.Errorf("got average %g, want %g", s.Average, want)
+	}
+
+	if s.Max != 0.05 {
+		t.Errorf("got max %g, want 0.05", s.Max)
+	}
+}
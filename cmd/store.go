@@ -0,0 +1,12 @@
+package cmd
+
+import "github.com/urfave/cli/v2"
+
+// getStoreURI returns the URI of --store, or --mongo-uri if it is not specified.
+func getStoreURI(ctx *cli.Context) string {
+	if uri := ctx.String("store"); uri != "" {
+		return uri
+	}
+
+	return ctx.String("mongo-uri")
+}
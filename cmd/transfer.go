@@ -0,0 +1,294 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sfuruya0612/apf/internal/aws"
+	"github.com/urfave/cli/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var transferCommand = &cli.Command{
+	Name:    "transfer",
+	Aliases: []string{"dt"},
+	Usage:   "Get data transfer pricing and estimate the monthly cost",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "from",
+			Usage: "Specify a valid region code of the source. Overrides --region",
+		},
+		&cli.StringFlag{
+			Name:  "to",
+			Usage: "Specify a valid region code of the destination, or internet. The source region is the transfer between AZs",
+		},
+		&cli.Float64Flag{
+			Name:  "gb",
+			Usage: "Specify the transferred data in GB per month",
+		},
+		&cli.BoolFlag{
+			Name:  "matrix",
+			Usage: "Print the per-GB prices (or the monthly cost of --gb) as a table of the sources by the destinations",
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		return getTransferPrice(ctx)
+	},
+}
+
+// transferRow is the per-GB price paid in USD, and the monthly cost of the transferred data.
+type transferRow struct {
+	TransferType string  `json:"transferType" yaml:"transferType"`
+	From         string  `json:"from" yaml:"from"`
+	To           string  `json:"to" yaml:"to"`
+	PricePerGB   float64 `json:"pricePerGB" yaml:"pricePerGB"`
+	GB           float64 `json:"gb" yaml:"gb"`
+	Monthly      float64 `json:"monthly" yaml:"monthly"`
+}
+
+// transferMatrixRow is the prices of a source keyed by the destination.
+type transferMatrixRow struct {
+	From   string             `json:"from" yaml:"from"`
+	Prices map[string]float64 `json:"prices" yaml:"prices"`
+}
+
+func getTransferPrice(ctx *cli.Context) error {
+	if ctx.String("term") != "ondemand" {
+		return fmt.Errorf("Unsupported term for transfer: %s", ctx.String("term"))
+	}
+
+	out, err := newOutput(ctx)
+	if err != nil {
+		return err
+	}
+
+	cond, err := getCondition(ctx)
+	if err != nil {
+		return err
+	}
+
+	if from := ctx.String("from"); from != "" {
+		cond.region = from
+	}
+
+	gb := ctx.Float64("gb")
+	if gb < 0 {
+		return fmt.Errorf("Invalid transferred data: %g GB", gb)
+	}
+
+	filter := bson.M{}
+	if to := ctx.String("to"); to != "" {
+		if strings.EqualFold(to, aws.TransferInternet) {
+			to = aws.TransferInternet
+		}
+		filter["product.attributes.destination"] = to
+	}
+
+	matrix := ctx.Bool("matrix")
+
+	// The price of a row depends on the tiers and --gb, and a row of the matrix is made of several products,
+	// so the products are not sorted nor limited by the store.
+	out.order, err = getRowOrder("transfer", cond)
+	if err != nil {
+		return err
+	}
+
+	if matrix && out.order.byPrice {
+		return fmt.Errorf("Unsupported sort key for transfer --matrix: price (the sources are sorted by region)")
+	}
+
+	results, err := findProducts(
+		getStoreURI(ctx),
+		"transfer",
+		cond,
+		filter,
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to find: %w", err)
+	}
+
+	if matrix {
+		return printTransferMatrix(results, gb, out)
+	}
+
+	return printTransfer(results, gb, out)
+}
+
+func printTransfer(results []bson.M, gb float64, out *output) error {
+	out.header = getTransferHeader()
+
+	for _, result := range results {
+		price, err := decodePrice(result)
+		if err != nil {
+			log.Printf("Skip product %v: %v", result["sku"], err)
+			continue
+		}
+
+		row, err := newTransferRow(price, gb)
+		if err != nil {
+			log.Printf("Skip product %s: %v", price.Sku, err)
+			continue
+		}
+
+		out.add(formatTransfer(row), result, row)
+	}
+
+	return out.render(os.Stdout)
+}
+
+func newTransferRow(price *aws.Price, gb float64) (*transferRow, error) {
+	if len(price.OnDemandTiers) == 0 {
+		return nil, fmt.Errorf("OnDemand price in USD is missing")
+	}
+
+	attr := price.Product.Attributes
+
+	return &transferRow{
+		TransferType: attr.TransferType,
+		From:         price.Region,
+		To:           attr.Destination,
+		PricePerGB:   transferPricePerGB(price.OnDemandTiers, gb),
+		GB:           gb,
+		// Internet transfer is charged by the tiers (e.g. first 10 TB, next 40 TB, next 100 TB, over 150 TB).
+		Monthly: aws.TieredCostUSD(price.OnDemandTiers, gb),
+	}, nil
+}
+
+// transferPricePerGB returns the per-GB price of the tier of the last GB of the transferred data.
+// Without the transferred data, it is the price of the first paid tier, since the first tier can be free (e.g. the first GB to the internet).
+func transferPricePerGB(tiers []aws.PriceTier, gb float64) float64 {
+	if gb > 0 {
+		for _, t := range tiers {
+			if gb > t.BeginRange && (t.EndRange == 0 || gb <= t.EndRange) {
+				return t.PricePerUnitUSD
+			}
+		}
+	}
+
+	for _, t := range tiers {
+		if t.PricePerUnitUSD > 0 {
+			return t.PricePerUnitUSD
+		}
+	}
+
+	return tiers[0].PricePerUnitUSD
+}
+
+// sortPrice returns the monthly cost if the transferred data is given, or the per-GB price.
+func (r *transferRow) sortPrice() float64 {
+	if r.GB > 0 {
+		return r.Monthly
+	}
+
+	return r.PricePerGB
+}
+
+func printTransferMatrix(results []bson.M, gb float64, out *output) error {
+	addTransferMatrix(results, gb, out)
+
+	return out.render(os.Stdout)
+}
+
+// addTransferMatrix adds the sources as the rows and the destinations as the columns.
+// The internet is the first column, and the regions follow in order.
+func addTransferMatrix(results []bson.M, gb float64, out *output) {
+	rows := map[string]*transferMatrixRow{}
+	destinations := map[string]bool{}
+
+	for _, result := range results {
+		price, err := decodePrice(result)
+		if err != nil {
+			log.Printf("Skip product %v: %v", result["sku"], err)
+			continue
+		}
+
+		r, err := newTransferRow(price, gb)
+		if err != nil {
+			log.Printf("Skip product %s: %v", price.Sku, err)
+			continue
+		}
+
+		row, ok := rows[r.From]
+		if !ok {
+			row = &transferMatrixRow{From: r.From, Prices: map[string]float64{}}
+			rows[r.From] = row
+		}
+
+		if gb > 0 {
+			row.Prices[r.To] = r.Monthly
+		} else {
+			row.Prices[r.To] = r.PricePerGB
+		}
+		destinations[r.To] = true
+	}
+
+	var columns []string
+	for d := range destinations {
+		if d != aws.TransferInternet {
+			columns = append(columns, d)
+		}
+	}
+	sort.Strings(columns)
+	if destinations[aws.TransferInternet] {
+		columns = append([]string{aws.TransferInternet}, columns...)
+	}
+
+	var sources []string
+	for from := range rows {
+		sources = append(sources, from)
+	}
+	sort.Strings(sources)
+
+	unit := "USD/GB"
+	if gb > 0 {
+		unit = "USD/month"
+	}
+	out.header = append([]string{"From\\To(" + unit + ")"}, columns...)
+
+	for _, from := range sources {
+		row := rows[from]
+
+		fields := []string{from}
+		for _, to := range columns {
+			p, ok := row.Prices[to]
+			if !ok {
+				fields = append(fields, na(""))
+				continue
+			}
+
+			if gb > 0 {
+				fields = append(fields, fmt.Sprintf("%.2f", p))
+			} else {
+				fields = append(fields, formatPrice(p))
+			}
+		}
+
+		out.add(fields, bson.M{}, row)
+	}
+}
+
+func getTransferHeader() []string {
+	return []string{
+		"From",
+		"To",
+		"TransferType",
+		"Price(USD/GB)",
+		"Transfer(GB)",
+		"Total(USD/month)",
+	}
+}
+
+func formatTransfer(row *transferRow) []string {
+	return []string{
+		na(row.From),
+		na(row.To),
+		na(row.TransferType),
+		formatPrice(row.PricePerGB),
+		strconv.FormatFloat(row.GB, 'f', -1, 64),
+		fmt.Sprintf("%.2f", row.Monthly),
+	}
+}
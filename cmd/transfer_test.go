@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/sfuruya0612/apf/internal/aws"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// internetTiers are the tiers of the transfer out to the internet from ap-northeast-1, whose first GB is free.
+var internetTiers = []aws.PriceTier{
+	{BeginRange: 0, EndRange: 1, Unit: "GB", PricePerUnitUSD: 0},
+	{BeginRange: 1, EndRange: 10240, Unit: "GB", PricePerUnitUSD: 0.114},
+	{BeginRange: 10240, EndRange: 51200, Unit: "GB", PricePerUnitUSD: 0.089},
+	{BeginRange: 51200, EndRange: 153600, Unit: "GB", PricePerUnitUSD: 0.086},
+	{BeginRange: 153600, EndRange: 0, Unit: "GB", PricePerUnitUSD: 0.084},
+}
+
+func transferPrice(sku, from, to string, tiers []aws.PriceTier) *aws.Price {
+	p := &aws.Price{Sku: sku, Region: from, OnDemandTiers: tiers}
+	p.Product.Attributes.Destination = to
+	if to == aws.TransferInternet {
+		p.Product.Attributes.TransferType = "AWS Outbound"
+	} else {
+		p.Product.Attributes.TransferType = "InterRegion Outbound"
+	}
+
+	return p
+}
+
+func TestNewTransferRow(t *testing.T) {
+	tests := []struct {
+		name       string
+		gb         float64
+		pricePerGB float64
+		monthly    float64
+	}{
+		// The first paid tier, not the free first GB.
+		{"no usage", 0, 0.114, 0},
+		{"first GB", 1, 0, 0},
+		{"first paid tier", 101, 0.114, 11.4},
+		// 1 GB free, 10239 GB at 0.114 and 9760 GB at 0.089
+		{"second paid tier", 20000, 0.089, 1167.246 + 868.64},
+		{"last tier", 200000, 0.084, 1167.246 + 40960*0.089 + 102400*0.086 + 46400*0.084},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			row, err := newTransferRow(transferPrice("A", "ap-northeast-1", aws.TransferInternet, internetTiers), tt.gb)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if row.PricePerGB != tt.pricePerGB {
+				t.Errorf("got %v/GB, want %v/GB", row.PricePerGB, tt.pricePerGB)
+			}
+			if math.Abs(row.Monthly-tt.monthly) > 1e-6 {
+				t.Errorf("got %v/month, want %v/month", row.Monthly, tt.monthly)
+			}
+		})
+	}
+
+	if _, err := newTransferRow(transferPrice("A", "ap-northeast-1", aws.TransferInternet, nil), 0); err == nil {
+		t.Error("got no error without tiers")
+	}
+}
+
+func TestAddTransferMatrix(t *testing.T) {
+	flat := func(price float64) []aws.PriceTier {
+		return []aws.PriceTier{{Unit: "GB", PricePerUnitUSD: price}}
+	}
+
+	results := []bson.M{
+		document(t, transferPrice("A", "us-east-1", "ap-northeast-1", flat(0.02))),
+		document(t, transferPrice("B", "ap-northeast-1", aws.TransferInternet, internetTiers)),
+		document(t, transferPrice("C", "ap-northeast-1", "us-east-1", flat(0.09))),
+		document(t, transferPrice("D", "ap-northeast-1", "ap-northeast-1", flat(0.01))),
+		document(t, transferPrice("E", "us-east-1", aws.TransferInternet, flat(0.09))),
+	}
+
+	tests := []struct {
+		name   string
+		gb     float64
+		header []string
+		rows   [][]string
+	}{
+		{
+			"per-GB prices", 0,
+			[]string{"From\\To(USD/GB)", "internet", "ap-northeast-1", "us-east-1"},
+			[][]string{
+				{"ap-northeast-1", "0.114", "0.01", "0.09"},
+				{"us-east-1", "0.09", "0.02", "N/A"},
+			},
+		},
+		{
+			"monthly cost", 101,
+			[]string{"From\\To(USD/month)", "internet", "ap-northeast-1", "us-east-1"},
+			[][]string{
+				{"ap-northeast-1", "11.40", "1.01", "9.09"},
+				{"us-east-1", "9.09", "2.02", "N/A"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &output{format: "table"}
+			addTransferMatrix(results, tt.gb, out)
+
+			if !reflect.DeepEqual(out.header, tt.header) {
+				t.Errorf("got header %v, want %v", out.header, tt.header)
+			}
+			if !reflect.DeepEqual(out.rows, tt.rows) {
+				t.Errorf("got rows %v, want %v", out.rows, tt.rows)
+			}
+		})
+	}
+}
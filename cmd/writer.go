@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/sfuruya0612/apf/internal/aws"
+	"github.com/sfuruya0612/apf/internal/store"
+)
+
+// priceIndexes are the keys used by the price subcommands to find products.
+var priceIndexes = []string{"region", "product.attributes.instancetype"}
+
+// savingsPlanIndexes are the keys used to join the Savings Plans rates to the products.
+var savingsPlanIndexes = []string{"region", "usagetype"}
+
+// getIndexes returns the keys indexed in the live collection.
+func getIndexes(collection string) []string {
+	if collection == getCollectionName(aws.SavingsPlansServiceCode) {
+		return savingsPlanIndexes
+	}
+
+	return priceIndexes
+}
+
+// priceWriter buffers prices and inserts them into a snapshot collection of the service in batches.
+// The live collection is not touched until Commit, so a failed run leaves the previous data as it is.
+type priceWriter struct {
+	serviceCode     string
+	store           store.Store
+	collection      string
+	batchSize       int
+	buf             []interface{}
+	inserted        int
+	publicationDate string
+	start           time.Time
+}
+
+func newPriceWriter(st store.Store, serviceCode string, batchSize int) (*priceWriter, error) {
+	if batchSize <= 0 {
+		return nil, fmt.Errorf("Invalid batch size: %d", batchSize)
+	}
+
+	start := time.Now()
+	name := getSnapshotName(getCollectionName(serviceCode), start)
+
+	log.Printf("Inserting %s products into %s collection\n", serviceCode, name)
+
+	return &priceWriter{
+		serviceCode: serviceCode,
+		store:       st,
+		collection:  name,
+		batchSize:   batchSize,
+		buf:         make([]interface{}, 0, batchSize),
+		start:       start,
+	}, nil
+}
+
+func (w *priceWriter) Write(ctx context.Context, prices []*aws.Price) error {
+	for _, p := range prices {
+		if err := w.add(ctx, p, p.PublicationDate); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteRates writes the Savings Plans rates, which have no publication date.
+func (w *priceWriter) WriteRates(ctx context.Context, rates []*aws.SavingsPlanRate) error {
+	for _, r := range rates {
+		if err := w.add(ctx, r, ""); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *priceWriter) add(ctx context.Context, doc interface{}, publicationDate string) error {
+	w.buf = append(w.buf, doc)
+
+	if publicationDate > w.publicationDate {
+		w.publicationDate = publicationDate
+	}
+
+	if len(w.buf) >= w.batchSize {
+		return w.Flush(ctx)
+	}
+
+	return nil
+}
+
+func (w *priceWriter) Flush(ctx context.Context) error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+
+	if err := w.store.Insert(ctx, w.collection, w.buf); err != nil {
+		return fmt.Errorf("Failed to insert %s products: %w", w.serviceCode, err)
+	}
+
+	w.inserted += len(w.buf)
+	w.buf = w.buf[:0]
+
+	return nil
+}
+
+// Commit flushes the buffered prices, verifies the snapshot collection, and swaps it with the live collection.
+// Snapshots older than the last keep ones are removed.
+func (w *priceWriter) Commit(ctx context.Context, keep int) error {
+	if err := w.Flush(ctx); err != nil {
+		return err
+	}
+
+	elapsed := time.Since(w.start)
+	log.Printf("Inserted %d %s products in %s (%.1f products/sec)\n",
+		w.inserted, w.serviceCode, elapsed.Round(time.Millisecond), float64(w.inserted)/elapsed.Seconds())
+
+	collection := getCollectionName(w.serviceCode)
+
+	if err := verifyCollection(w.store, ctx, w.collection, int64(w.inserted), getIndexes(collection)); err != nil {
+		return fmt.Errorf("Failed to verify %s collection: %w", w.collection, err)
+	}
+
+	if err := swapCollection(w.store, ctx, w.collection, collection); err != nil {
+		return err
+	}
+
+	if err := recordSnapshot(w.store, ctx, &snapshot{
+		ID:              strings.TrimPrefix(w.collection, collection+"_"),
+		Collection:      collection,
+		ServiceCode:     w.serviceCode,
+		FetchedAt:       w.start.UTC(),
+		PublicationDate: w.publicationDate,
+		Count:           int64(w.inserted),
+	}); err != nil {
+		return err
+	}
+
+	// The live collection is already swapped, so failing to remove old snapshots is not fatal.
+	if err := pruneSnapshots(w.store, ctx, collection, keep); err != nil {
+		log.Printf("Failed to prune %s snapshots: %v\n", collection, err)
+	}
+
+	return nil
+}
+
+// Abort removes the snapshot collection.
+func (w *priceWriter) Abort(ctx context.Context) {
+	log.Printf("Drop %s collection\n", w.collection)
+
+	if err := w.store.Drop(ctx, w.collection); err != nil {
+		log.Printf("Failed to remove %s collection: %v\n", w.collection, err)
+	}
+}
+
+// verifyCollection creates the indexes, and checks that the collection has them and the expected number of documents.
+func verifyCollection(st store.Store, ctx context.Context, collection string, want int64, indexes []string) error {
+	if want == 0 {
+		return fmt.Errorf("No products")
+	}
+
+	count, err := st.Count(ctx, collection)
+	if err != nil {
+		return err
+	}
+
+	if count != want {
+		return fmt.Errorf("%d documents, want %d", count, want)
+	}
+
+	if err := st.CreateIndexes(ctx, collection, indexes); err != nil {
+		return fmt.Errorf("Failed to create indexes: %w", err)
+	}
+
+	keys, err := st.IndexKeys(ctx, collection)
+	if err != nil {
+		return fmt.Errorf("Failed to list indexes: %w", err)
+	}
+
+	for _, index := range indexes {
+		if !contains(keys, index) {
+			return fmt.Errorf("Index %s is missing", index)
+		}
+	}
+
+	return nil
+}
+
+// swapCollection atomically replaces the live collection with the documents of the snapshot collection,
+// and records the snapshot as the live one.
+func swapCollection(st store.Store, ctx context.Context, snapshot, collection string) error {
+	log.Printf("Swap %s collection with %s\n", collection, snapshot)
+
+	if err := st.Replace(ctx, snapshot, collection); err != nil {
+		return fmt.Errorf("Failed to swap %s collection: %w", collection, err)
+	}
+
+	if err := setLiveSnapshot(st, ctx, collection, strings.TrimPrefix(snapshot, collection+"_")); err != nil {
+		return err
+	}
+
+	// The indexes of the replaced collection are kept, but the first swap has none.
+	if err := st.CreateIndexes(ctx, collection, getIndexes(collection)); err != nil {
+		return fmt.Errorf("Failed to create indexes of %s collection: %w", collection, err)
+	}
+
+	return nil
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sfuruya0612/apf/internal/aws"
+	"github.com/sfuruya0612/apf/internal/store"
+)
+
+// insertCountingStore records the number of documents of each Insert into the snapshots of the collection.
+type insertCountingStore struct {
+	store.Store
+	collection string
+	inserts    []int
+}
+
+func (s *insertCountingStore) Insert(ctx context.Context, collection string, docs []interface{}) error {
+	if strings.HasPrefix(collection, s.collection+"_") {
+		s.inserts = append(s.inserts, len(docs))
+	}
+	return s.Store.Insert(ctx, collection, docs)
+}
+
+func writerPrices(n int, publicationDates ...string) []*aws.Price {
+	prices := make([]*aws.Price, 0, n)
+	for i := 0; i < n; i++ {
+		p := &aws.Price{Sku: fmt.Sprintf("SKU%d", i), Region: "us-east-1"}
+		if i < len(publicationDates) {
+			p.PublicationDate = publicationDates[i]
+		}
+		prices = append(prices, p)
+	}
+
+	return prices
+}
+
+func TestNewPriceWriter(t *testing.T) {
+	for _, batchSize := range []int{0, -1} {
+		if _, err := newPriceWriter(openTestStore(t), "AmazonEC2", batchSize); err == nil {
+			t.Errorf("batch size %d: got no error", batchSize)
+		}
+	}
+}
+
+func TestPriceWriterBatches(t *testing.T) {
+	tests := []struct {
+		name      string
+		batchSize int
+		writes    []int
+		want      []int
+	}{
+		{"fewer than a batch", 10, []int{3}, []int{3}},
+		// The buffer is flushed when it is full, and the rest is flushed by Commit.
+		{"several batches", 2, []int{5}, []int{2, 2, 1}},
+		{"across writes", 3, []int{2, 2, 2}, []int{3, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := &insertCountingStore{Store: openTestStore(t), collection: "ec2"}
+			ctx := context.Background()
+
+			w, err := newPriceWriter(st, "AmazonEC2", tt.batchSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var total int
+			for _, n := range tt.writes {
+				if err := w.Write(ctx, writerPrices(n)); err != nil {
+					t.Fatal(err)
+				}
+				total += n
+			}
+
+			if err := w.Commit(ctx, 3); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(st.inserts, tt.want) {
+				t.Errorf("got inserts %v, want %v", st.inserts, tt.want)
+			}
+
+			if count, err := st.Count(ctx, "ec2"); err != nil || count != int64(total) {
+				t.Errorf("got %d documents in the live collection (%v), want %d", count, err, total)
+			}
+		})
+	}
+}
+
+func TestPriceWriterCommit(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+
+	w, err := newPriceWriter(st, "AmazonEC2", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Write(ctx, writerPrices(3, "2023-05-01T00:00:00Z", "2023-06-01T00:00:00Z", "2023-04-01T00:00:00Z")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Flush inserts the buffered price, but the live collection is not touched until Commit.
+	if err := w.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if count, err := st.Count(ctx, w.collection); err != nil || count != 3 {
+		t.Errorf("got %d documents in the snapshot (%v), want 3", count, err)
+	}
+	if count, _ := st.Count(ctx, "ec2"); count != 0 {
+		t.Errorf("got %d documents in the live collection before Commit, want 0", count)
+	}
+
+	if err := w.Commit(ctx, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	id := strings.TrimPrefix(w.collection, "ec2_")
+
+	metadata, err := getSnapshotMetadata(st, ctx, "ec2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, ok := metadata[id]
+	if !ok {
+		t.Fatalf("got no metadata of snapshot %s", id)
+	}
+	// The latest publication date of the prices is recorded.
+	if s.Count != 3 || s.PublicationDate != "2023-06-01T00:00:00Z" {
+		t.Errorf("got %d products published at %s, want 3 published at 2023-06-01T00:00:00Z", s.Count, s.PublicationDate)
+	}
+
+	if live, err := getLiveSnapshot(st, ctx, "ec2", nil); err != nil || live != id {
+		t.Errorf("got live snapshot %s (%v), want %s", live, err, id)
+	}
+}
+
+func TestPriceWriterAbort(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+
+	w, err := newPriceWriter(st, "AmazonEC2", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Write(ctx, writerPrices(3)); err != nil {
+		t.Fatal(err)
+	}
+
+	w.Abort(ctx)
+
+	// The snapshot is removed, and the live collection is left as it is.
+	names, err := st.ListCollections(ctx, "^ec2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 0 {
+		t.Errorf("got collections %v, want none", names)
+	}
+
+	// Committing nothing fails the verification.
+	w, err = newPriceWriter(st, "AmazonEC2", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Commit(ctx, 3); err == nil {
+		t.Error("got no error committing no products")
+	}
+}
@@ -0,0 +1,107 @@
+package aws
+
+import "strings"
+
+// EBS usage kinds of the products in the AmazonEC2 offer.
+const (
+	EbsUsageStorage    = "storage"
+	EbsUsageIops       = "iops"
+	EbsUsageThroughput = "throughput"
+	EbsUsageSnapshot   = "snapshot"
+)
+
+// EbsVolumeTypes are the volume types stored in the price list.
+var EbsVolumeTypes = []string{"gp2", "gp3", "io1", "io2", "st1", "sc1"}
+
+// ebsProductFamilies are the product families of EBS, keyed by product family with the usage kind.
+var ebsProductFamilies = map[string]string{
+	"Storage":                EbsUsageStorage,
+	"System Operation":       EbsUsageIops,
+	"Provisioned Throughput": EbsUsageThroughput,
+	"Storage Snapshot":       EbsUsageSnapshot,
+}
+
+// ebsIopsTiers are the ranges of the io2 provisioned IOPS tiers, keyed by the usage type suffix.
+// Each tier is a separate product without the range.
+var ebsIopsTiers = map[string][2]string{
+	"EBS:VolumeP-IOPS.io2":       {"0", "32000"},
+	"EBS:VolumeP-IOPS.io2.tier2": {"32000", "64000"},
+	"EBS:VolumeP-IOPS.io2.tier3": {"64000", "Inf"},
+}
+
+// EbsUsage returns the usage kind of the EBS product family, or an empty string if it is not EBS.
+func EbsUsage(productFamily string) string {
+	return ebsProductFamilies[productFamily]
+}
+
+func isEbsProduct(productFamily string, attr map[string]interface{}) bool {
+	switch EbsUsage(productFamily) {
+	case EbsUsageStorage, EbsUsageIops, EbsUsageThroughput:
+		volume := stringValue(attr, "volumeApiName")
+		for _, v := range EbsVolumeTypes {
+			if v == volume {
+				return true
+			}
+		}
+		return false
+	case EbsUsageSnapshot:
+		// Exclude the archive tier and Fast Snapshot Restore.
+		return strings.HasSuffix(stringValue(attr, "usagetype"), "EBS:SnapshotUsage")
+	default:
+		return false
+	}
+}
+
+// product example:
+//
+//	"product": {
+//	  "productFamily": "Storage",
+//	  "attributes": {
+//	    "maxThroughputvolume": "1000 MiB/s",
+//	    "volumeType": "General Purpose",
+//	    "maxIopsvolume": "16000",
+//	    "usagetype": "APN1-EBS:VolumeUsage.gp3",
+//	    "locationType": "AWS Region",
+//	    "maxVolumeSize": "16 TiB",
+//	    "storageMedia": "SSD-backed",
+//	    "regionCode": "ap-northeast-1",
+//	    "servicecode": "AmazonEC2",
+//	    "volumeApiName": "gp3",
+//	    "location": "Asia Pacific (Tokyo)",
+//	    "servicename": "Amazon Elastic Compute Cloud",
+//	    "operation": ""
+//	  },
+//	  "sku": "3UGNP4FZ7W2WRPAS"
+//	}
+func (p *Price) addEbsAttributes(attr map[string]interface{}) *Price {
+	p.Product.Attributes.VolumeApiName = stringValue(attr, "volumeApiName")
+	p.Product.Attributes.VolumeType = stringValue(attr, "volumeType")
+	p.Product.Attributes.StorageMedia = stringValue(attr, "storageMedia")
+	p.Product.Attributes.MaxVolumeSize = stringValue(attr, "maxVolumeSize")
+	p.Product.Attributes.MaxIopsvolume = stringValue(attr, "maxIopsvolume")
+	p.Product.Attributes.MaxThroughputvolume = stringValue(attr, "maxThroughputvolume")
+	p.Product.Attributes.Group = stringValue(attr, "group")
+	p.Product.Attributes.GroupDescription = stringValue(attr, "groupDescription")
+	p.Product.Attributes.UsageType = stringValue(attr, "usagetype")
+	p.Product.Attributes.LocationType = stringValue(attr, "locationType")
+	p.Product.Attributes.RegionCode = stringValue(attr, "regionCode")
+	p.Product.Attributes.Servicecode = stringValue(attr, "servicecode")
+	p.Product.Attributes.Location = stringValue(attr, "location")
+	p.Product.Attributes.Servicename = stringValue(attr, "servicename")
+	p.Product.Attributes.Operation = stringValue(attr, "operation")
+
+	// The io2 IOPS tiers have no range, so the range is set from the usage type.
+	for suffix, r := range ebsIopsTiers {
+		if !strings.HasSuffix(p.Product.Attributes.UsageType, suffix) {
+			continue
+		}
+
+		for i := range p.OnDemand {
+			if p.OnDemand[i].BeginRange == "0" && p.OnDemand[i].EndRange == "Inf" {
+				p.OnDemand[i].BeginRange, p.OnDemand[i].EndRange = r[0], r[1]
+			}
+		}
+	}
+
+	return p
+}
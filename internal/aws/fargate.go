@@ -0,0 +1,67 @@
+package aws
+
+// Fargate usage kinds of the products.
+const (
+	FargateUsageVcpu             = "vcpu"
+	FargateUsageMemory           = "memory"
+	FargateUsageWindowsLicense   = "windows-license"
+	FargateUsageEphemeralStorage = "ephemeral-storage"
+)
+
+type fargateUsage struct {
+	osEngine     string
+	architecture string
+	usage        string
+}
+
+// fargateUsageTypes are the usage types stored in the price list without the region prefix (e.g. APN1-).
+// Fargate has no instance types, so the platform and the usage kind are mapped from the usage type.
+// Ephemeral storage is shared by every platform.
+var fargateUsageTypes = map[string]fargateUsage{
+	"Fargate-vCPU-Hours:perCPU":         {"Linux", ArchitectureX86, FargateUsageVcpu},
+	"Fargate-GB-Hours":                  {"Linux", ArchitectureX86, FargateUsageMemory},
+	"Fargate-ARM-vCPU-Hours:perCPU":     {"Linux", ArchitectureArm64, FargateUsageVcpu},
+	"Fargate-ARM-GB-Hours":              {"Linux", ArchitectureArm64, FargateUsageMemory},
+	"Fargate-Windows-vCPU-Hours:perCPU": {"Windows", ArchitectureX86, FargateUsageVcpu},
+	"Fargate-Windows-GB-Hours":          {"Windows", ArchitectureX86, FargateUsageMemory},
+	"Fargate-Windows-OS-Hours:perCPU":   {"Windows", ArchitectureX86, FargateUsageWindowsLicense},
+	"Fargate-EphemeralStorage-GB-Hours": {"", "", FargateUsageEphemeralStorage},
+}
+
+// FargateUsage returns the usage kind of the usage type, or an empty string if it is not stored.
+// Fargate Spot (e.g. APN1-SpotUsage-Fargate-...) is not stored.
+func FargateUsage(usageType string) string {
+	return fargateUsageTypes[trimRegionPrefix(usageType)].usage
+}
+
+// product example:
+//
+//	"product": {
+//	  "productFamily": "Compute",
+//	  "attributes": {
+//	    "regionCode": "ap-northeast-1",
+//	    "servicecode": "AmazonECS",
+//	    "usagetype": "APN1-Fargate-ARM-vCPU-Hours:perCPU",
+//	    "locationType": "AWS Region",
+//	    "location": "Asia Pacific (Tokyo)",
+//	    "servicename": "Amazon Elastic Container Service",
+//	    "operation": "",
+//	    "cputype": "perCPU"
+//	  },
+//	  "sku": "8CESGAFWKAJ98PME"
+//	}
+func (p *Price) addFargateAttributes(attr map[string]interface{}) *Price {
+	p.Product.Attributes.UsageType = stringValue(attr, "usagetype")
+	p.Product.Attributes.LocationType = stringValue(attr, "locationType")
+	p.Product.Attributes.RegionCode = stringValue(attr, "regionCode")
+	p.Product.Attributes.Servicecode = stringValue(attr, "servicecode")
+	p.Product.Attributes.Location = stringValue(attr, "location")
+	p.Product.Attributes.Servicename = stringValue(attr, "servicename")
+	p.Product.Attributes.Operation = stringValue(attr, "operation")
+
+	u := fargateUsageTypes[trimRegionPrefix(p.Product.Attributes.UsageType)]
+	p.Product.Attributes.OSEngine = u.osEngine
+	p.Product.Attributes.ProcessorArchitecture = u.architecture
+
+	return p
+}
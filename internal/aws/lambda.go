@@ -0,0 +1,65 @@
+package aws
+
+import "strings"
+
+// Lambda usage kinds of the product groups.
+const (
+	LambdaUsageRequests         = "requests"
+	LambdaUsageDuration         = "duration"
+	LambdaUsageEphemeralStorage = "ephemeral-storage"
+)
+
+// lambdaGroups are the product groups stored in the price list, keyed by group with the usage kind.
+// Groups ending in -ARM are the prices of arm64 (Graviton) functions.
+var lambdaGroups = map[string]string{
+	"AWS-Lambda-Requests":             LambdaUsageRequests,
+	"AWS-Lambda-Requests-ARM":         LambdaUsageRequests,
+	"AWS-Lambda-Duration":             LambdaUsageDuration,
+	"AWS-Lambda-Duration-ARM":         LambdaUsageDuration,
+	"AWS-Lambda-Storage-Duration":     LambdaUsageEphemeralStorage,
+	"AWS-Lambda-Storage-Duration-ARM": LambdaUsageEphemeralStorage,
+}
+
+// LambdaUsage returns the usage kind of the Lambda product group, or an empty string if it is not stored.
+func LambdaUsage(group string) string {
+	return lambdaGroups[group]
+}
+
+// product example:
+//
+//	"product": {
+//	  "productFamily": "Serverless",
+//	  "attributes": {
+//	    "regionCode": "ap-northeast-1",
+//	    "servicecode": "AWSLambda",
+//	    "usagetype": "APN1-Lambda-GB-Second-ARM",
+//	    "locationType": "AWS Region",
+//	    "location": "Asia Pacific (Tokyo)",
+//	    "servicename": "AWS Lambda",
+//	    "operation": "",
+//	    "group": "AWS-Lambda-Duration-ARM",
+//	    "groupDescription": "Invocation duration in GB-seconds for Arm functions"
+//	  },
+//	  "sku": "2DRGUHB4DUBPQS5Z"
+//	}
+func (p *Price) addLambdaAttributes(attr map[string]interface{}) *Price {
+	group := stringValue(attr, "group")
+
+	p.Product.Attributes.Group = group
+	p.Product.Attributes.GroupDescription = stringValue(attr, "groupDescription")
+	p.Product.Attributes.UsageType = stringValue(attr, "usagetype")
+	p.Product.Attributes.LocationType = stringValue(attr, "locationType")
+	p.Product.Attributes.RegionCode = stringValue(attr, "regionCode")
+	p.Product.Attributes.Servicecode = stringValue(attr, "servicecode")
+	p.Product.Attributes.Location = stringValue(attr, "location")
+	p.Product.Attributes.Servicename = stringValue(attr, "servicename")
+	p.Product.Attributes.Operation = stringValue(attr, "operation")
+
+	if strings.HasSuffix(group, "-ARM") {
+		p.Product.Attributes.ProcessorArchitecture = ArchitectureArm64
+	} else {
+		p.Product.Attributes.ProcessorArchitecture = ArchitectureX86
+	}
+
+	return p
+}
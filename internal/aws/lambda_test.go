@@ -0,0 +1,32 @@
+package aws
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePriceLambda(t *testing.T) {
+	price, err := parsePrice("AWSLambda", loadFixture(t, "lambda_duration_us_east_1.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attr := price.Product.Attributes
+	if LambdaUsage(attr.Group) != LambdaUsageDuration || attr.ProcessorArchitecture != ArchitectureX86 {
+		t.Errorf("got group %q and architecture %q", attr.Group, attr.ProcessorArchitecture)
+	}
+
+	// The first tier is the price of the product.
+	if price.OnDemandPricePerUSD != "0.0000166667" {
+		t.Errorf("got price %q, want 0.0000166667", price.OnDemandPricePerUSD)
+	}
+
+	want := []PriceTier{
+		{BeginRange: 0, EndRange: 6e9, Unit: "Lambda-GB-Second", PricePerUnitUSD: 0.0000166667},
+		{BeginRange: 6e9, EndRange: 15e9, Unit: "Lambda-GB-Second", PricePerUnitUSD: 0.000015},
+		{BeginRange: 15e9, EndRange: 0, Unit: "Lambda-GB-Second", PricePerUnitUSD: 0.0000133334},
+	}
+	if !reflect.DeepEqual(price.OnDemandTiers, want) {
+		t.Errorf("got tiers %+v, want %+v", price.OnDemandTiers, want)
+	}
+}
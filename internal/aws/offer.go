@@ -0,0 +1,253 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// offer file example (bulk Price List API):
+//
+//	{
+//	  "formatVersion": "v1.0",
+//	  "disclaimer": "...",
+//	  "offerCode": "AmazonEC2",
+//	  "version": "20230601000000",
+//	  "publicationDate": "2023-06-01T00:00:00Z",
+//	  "products": {
+//	    "2223B6PCG6QAUYY6": {"sku": "2223B6PCG6QAUYY6", "productFamily": "Compute Instance", "attributes": {...}}
+//	  },
+//	  "terms": {
+//	    "OnDemand": {"2223B6PCG6QAUYY6": {"2223B6PCG6QAUYY6.JRTCKXETXF": {...}}},
+//	    "Reserved": {"2223B6PCG6QAUYY6": {"2223B6PCG6QAUYY6.4NA7Y494T4": {...}}}
+//	  }
+//	}
+type offer struct {
+	serviceCode     string
+	publicationDate string
+	regions         map[string]bool
+	maxProducts     int
+	hasProducts     bool
+	products        map[string]map[string]interface{}
+	terms           map[string]map[string]interface{}
+}
+
+// offerBatchSize is the number of prices passed to the handler at once, the same as a page of GetProducts.
+const offerBatchSize = 100
+
+// ReadOffer reads an offer file of the bulk Price List API, and passes the service code and prices to handle.
+// The file is decoded token by token, and only the products in the given regions are kept in memory,
+// so multi-GB offer files can be read. If regions is empty or contains AllRegions, every region is kept.
+// The products are joined with the terms that follow them, so more than maxProducts kept products are an error
+// instead of running out of memory. maxProducts of 0 is unlimited.
+func ReadOffer(r io.Reader, regions []string, maxProducts int, handle func(serviceCode string, prices []*Price) error) error {
+	o := &offer{
+		maxProducts: maxProducts,
+		products:    map[string]map[string]interface{}{},
+		terms:       map[string]map[string]interface{}{},
+	}
+
+	if rs := normalizeRegions(regions); rs[0] != AllRegions {
+		o.regions = map[string]bool{}
+		for _, region := range rs {
+			o.regions[region] = true
+		}
+	}
+
+	dec := json.NewDecoder(r)
+
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+
+	for dec.More() {
+		key, err := readKey(dec)
+		if err != nil {
+			return err
+		}
+
+		switch key {
+		case "offerCode":
+			if err := dec.Decode(&o.serviceCode); err != nil {
+				return fmt.Errorf("Failed to read offerCode: %w", err)
+			}
+		case "publicationDate":
+			if err := dec.Decode(&o.publicationDate); err != nil {
+				return fmt.Errorf("Failed to read publicationDate: %w", err)
+			}
+		case "products":
+			if err := o.readProducts(dec); err != nil {
+				return fmt.Errorf("Failed to read products: %w", err)
+			}
+			o.hasProducts = true
+		case "terms":
+			// The terms are joined only to the products read before, so they would all be dropped.
+			if !o.hasProducts {
+				return fmt.Errorf("Terms precede products, which is not supported")
+			}
+
+			if err := o.readTerms(dec); err != nil {
+				return fmt.Errorf("Failed to read terms: %w", err)
+			}
+		default:
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return fmt.Errorf("Failed to read %s: %w", key, err)
+			}
+		}
+	}
+
+	if o.serviceCode == "" {
+		return fmt.Errorf("offerCode is missing")
+	}
+
+	var prices []*Price
+	for sku, product := range o.products {
+		p := map[string]interface{}{
+			"product":         product,
+			"publicationDate": o.publicationDate,
+			"terms":           o.terms[sku],
+		}
+		prices = appendPrice(o.serviceCode, prices, p)
+
+		delete(o.products, sku)
+		delete(o.terms, sku)
+
+		if len(prices) >= offerBatchSize {
+			if err := handle(o.serviceCode, prices); err != nil {
+				return err
+			}
+			prices = nil
+		}
+	}
+
+	if len(prices) > 0 {
+		if err := handle(o.serviceCode, prices); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (o *offer) readProducts(dec *json.Decoder) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+
+	for dec.More() {
+		sku, err := readKey(dec)
+		if err != nil {
+			return err
+		}
+
+		var product map[string]interface{}
+		if err := dec.Decode(&product); err != nil {
+			return fmt.Errorf("product %s: %w", sku, err)
+		}
+
+		if o.keep(product) {
+			if o.maxProducts > 0 && len(o.products) >= o.maxProducts {
+				return fmt.Errorf("More than %d products are kept in memory (import fewer regions or the offer files per region)", o.maxProducts)
+			}
+			o.products[sku] = product
+		}
+	}
+
+	return expectDelim(dec, '}')
+}
+
+func (o *offer) keep(product map[string]interface{}) bool {
+	attr, ok := product["attributes"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	locationTypeKey, regionCodeKey := locationKeys(o.serviceCode)
+
+	// Only AWS Region location. (Exclude AWS Outpost)
+	if stringValue(attr, locationTypeKey) != "AWS Region" {
+		return false
+	}
+
+	if o.regions != nil && !o.regions[stringValue(attr, regionCodeKey)] {
+		return false
+	}
+
+	return isTargetProduct(o.serviceCode, product)
+}
+
+func (o *offer) readTerms(dec *json.Decoder) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+
+	for dec.More() {
+		termType, err := readKey(dec)
+		if err != nil {
+			return err
+		}
+
+		if err := expectDelim(dec, '{'); err != nil {
+			return err
+		}
+
+		for dec.More() {
+			sku, err := readKey(dec)
+			if err != nil {
+				return err
+			}
+
+			if _, ok := o.products[sku]; !ok {
+				var skip json.RawMessage
+				if err := dec.Decode(&skip); err != nil {
+					return fmt.Errorf("%s term of %s: %w", termType, sku, err)
+				}
+				continue
+			}
+
+			var term map[string]interface{}
+			if err := dec.Decode(&term); err != nil {
+				return fmt.Errorf("%s term of %s: %w", termType, sku, err)
+			}
+
+			if o.terms[sku] == nil {
+				o.terms[sku] = map[string]interface{}{}
+			}
+			o.terms[sku][termType] = term
+		}
+
+		if err := expectDelim(dec, '}'); err != nil {
+			return err
+		}
+	}
+
+	return expectDelim(dec, '}')
+}
+
+func readKey(dec *json.Decoder) (string, error) {
+	t, err := dec.Token()
+	if err != nil {
+		return "", err
+	}
+
+	key, ok := t.(string)
+	if !ok {
+		return "", fmt.Errorf("Unexpected token %v, want object key", t)
+	}
+
+	return key, nil
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	if d, ok := t.(json.Delim); !ok || d != want {
+		return fmt.Errorf("Unexpected token %v, want %v", t, want)
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package aws
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// readOfferFixture returns the prices of the offer file in testdata keyed by the SKU.
+func readOfferFixture(t *testing.T, name string, regions []string) (string, map[string]*Price) {
+	t.Helper()
+
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var serviceCode string
+	prices := map[string]*Price{}
+	if err := ReadOffer(f, regions, 0, func(sc string, p []*Price) error {
+		serviceCode = sc
+		for _, price := range p {
+			prices[price.Sku] = price
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	return serviceCode, prices
+}
+
+func TestReadOffer(t *testing.T) {
+	tests := []struct {
+		name    string
+		regions []string
+		want    []string
+	}{
+		// Outposts and products without vcpu are skipped.
+		{"all regions", []string{AllRegions}, []string{"8VCNEHQMSCQS4P39", "Q5KJSQ8D6YCWXG4R"}},
+		{"no regions", nil, []string{"8VCNEHQMSCQS4P39", "Q5KJSQ8D6YCWXG4R"}},
+		{"a region", []string{"us-east-1"}, []string{"8VCNEHQMSCQS4P39"}},
+		{"other regions", []string{"eu-west-1"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serviceCode, prices := readOfferFixture(t, "offer_ec2.json", tt.regions)
+			if serviceCode != "" && serviceCode != "AmazonEC2" {
+				t.Errorf("got service code %q, want AmazonEC2", serviceCode)
+			}
+
+			var skus []string
+			for sku := range prices {
+				skus = append(skus, sku)
+			}
+			sort.Strings(skus)
+
+			if strings.Join(skus, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got %v, want %v", skus, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadOfferTerms(t *testing.T) {
+	_, prices := readOfferFixture(t, "offer_ec2.json", []string{"us-east-1"})
+
+	price := prices["8VCNEHQMSCQS4P39"]
+	if price == nil {
+		t.Fatal("8VCNEHQMSCQS4P39 is missing")
+	}
+
+	if price.OnDemandHourlyUSD != 0.096 || price.PublicationDate != "2023-06-01T00:00:00Z" || price.Region != "us-east-1" {
+		t.Errorf("got price %v, publication date %q and region %q", price.OnDemandHourlyUSD, price.PublicationDate, price.Region)
+	}
+
+	if len(price.Reserved) != 1 || price.Reserved[0].UpfrontFeeUSD != "245" || price.Reserved[0].HourlyPriceUSD != "0.0280000000" {
+		t.Errorf("got reserved terms %+v", price.Reserved)
+	}
+}
+
+func TestReadOfferError(t *testing.T) {
+	tests := []struct {
+		name  string
+		offer string
+	}{
+		{"not an object", `[]`},
+		{"offerCode is missing", `{"products": {}, "terms": {}}`},
+		{"broken product", `{"offerCode": "AmazonEC2", "products": {"A": [}}`},
+		{"truncated", `{"offerCode": "AmazonEC2", "products": {`},
+		{"terms before products", `{"offerCode": "AmazonEC2", "terms": {}, "products": {}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ReadOffer(strings.NewReader(tt.offer), nil, 0, func(string, []*Price) error { return nil })
+			if err == nil {
+				t.Error("got no error")
+			}
+		})
+	}
+}
+
+func TestReadOfferMaxProducts(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxProducts int
+		wantErr     bool
+	}{
+		{"unlimited", 0, false},
+		{"enough", 2, false},
+		// Only the products kept are counted.
+		{"too many", 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", "offer_ec2.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			err = ReadOffer(f, []string{AllRegions}, tt.maxProducts, func(string, []*Price) error { return nil })
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
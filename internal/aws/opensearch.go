@@ -0,0 +1,69 @@
+package aws
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ebsOnlyStorage matches the storage of instances without instance store (e.g. "EBS Only").
+var ebsOnlyStorage = regexp.MustCompile(`(?i)^ebs only$`)
+
+// product example:
+//
+//	"product": {
+//	  "productFamily": "Amazon OpenSearch Service Instance",
+//	  "attributes": {
+//	    "memoryGib": "16",
+//	    "vcpu": "2",
+//	    "instanceType": "r6g.large.search",
+//	    "usagetype": "APN1-ESInstance:r6g.large",
+//	    "locationType": "AWS Region",
+//	    "storage": "EBS Only",
+//	    "instanceFamily": "Memory optimized",
+//	    "regionCode": "ap-northeast-1",
+//	    "servicecode": "AmazonES",
+//	    "currentGeneration": "Yes",
+//	    "location": "Asia Pacific (Tokyo)",
+//	    "servicename": "Amazon OpenSearch Service",
+//	    "operation": "ESDomain"
+//	  },
+//	  "sku": "2B8VQ8JXDXHZTVGV"
+//	}
+func (p *Price) addOpensearchAttributes(attr map[string]interface{}) *Price {
+	p.Product.Attributes.Memory = memoryValue(attr)
+	p.Product.Attributes.Vcpu = stringValue(attr, "vcpu")
+	p.Product.Attributes.InstanceType = stringValue(attr, "instanceType")
+	p.Product.Attributes.UsageType = stringValue(attr, "usagetype")
+	p.Product.Attributes.LocationType = stringValue(attr, "locationType")
+	p.Product.Attributes.Storage = stringValue(attr, "storage")
+	p.Product.Attributes.InstanceFamily = stringValue(attr, "instanceFamily")
+	p.Product.Attributes.RegionCode = stringValue(attr, "regionCode")
+	p.Product.Attributes.Servicecode = stringValue(attr, "servicecode")
+	p.Product.Attributes.CurrentGeneration = stringValue(attr, "currentGeneration")
+	p.Product.Attributes.Location = stringValue(attr, "location")
+	p.Product.Attributes.Servicename = stringValue(attr, "servicename")
+	p.Product.Attributes.Operation = stringValue(attr, "operation")
+
+	p.Product.Attributes.StorageMedia = storageMedia(p.Product.Attributes.Storage)
+
+	// UltraWarm nodes are priced as instances (e.g. ultrawarm1.medium.search).
+	if strings.HasPrefix(p.Product.Attributes.InstanceType, "ultrawarm") {
+		p.Product.Attributes.UltraWarm = "Yes"
+	} else {
+		p.Product.Attributes.UltraWarm = "No"
+	}
+
+	return p
+}
+
+// storageMedia returns EBS if the instance has no instance store, otherwise Instance store.
+func storageMedia(storage string) string {
+	switch {
+	case storage == "":
+		return ""
+	case ebsOnlyStorage.MatchString(storage):
+		return "EBS"
+	default:
+		return "Instance store"
+	}
+}
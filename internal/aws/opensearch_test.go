@@ -0,0 +1,54 @@
+package aws
+
+import "testing"
+
+func TestAddOpensearchAttributes(t *testing.T) {
+	tests := []struct {
+		name         string
+		attr         map[string]interface{}
+		memory       string
+		storageMedia string
+		ultraWarm    string
+	}{
+		{
+			"EBS only",
+			map[string]interface{}{"instanceType": "r6g.large.search", "memoryGib": "16", "storage": "EBS Only"},
+			"16 GiB", "EBS", "No",
+		},
+		{
+			"instance store",
+			map[string]interface{}{"instanceType": "i3.large.search", "memoryGib": "15.25", "storage": "1 x 475 NVMe SSD"},
+			"15.25 GiB", "Instance store", "No",
+		},
+		{
+			"UltraWarm",
+			map[string]interface{}{"instanceType": "ultrawarm1.medium.search", "memoryGib": "15.25", "storage": "1.5 TB"},
+			"15.25 GiB", "Instance store", "Yes",
+		},
+		// memory is preferred to memoryGib.
+		{
+			"no storage",
+			map[string]interface{}{"instanceType": "t3.small.search", "memory": "2 GiB", "memoryGib": "2"},
+			"2 GiB", "", "No",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attr := (&Price{}).addOpensearchAttributes(tt.attr).Product.Attributes
+
+			if attr.InstanceType != tt.attr["instanceType"] {
+				t.Errorf("got instance type %q, want %q", attr.InstanceType, tt.attr["instanceType"])
+			}
+			if attr.Memory != tt.memory {
+				t.Errorf("got memory %q, want %q", attr.Memory, tt.memory)
+			}
+			if attr.StorageMedia != tt.storageMedia {
+				t.Errorf("got storage media %q, want %q", attr.StorageMedia, tt.storageMedia)
+			}
+			if attr.UltraWarm != tt.ultraWarm {
+				t.Errorf("got UltraWarm %q, want %q", attr.UltraWarm, tt.ultraWarm)
+			}
+		})
+	}
+}
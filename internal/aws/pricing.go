@@ -5,6 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/pricing"
@@ -54,52 +57,132 @@ type Price struct {
 			PreInstalledSw              string
 			Marketoption                string
 			Availabilityzone            string
+			Group                       string
+			GroupDescription            string
+			VolumeApiName               string
+			VolumeType                  string
+			StorageMedia                string
+			MaxVolumeSize               string
+			MaxIopsvolume               string
+			MaxThroughputvolume         string
+			StorageClass                string
+			UltraWarm                   string
+			Io                          string
+			StorageConfig               string
+			TransferType                string
+			ToLocation                  string
+			ToRegionCode                string
+			Destination                 string
 		}
 	}
+	Sku                 string
 	ServiceCode         string
+	Region              string
+	PublicationDate     string
 	OnDemandPricePerUSD string
+	OnDemand            []PriceDimension
+	Reserved            []ReservedTerm
+
+	// Numeric values of the attributes and the hourly On-Demand price for range queries.
+	// They are 0 if the attribute is not a number (e.g. NA).
+	VcpuCount         float64
+	MemoryGiB         float64
+	OnDemandHourlyUSD float64
+
+	// OnDemandTiers are the typed On-Demand prices in USD. Flat prices have a single tier.
+	OnDemandTiers []PriceTier
 }
 
-func GetProducts(cfg aws.Config, serviceCode string) ([]*Price, error) {
-	log.Printf("Fetching %s products from AWS Price List API\n", serviceCode)
+// AllRegions is the special region value that fetches products for every AWS Region.
+const AllRegions = "all"
+
+// Processor architectures of the serverless products stored in ProcessorArchitecture.
+const (
+	ArchitectureX86   = "x86_64"
+	ArchitectureArm64 = "arm64"
+)
 
+// GetProducts fetches the products of the service for each of the given region codes,
+// and passes the parsed prices to handle page by page.
+// If regions is empty or contains AllRegions, products for every AWS Region are fetched.
+func GetProducts(cfg aws.Config, serviceCode string, regions []string, handle func([]*Price) error) error {
 	client := pricing.NewFromConfig(cfg)
 
-	input := &pricing.GetProductsInput{
-		ServiceCode: aws.String(serviceCode),
-		Filters: []types.Filter{
-			{
-				Field: aws.String("regionCode"),
-				Type:  types.FilterTypeTermMatch,
-				Value: aws.String("ap-northeast-1"),
-			},
-			// Only AWS Region location. (Exclude AWS Outpost)
-			{
-				Field: aws.String("locationType"),
-				Type:  types.FilterTypeTermMatch,
-				Value: aws.String("AWS Region"),
-			},
-		},
+	for _, region := range normalizeRegions(regions) {
+		if region == AllRegions {
+			log.Printf("Fetching %s products for all regions from AWS Price List API\n", serviceCode)
+		} else {
+			log.Printf("Fetching %s products for %s from AWS Price List API\n", serviceCode, region)
+		}
+
+		paginator := pricing.NewGetProductsPaginator(client, getProductsInput(serviceCode, region))
+
+		for paginator.HasMorePages() {
+			output, err := paginator.NextPage(context.Background())
+			if err != nil {
+				return fmt.Errorf("Failed to get products: %w", err)
+			}
+
+			p, err := parsePricing(serviceCode, nil, output.PriceList)
+			if err != nil {
+				return fmt.Errorf("Failed to parse products: %w", err)
+			}
+
+			if err := handle(p); err != nil {
+				return err
+			}
+		}
 	}
 
-	paginator := pricing.NewGetProductsPaginator(client, input)
+	return nil
+}
 
-	var p []*Price
+func normalizeRegions(regions []string) []string {
+	if len(regions) == 0 {
+		return []string{AllRegions}
+	}
 
-	for paginator.HasMorePages() {
-		output, err := paginator.NextPage(context.Background())
-		if err != nil {
-			return nil, fmt.Errorf("Failed to get products: %w", err)
+	seen := map[string]bool{}
+	var r []string
+	for _, region := range regions {
+		if region == AllRegions {
+			return []string{AllRegions}
 		}
 
-		p, err = parsePricing(serviceCode, p, output.PriceList)
-		if err != nil {
-			return nil, fmt.Errorf("Failed to parse products: %w", err)
+		if seen[region] {
+			continue
 		}
+		seen[region] = true
+		r = append(r, region)
+	}
+
+	return r
+}
+
+func getProductsInput(serviceCode, region string) *pricing.GetProductsInput {
+	locationTypeKey, regionCodeKey := locationKeys(serviceCode)
+
+	filters := []types.Filter{
+		// Only AWS Region location. (Exclude AWS Outpost)
+		{
+			Field: aws.String(locationTypeKey),
+			Type:  types.FilterTypeTermMatch,
+			Value: aws.String("AWS Region"),
+		},
+	}
 
+	if region != AllRegions {
+		filters = append(filters, types.Filter{
+			Field: aws.String(regionCodeKey),
+			Type:  types.FilterTypeTermMatch,
+			Value: aws.String(region),
+		})
 	}
 
-	return p, nil
+	return &pricing.GetProductsInput{
+		ServiceCode: aws.String(serviceCode),
+		Filters:     filters,
+	}
 }
 
 func parsePricing(serviceCode string, prices []*Price, priceList []string) ([]*Price, error) {
@@ -109,44 +192,216 @@ func parsePricing(serviceCode string, prices []*Price, priceList []string) ([]*P
 			return nil, err
 		}
 
-		attr := p["product"].(map[string]interface{})["attributes"].(map[string]interface{})
+		prices = appendPrice(serviceCode, prices, p)
+	}
 
-		// If the product does not have vcpu or memory, skip it.
-		if attr["vcpu"] == nil || attr["memory"] == nil {
-			continue
-		}
+	return prices, nil
+}
+
+func appendPrice(serviceCode string, prices []*Price, p map[string]interface{}) []*Price {
+	price, err := parsePrice(serviceCode, p)
+	if err != nil {
+		// A single broken product should not abort the whole fetch.
+		log.Printf("Skip %s product %s: %v\n", serviceCode, productSku(p), err)
+		return prices
+	}
 
-		price := &Price{}
-		price.ServiceCode = serviceCode
-		price.Product.ProductFamily = p["product"].(map[string]interface{})["productFamily"].(string)
+	if price == nil {
+		return prices
+	}
 
-		// TODO: Looking for a better way to get the price.
-		sku := p["product"].(map[string]interface{})["sku"].(string)
-		skuOfferTermCode := fmt.Sprintf("%s.%s", sku, "JRTCKXETXF")
-		skuOfferTermCodeRateCode := fmt.Sprintf("%s.%s.%s", sku, "JRTCKXETXF", "6YS6EN2CT7")
+	return append(prices, price)
+}
 
-		// OnDemand Terms has nil data.
-		if p["terms"].(map[string]interface{})["OnDemand"] == nil {
-			continue
+// isTargetProduct reports whether the product is stored in the price list.
+func isTargetProduct(serviceCode string, product map[string]interface{}) bool {
+	attr, ok := product["attributes"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	switch serviceCode {
+	case "AWSLambda":
+		return lambdaGroups[stringValue(attr, "group")] != ""
+	case "AmazonECS":
+		return FargateUsage(stringValue(attr, "usagetype")) != ""
+	case "AmazonS3":
+		return S3Usage(stringValue(attr, "usagetype")) != ""
+	case "AmazonEC2":
+		if isEbsProduct(stringValue(product, "productFamily"), attr) {
+			return true
+		}
+	case "AWSDataTransfer":
+		return isTransferProduct(stringValue(product, "productFamily"), attr)
+	case "AmazonRDS":
+		if RdsUsage(stringValue(product, "productFamily"), stringValue(attr, "usagetype")) != "" {
+			return true
 		}
+	}
+
+	// If the product does not have vcpu or memory, skip it.
+	return attr["vcpu"] != nil && memoryValue(attr) != ""
+}
 
-		price.OnDemandPricePerUSD = p["terms"].(map[string]interface{})["OnDemand"].(map[string]interface{})[skuOfferTermCode].(map[string]interface{})["priceDimensions"].(map[string]interface{})[skuOfferTermCodeRateCode].(map[string]interface{})["pricePerUnit"].(map[string]interface{})["USD"].(string)
+// memoryValue returns the memory attribute like "8 GiB".
+// OpenSearch products have memoryGib like "8" instead of memory.
+func memoryValue(attr map[string]interface{}) string {
+	if memory := stringValue(attr, "memory"); memory != "" {
+		return memory
+	}
+
+	if memory := stringValue(attr, "memoryGib"); memory != "" {
+		return memory + " GiB"
+	}
+
+	return ""
+}
 
-		switch serviceCode {
-		case "AmazonEC2":
+// parsePrice returns nil without error if the product is not a target of the price list.
+func parsePrice(serviceCode string, p map[string]interface{}) (*Price, error) {
+	product, ok := p["product"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("product is missing")
+	}
+
+	attr, ok := product["attributes"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("product attributes are missing")
+	}
+
+	if !isTargetProduct(serviceCode, product) {
+		return nil, nil
+	}
+
+	terms, ok := p["terms"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("terms are missing")
+	}
+
+	// OnDemand Terms has nil data.
+	onDemand, ok := terms["OnDemand"].(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	price := &Price{}
+	price.Sku = stringValue(product, "sku")
+	price.ServiceCode = serviceCode
+	_, regionCodeKey := locationKeys(serviceCode)
+	price.Region = stringValue(attr, regionCodeKey)
+	price.PublicationDate = stringValue(p, "publicationDate")
+	price.Product.ProductFamily = stringValue(product, "productFamily")
+
+	dimensions, err := parseTerms(onDemand)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse OnDemand terms: %w", err)
+	}
+	price.OnDemand = dimensions
+
+	price.OnDemandPricePerUSD = hourlyPricePerUSD(dimensions)
+	if price.OnDemandPricePerUSD == "" {
+		return nil, fmt.Errorf("OnDemand price in USD is missing")
+	}
+
+	price.OnDemandHourlyUSD, err = strconv.ParseFloat(price.OnDemandPricePerUSD, 64)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid OnDemand price: %w", err)
+	}
+
+	price.VcpuCount, _ = strconv.ParseFloat(stringValue(attr, "vcpu"), 64)
+	price.MemoryGiB, _ = ParseMemoryGiB(memoryValue(attr))
+
+	if reserved, ok := terms["Reserved"].(map[string]interface{}); ok {
+		price.Reserved, err = parseReservedTerms(reserved)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse Reserved terms: %w", err)
+		}
+	}
+
+	switch serviceCode {
+	case "AmazonEC2":
+		if isEbsProduct(price.Product.ProductFamily, attr) {
+			price = price.addEbsAttributes(attr)
+		} else {
 			price = price.addEc2Attributes(attr)
-		case "AmazonRDS":
-			price = price.addRdsAttributes(attr)
-		case "AmazonElastiCache":
-			price = price.addElasticacheAttributes(attr)
-		default:
-			panic("Unknown service code")
 		}
+	case "AmazonRDS":
+		price = price.addRdsAttributes(attr)
+	case "AmazonElastiCache":
+		price = price.addElasticacheAttributes(attr)
+	case "AWSLambda":
+		price = price.addLambdaAttributes(attr)
+	case "AmazonECS":
+		price = price.addFargateAttributes(attr)
+	case "AmazonS3":
+		price = price.addS3Attributes(attr)
+	case "AmazonES":
+		price = price.addOpensearchAttributes(attr)
+	case "AmazonRedshift":
+		price = price.addRedshiftAttributes(attr)
+	case "AWSDataTransfer":
+		price = price.addTransferAttributes(attr)
+	default:
+		return nil, fmt.Errorf("Unknown service code: %s", serviceCode)
+	}
 
-		prices = append(prices, price)
+	// Tiers are parsed after the service attributes, which may fix the ranges.
+	price.OnDemandTiers, err = ParseTiers(price.OnDemand)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse OnDemand tiers: %w", err)
 	}
 
-	return prices, nil
+	return price, nil
+}
+
+// ParseMemoryGiB parses memory like "13.07 GiB", "1,952 GiB" or "512 MiB" into GiB. A number without a unit is GiB.
+func ParseMemoryGiB(memory string) (float64, error) {
+	fields := strings.Fields(memory)
+	if len(fields) == 0 || len(fields) > 2 {
+		return 0, fmt.Errorf("Invalid memory: %q", memory)
+	}
+
+	v, err := strconv.ParseFloat(strings.ReplaceAll(fields[0], ",", ""), 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid memory: %q", memory)
+	}
+
+	if len(fields) == 1 {
+		return v, nil
+	}
+
+	switch strings.ToLower(fields[1]) {
+	case "gib", "gb":
+		return v, nil
+	case "mib", "mb":
+		return v / 1024, nil
+	case "tib", "tb":
+		return v * 1024, nil
+	default:
+		return 0, fmt.Errorf("Invalid memory unit: %q", memory)
+	}
+}
+
+// regionPrefix is the prefix of the usage types outside us-east-1 (e.g. APN1-, EUC1-).
+var regionPrefix = regexp.MustCompile(`^[A-Z]{2,4}[0-9]*-`)
+
+// trimRegionPrefix returns the usage type without the region prefix.
+func trimRegionPrefix(usageType string) string {
+	return regionPrefix.ReplaceAllString(usageType, "")
+}
+
+func productSku(p map[string]interface{}) string {
+	product, ok := p["product"].(map[string]interface{})
+	if !ok {
+		return "unknown"
+	}
+
+	sku := stringValue(product, "sku")
+	if sku == "" {
+		return "unknown"
+	}
+
+	return sku
 }
 
 func parseProduct(price string) (map[string]interface{}, error) {
@@ -205,77 +460,77 @@ func (p *Price) addEc2Attributes(attr map[string]interface{}) *Price {
 	if attr["enhancedNetworkingSupported"] == nil {
 		attr["enhancedNetworkingSupported"] = "unknown"
 	} else {
-		p.Product.Attributes.EnhancedNetworkingSupported = attr["enhancedNetworkingSupported"].(string)
+		p.Product.Attributes.EnhancedNetworkingSupported = stringValue(attr, "enhancedNetworkingSupported")
 	}
 
 	if attr["intelTurboAvailable"] == nil {
 		attr["intelTurboAvailable"] = "unknown"
 	} else {
-		p.Product.Attributes.IntelTurboAvailable = attr["intelTurboAvailable"].(string)
+		p.Product.Attributes.IntelTurboAvailable = stringValue(attr, "intelTurboAvailable")
 	}
 
 	if attr["dedicatedEbsThroughput"] == nil {
 		attr["dedicatedEbsThroughput"] = "unknown"
 	} else {
-		p.Product.Attributes.DedicatedEbsThroughput = attr["dedicatedEbsThroughput"].(string)
+		p.Product.Attributes.DedicatedEbsThroughput = stringValue(attr, "dedicatedEbsThroughput")
 	}
 
 	if attr["intelAvx2Available"] == nil {
 		attr["intelAvx2Available"] = "unknown"
 	} else {
-		p.Product.Attributes.IntelAvx2Available = attr["intelAvx2Available"].(string)
+		p.Product.Attributes.IntelAvx2Available = stringValue(attr, "intelAvx2Available")
 	}
 	if attr["clockSpeed"] == nil {
 		attr["clockSpeed"] = "unknown"
 	} else {
-		p.Product.Attributes.ClockSpeed = attr["clockSpeed"].(string)
+		p.Product.Attributes.ClockSpeed = stringValue(attr, "clockSpeed")
 	}
 
 	if attr["gpuMemory"] == nil {
 		attr["gpuMemory"] = "unknown"
 	} else {
-		p.Product.Attributes.GpuMemory = attr["gpuMemory"].(string)
+		p.Product.Attributes.GpuMemory = stringValue(attr, "gpuMemory")
 	}
 
 	if attr["intelAvxAvailable"] == nil {
 		attr["intelAvxAvailable"] = "unknown"
 	} else {
-		p.Product.Attributes.IntelAvxAvailable = attr["intelAvxAvailable"].(string)
+		p.Product.Attributes.IntelAvxAvailable = stringValue(attr, "intelAvxAvailable")
 	}
 
 	if attr["processorFeatures"] == nil {
 		attr["processorFeatures"] = "unknown"
 	} else {
-		p.Product.Attributes.ProcessorFeatures = attr["processorFeatures"].(string)
-	}
-
-	p.Product.Attributes.Memory = attr["memory"].(string)
-	p.Product.Attributes.Vcpu = attr["vcpu"].(string)
-	p.Product.Attributes.Classicnetworkingsupport = attr["classicnetworkingsupport"].(string)
-	p.Product.Attributes.Capacitystatus = attr["capacitystatus"].(string)
-	p.Product.Attributes.LocationType = attr["locationType"].(string)
-	p.Product.Attributes.Storage = attr["storage"].(string)
-	p.Product.Attributes.InstanceFamily = attr["instanceFamily"].(string)
-	p.Product.Attributes.OSEngine = attr["operatingSystem"].(string)
-	p.Product.Attributes.RegionCode = attr["regionCode"].(string)
-	p.Product.Attributes.PhysicalProcessor = attr["physicalProcessor"].(string)
-	p.Product.Attributes.Ecu = attr["ecu"].(string)
-	p.Product.Attributes.NetworkPerformance = attr["networkPerformance"].(string)
-	p.Product.Attributes.Servicename = attr["servicename"].(string)
-	p.Product.Attributes.Vpcnetworkingsupport = attr["vpcnetworkingsupport"].(string)
-	p.Product.Attributes.InstanceType = attr["instanceType"].(string)
-	p.Product.Attributes.Tenancy = attr["tenancy"].(string)
-	p.Product.Attributes.UsageType = attr["usagetype"].(string)
-	p.Product.Attributes.NormalizationSizeFactor = attr["normalizationSizeFactor"].(string)
-	p.Product.Attributes.Servicecode = attr["servicecode"].(string)
-	p.Product.Attributes.LicenseModel = attr["licenseModel"].(string)
-	p.Product.Attributes.CurrentGeneration = attr["currentGeneration"].(string)
-	p.Product.Attributes.PreInstalledSw = attr["preInstalledSw"].(string)
-	p.Product.Attributes.Location = attr["location"].(string)
-	p.Product.Attributes.ProcessorArchitecture = attr["processorArchitecture"].(string)
-	p.Product.Attributes.Marketoption = attr["marketoption"].(string)
-	p.Product.Attributes.Operation = attr["operation"].(string)
-	p.Product.Attributes.Availabilityzone = attr["availabilityzone"].(string)
+		p.Product.Attributes.ProcessorFeatures = stringValue(attr, "processorFeatures")
+	}
+
+	p.Product.Attributes.Memory = stringValue(attr, "memory")
+	p.Product.Attributes.Vcpu = stringValue(attr, "vcpu")
+	p.Product.Attributes.Classicnetworkingsupport = stringValue(attr, "classicnetworkingsupport")
+	p.Product.Attributes.Capacitystatus = stringValue(attr, "capacitystatus")
+	p.Product.Attributes.LocationType = stringValue(attr, "locationType")
+	p.Product.Attributes.Storage = stringValue(attr, "storage")
+	p.Product.Attributes.InstanceFamily = stringValue(attr, "instanceFamily")
+	p.Product.Attributes.OSEngine = stringValue(attr, "operatingSystem")
+	p.Product.Attributes.RegionCode = stringValue(attr, "regionCode")
+	p.Product.Attributes.PhysicalProcessor = stringValue(attr, "physicalProcessor")
+	p.Product.Attributes.Ecu = stringValue(attr, "ecu")
+	p.Product.Attributes.NetworkPerformance = stringValue(attr, "networkPerformance")
+	p.Product.Attributes.Servicename = stringValue(attr, "servicename")
+	p.Product.Attributes.Vpcnetworkingsupport = stringValue(attr, "vpcnetworkingsupport")
+	p.Product.Attributes.InstanceType = stringValue(attr, "instanceType")
+	p.Product.Attributes.Tenancy = stringValue(attr, "tenancy")
+	p.Product.Attributes.UsageType = stringValue(attr, "usagetype")
+	p.Product.Attributes.NormalizationSizeFactor = stringValue(attr, "normalizationSizeFactor")
+	p.Product.Attributes.Servicecode = stringValue(attr, "servicecode")
+	p.Product.Attributes.LicenseModel = stringValue(attr, "licenseModel")
+	p.Product.Attributes.CurrentGeneration = stringValue(attr, "currentGeneration")
+	p.Product.Attributes.PreInstalledSw = stringValue(attr, "preInstalledSw")
+	p.Product.Attributes.Location = stringValue(attr, "location")
+	p.Product.Attributes.ProcessorArchitecture = stringValue(attr, "processorArchitecture")
+	p.Product.Attributes.Marketoption = stringValue(attr, "marketoption")
+	p.Product.Attributes.Operation = stringValue(attr, "operation")
+	p.Product.Attributes.Availabilityzone = stringValue(attr, "availabilityzone")
 
 	return p
 }
@@ -316,56 +571,63 @@ func (p *Price) addRdsAttributes(attr map[string]interface{}) *Price {
 	if attr["engineCode"] == nil {
 		p.Product.Attributes.EngineCode = "unknown"
 	} else {
-		p.Product.Attributes.EngineCode = attr["engineCode"].(string)
+		p.Product.Attributes.EngineCode = stringValue(attr, "engineCode")
 	}
 
 	if attr["databaseEdition"] == nil {
 		p.Product.Attributes.DatabaseEdition = "unknown"
 	} else {
-		p.Product.Attributes.DatabaseEdition = attr["databaseEdition"].(string)
+		p.Product.Attributes.DatabaseEdition = stringValue(attr, "databaseEdition")
 	}
 
 	if attr["physicalProcessor"] == nil {
 		p.Product.Attributes.PhysicalProcessor = "unknown"
 	} else {
-		p.Product.Attributes.PhysicalProcessor = attr["physicalProcessor"].(string)
+		p.Product.Attributes.PhysicalProcessor = stringValue(attr, "physicalProcessor")
 	}
 
 	if attr["currentGeneration"] == nil {
 		p.Product.Attributes.CurrentGeneration = "unknown"
 	} else {
-		p.Product.Attributes.CurrentGeneration = attr["currentGeneration"].(string)
+		p.Product.Attributes.CurrentGeneration = stringValue(attr, "currentGeneration")
 	}
 
 	if attr["networkPerformance"] == nil {
 		p.Product.Attributes.NetworkPerformance = "unknown"
 	} else {
-		p.Product.Attributes.NetworkPerformance = attr["networkPerformance"].(string)
+		p.Product.Attributes.NetworkPerformance = stringValue(attr, "networkPerformance")
 	}
 
 	if attr["processorArchitecture"] == nil {
 		p.Product.Attributes.ProcessorArchitecture = "unknown"
 	} else {
-		p.Product.Attributes.ProcessorArchitecture = attr["processorArchitecture"].(string)
-	}
-
-	p.Product.Attributes.InstanceTypeFamily = attr["instanceTypeFamily"].(string)
-	p.Product.Attributes.Memory = attr["memory"].(string)
-	p.Product.Attributes.Vcpu = attr["vcpu"].(string)
-	p.Product.Attributes.InstanceType = attr["instanceType"].(string)
-	p.Product.Attributes.UsageType = attr["usagetype"].(string)
-	p.Product.Attributes.LocationType = attr["locationType"].(string)
-	p.Product.Attributes.Storage = attr["storage"].(string)
-	p.Product.Attributes.NormalizationSizeFactor = attr["normalizationSizeFactor"].(string)
-	p.Product.Attributes.InstanceFamily = attr["instanceFamily"].(string)
-	p.Product.Attributes.OSEngine = attr["databaseEngine"].(string)
-	p.Product.Attributes.RegionCode = attr["regionCode"].(string)
-	p.Product.Attributes.Servicecode = attr["servicecode"].(string)
-	p.Product.Attributes.LicenseModel = attr["licenseModel"].(string)
-	p.Product.Attributes.DeploymentOption = attr["deploymentOption"].(string)
-	p.Product.Attributes.Location = attr["location"].(string)
-	p.Product.Attributes.Servicename = attr["servicename"].(string)
-	p.Product.Attributes.Operation = attr["operation"].(string)
+		p.Product.Attributes.ProcessorArchitecture = stringValue(attr, "processorArchitecture")
+	}
+
+	p.Product.Attributes.InstanceTypeFamily = stringValue(attr, "instanceTypeFamily")
+	p.Product.Attributes.Memory = stringValue(attr, "memory")
+	p.Product.Attributes.Vcpu = stringValue(attr, "vcpu")
+	p.Product.Attributes.InstanceType = stringValue(attr, "instanceType")
+	p.Product.Attributes.UsageType = stringValue(attr, "usagetype")
+	p.Product.Attributes.LocationType = stringValue(attr, "locationType")
+	p.Product.Attributes.Storage = stringValue(attr, "storage")
+	p.Product.Attributes.NormalizationSizeFactor = stringValue(attr, "normalizationSizeFactor")
+	p.Product.Attributes.InstanceFamily = stringValue(attr, "instanceFamily")
+	p.Product.Attributes.OSEngine = stringValue(attr, "databaseEngine")
+	p.Product.Attributes.RegionCode = stringValue(attr, "regionCode")
+	p.Product.Attributes.Servicecode = stringValue(attr, "servicecode")
+	p.Product.Attributes.LicenseModel = stringValue(attr, "licenseModel")
+	p.Product.Attributes.DeploymentOption = stringValue(attr, "deploymentOption")
+	p.Product.Attributes.Location = stringValue(attr, "location")
+	p.Product.Attributes.Servicename = stringValue(attr, "servicename")
+	p.Product.Attributes.Operation = stringValue(attr, "operation")
+	p.Product.Attributes.VolumeType = stringValue(attr, "volumeType")
+	p.Product.Attributes.Group = stringValue(attr, "group")
+
+	// Aurora storage may have the database engine Any.
+	if strings.HasPrefix(p.Product.Attributes.OSEngine, "Aurora") || strings.Contains(p.Product.Attributes.UsageType, "Aurora") {
+		p.Product.Attributes.StorageConfig = rdsStorageConfig(p.Product.Attributes.UsageType)
+	}
 
 	return p
 }
@@ -393,20 +655,20 @@ func (p *Price) addRdsAttributes(attr map[string]interface{}) *Price {
 //	  "sku": "223SCNAF37X3F5SU"
 //	}
 func (p *Price) addElasticacheAttributes(attr map[string]interface{}) *Price {
-	p.Product.Attributes.Memory = attr["memory"].(string)
-	p.Product.Attributes.Vcpu = attr["vcpu"].(string)
-	p.Product.Attributes.InstanceType = attr["instanceType"].(string)
-	p.Product.Attributes.UsageType = attr["usagetype"].(string)
-	p.Product.Attributes.LocationType = attr["locationType"].(string)
-	p.Product.Attributes.InstanceFamily = attr["instanceFamily"].(string)
-	p.Product.Attributes.OSEngine = attr["cacheEngine"].(string)
-	p.Product.Attributes.RegionCode = attr["regionCode"].(string)
-	p.Product.Attributes.Servicecode = attr["servicecode"].(string)
-	p.Product.Attributes.CurrentGeneration = attr["currentGeneration"].(string)
-	p.Product.Attributes.NetworkPerformance = attr["networkPerformance"].(string)
-	p.Product.Attributes.Location = attr["location"].(string)
-	p.Product.Attributes.Servicename = attr["servicename"].(string)
-	p.Product.Attributes.Operation = attr["operation"].(string)
+	p.Product.Attributes.Memory = stringValue(attr, "memory")
+	p.Product.Attributes.Vcpu = stringValue(attr, "vcpu")
+	p.Product.Attributes.InstanceType = stringValue(attr, "instanceType")
+	p.Product.Attributes.UsageType = stringValue(attr, "usagetype")
+	p.Product.Attributes.LocationType = stringValue(attr, "locationType")
+	p.Product.Attributes.InstanceFamily = stringValue(attr, "instanceFamily")
+	p.Product.Attributes.OSEngine = stringValue(attr, "cacheEngine")
+	p.Product.Attributes.RegionCode = stringValue(attr, "regionCode")
+	p.Product.Attributes.Servicecode = stringValue(attr, "servicecode")
+	p.Product.Attributes.CurrentGeneration = stringValue(attr, "currentGeneration")
+	p.Product.Attributes.NetworkPerformance = stringValue(attr, "networkPerformance")
+	p.Product.Attributes.Location = stringValue(attr, "location")
+	p.Product.Attributes.Servicename = stringValue(attr, "servicename")
+	p.Product.Attributes.Operation = stringValue(attr, "operation")
 
 	return p
 }
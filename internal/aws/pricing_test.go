@@ -0,0 +1,63 @@
+package aws
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestNormalizeRegions(t *testing.T) {
+	tests := []struct {
+		name    string
+		regions []string
+		want    []string
+	}{
+		{"no regions", nil, []string{AllRegions}},
+		{"a region", []string{"us-east-1"}, []string{"us-east-1"}},
+		{"regions in order", []string{"us-east-1", "ap-northeast-1"}, []string{"us-east-1", "ap-northeast-1"}},
+		{"duplicates", []string{"us-east-1", "ap-northeast-1", "us-east-1"}, []string{"us-east-1", "ap-northeast-1"}},
+		// all overrides the other regions.
+		{"all", []string{"us-east-1", AllRegions}, []string{AllRegions}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeRegions(tt.regions); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetProductsInput(t *testing.T) {
+	tests := []struct {
+		name        string
+		serviceCode string
+		region      string
+		want        map[string]string
+	}{
+		{"a region", "AmazonEC2", "us-east-1", map[string]string{"locationType": "AWS Region", "regionCode": "us-east-1"}},
+		{"all regions", "AmazonEC2", AllRegions, map[string]string{"locationType": "AWS Region"}},
+		// Data transfer is located by the source region.
+		{"data transfer", "AWSDataTransfer", "ap-northeast-1", map[string]string{"fromLocationType": "AWS Region", "fromRegionCode": "ap-northeast-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := getProductsInput(tt.serviceCode, tt.region)
+
+			if aws.ToString(in.ServiceCode) != tt.serviceCode {
+				t.Errorf("got service code %s, want %s", aws.ToString(in.ServiceCode), tt.serviceCode)
+			}
+
+			got := map[string]string{}
+			for _, f := range in.Filters {
+				got[aws.ToString(f.Field)] = aws.ToString(f.Value)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got filters %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package aws
+
+import "strings"
+
+// RDS usage kinds of the products other than the database instances.
+const (
+	RdsUsageServerlessV2 = "serverless-v2"
+	RdsUsageStorage      = "storage"
+	RdsUsageIo           = "io"
+	RdsUsageBackup       = "backup"
+)
+
+// Aurora storage configurations stored in StorageConfig.
+const (
+	RdsStorageStandard    = "Standard"
+	RdsStorageIoOptimized = "IO-Optimized"
+)
+
+// RdsProductFamilies are the product families of the RDS usage kinds.
+var RdsProductFamilies = []string{"ServerlessV2", "Database Storage", "System Operation", "Storage Snapshot"}
+
+// RdsUsage returns the usage kind of the RDS product, or an empty string if it is not stored.
+// Database instances are not a usage kind, because they are stored by vcpu and memory.
+func RdsUsage(productFamily, usageType string) string {
+	switch productFamily {
+	case "ServerlessV2":
+		if strings.Contains(usageType, "ServerlessV2") {
+			return RdsUsageServerlessV2
+		}
+	case "Database Storage":
+		return RdsUsageStorage
+	case "System Operation":
+		// Aurora I/O requests. (Exclude the provisioned IOPS of the other engines)
+		if strings.HasSuffix(usageType, "Aurora:StorageIOUsage") {
+			return RdsUsageIo
+		}
+	case "Storage Snapshot":
+		if strings.Contains(usageType, "BackupUsage") {
+			return RdsUsageBackup
+		}
+	}
+
+	return ""
+}
+
+// rdsStorageConfig returns the Aurora storage configuration of the usage type.
+// e.g. InstanceUsageIOOptimized:db.r6g.large, Aurora:IO-OptimizedStorageUsage, Aurora:ServerlessV2IOOptimizedUsage
+func rdsStorageConfig(usageType string) string {
+	if strings.Contains(strings.ReplaceAll(usageType, "-", ""), "IOOptimized") {
+		return RdsStorageIoOptimized
+	}
+
+	return RdsStorageStandard
+}
@@ -0,0 +1,53 @@
+package aws
+
+import "strings"
+
+// product example:
+//
+//	"product": {
+//	  "productFamily": "Compute Instance",
+//	  "attributes": {
+//	    "memory": "32 GiB",
+//	    "vcpu": "4",
+//	    "instanceType": "ra3.xlplus",
+//	    "usagetype": "APN1-Node:ra3.xlplus",
+//	    "locationType": "AWS Region",
+//	    "storage": "32TB RMS",
+//	    "io": "0.65 GB/s",
+//	    "ecu": "NA",
+//	    "regionCode": "ap-northeast-1",
+//	    "servicecode": "AmazonRedshift",
+//	    "currentGeneration": "Yes",
+//	    "location": "Asia Pacific (Tokyo)",
+//	    "servicename": "Amazon Redshift",
+//	    "operation": "RunComputeNode:0001"
+//	  },
+//	  "sku": "2M4PNWQSQ8FMVHQC"
+//	}
+func (p *Price) addRedshiftAttributes(attr map[string]interface{}) *Price {
+	p.Product.Attributes.Memory = stringValue(attr, "memory")
+	p.Product.Attributes.Vcpu = stringValue(attr, "vcpu")
+	p.Product.Attributes.InstanceType = stringValue(attr, "instanceType")
+	p.Product.Attributes.UsageType = stringValue(attr, "usagetype")
+	p.Product.Attributes.LocationType = stringValue(attr, "locationType")
+	p.Product.Attributes.Storage = stringValue(attr, "storage")
+	p.Product.Attributes.Ecu = stringValue(attr, "ecu")
+	p.Product.Attributes.Io = stringValue(attr, "io")
+	p.Product.Attributes.RegionCode = stringValue(attr, "regionCode")
+	p.Product.Attributes.Servicecode = stringValue(attr, "servicecode")
+	p.Product.Attributes.CurrentGeneration = stringValue(attr, "currentGeneration")
+	p.Product.Attributes.Location = stringValue(attr, "location")
+	p.Product.Attributes.Servicename = stringValue(attr, "servicename")
+	p.Product.Attributes.Operation = stringValue(attr, "operation")
+
+	// RA3 nodes use Redshift Managed Storage (RMS), and the others have local storage.
+	if strings.HasPrefix(p.Product.Attributes.InstanceType, "ra3.") || strings.Contains(p.Product.Attributes.Storage, "RMS") {
+		p.Product.Attributes.StorageMedia = "Managed"
+	} else {
+		p.Product.Attributes.StorageMedia = "Local"
+	}
+
+	p.Product.Attributes.InstanceFamily = strings.SplitN(p.Product.Attributes.InstanceType, ".", 2)[0]
+
+	return p
+}
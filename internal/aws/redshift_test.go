@@ -0,0 +1,34 @@
+package aws
+
+import "testing"
+
+func TestAddRedshiftAttributes(t *testing.T) {
+	tests := []struct {
+		name           string
+		attr           map[string]interface{}
+		storageMedia   string
+		instanceFamily string
+	}{
+		{"RA3", map[string]interface{}{"instanceType": "ra3.xlplus", "memory": "32 GiB", "storage": "32TB RMS"}, "Managed", "ra3"},
+		// RA3 nodes are managed even if the storage does not say RMS.
+		{"RA3 without RMS", map[string]interface{}{"instanceType": "ra3.4xlarge", "memory": "96 GiB", "storage": "128TB"}, "Managed", "ra3"},
+		{"dense compute", map[string]interface{}{"instanceType": "dc2.large", "memory": "15 GiB", "storage": "0.16TB SSD"}, "Local", "dc2"},
+		{"dense storage", map[string]interface{}{"instanceType": "ds2.xlarge", "memory": "31 GiB", "storage": "2TB HDD"}, "Local", "ds2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attr := (&Price{}).addRedshiftAttributes(tt.attr).Product.Attributes
+
+			if attr.Memory != tt.attr["memory"] || attr.Storage != tt.attr["storage"] {
+				t.Errorf("got memory %q and storage %q, want %q and %q", attr.Memory, attr.Storage, tt.attr["memory"], tt.attr["storage"])
+			}
+			if attr.StorageMedia != tt.storageMedia {
+				t.Errorf("got storage media %q, want %q", attr.StorageMedia, tt.storageMedia)
+			}
+			if attr.InstanceFamily != tt.instanceFamily {
+				t.Errorf("got instance family %q, want %q", attr.InstanceFamily, tt.instanceFamily)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package aws
+
+import "sort"
+
+// S3 usage kinds of the products.
+const (
+	S3UsageStorage      = "storage"
+	S3UsageRequestTier1 = "request-tier1"
+	S3UsageRequestTier2 = "request-tier2"
+	S3UsageRetrieval    = "retrieval"
+)
+
+// s3StorageClasses are the usage types (without the region prefix) of each storage class, keyed by the API name.
+// Tier 1 requests are PUT, COPY, POST and LIST, and tier 2 requests are GET and the others.
+var s3StorageClasses = map[string]map[string]string{
+	"STANDARD": {
+		S3UsageStorage:      "TimedStorage-ByteHrs",
+		S3UsageRequestTier1: "Requests-Tier1",
+		S3UsageRequestTier2: "Requests-Tier2",
+	},
+	"STANDARD_IA": {
+		S3UsageStorage:      "TimedStorage-SIA-ByteHrs",
+		S3UsageRequestTier1: "Requests-SIA-Tier1",
+		S3UsageRequestTier2: "Requests-SIA-Tier2",
+		S3UsageRetrieval:    "Retrieval-SIA",
+	},
+	"ONEZONE_IA": {
+		S3UsageStorage:      "TimedStorage-ZIA-ByteHrs",
+		S3UsageRequestTier1: "Requests-ZIA-Tier1",
+		S3UsageRequestTier2: "Requests-ZIA-Tier2",
+		S3UsageRetrieval:    "Retrieval-ZIA",
+	},
+	"INTELLIGENT_TIERING": {
+		S3UsageStorage:      "TimedStorage-INT-FA-ByteHrs",
+		S3UsageRequestTier1: "Requests-INT-Tier1",
+		S3UsageRequestTier2: "Requests-INT-Tier2",
+	},
+	"GLACIER_IR": {
+		S3UsageStorage:      "TimedStorage-GIR-ByteHrs",
+		S3UsageRequestTier1: "Requests-GIR-Tier1",
+		S3UsageRequestTier2: "Requests-GIR-Tier2",
+		S3UsageRetrieval:    "Retrieval-GIR",
+	},
+	"GLACIER": {
+		S3UsageStorage:      "TimedStorage-GlacierByteHrs",
+		S3UsageRequestTier1: "Requests-GLACIER-Tier1",
+		S3UsageRequestTier2: "Requests-GLACIER-Tier2",
+	},
+	"DEEP_ARCHIVE": {
+		S3UsageStorage:      "TimedStorage-GDA-ByteHrs",
+		S3UsageRequestTier1: "Requests-GDA-Tier1",
+		S3UsageRequestTier2: "Requests-GDA-Tier2",
+	},
+}
+
+type s3Usage struct {
+	storageClass string
+	usage        string
+}
+
+// s3UsageTypes is the reverse index of s3StorageClasses keyed by the usage type.
+var s3UsageTypes = map[string]s3Usage{}
+
+func init() {
+	for class, usageTypes := range s3StorageClasses {
+		for usage, usageType := range usageTypes {
+			s3UsageTypes[usageType] = s3Usage{storageClass: class, usage: usage}
+		}
+	}
+}
+
+// S3StorageClasses returns the API names of the storage classes stored in the price list.
+func S3StorageClasses() []string {
+	var classes []string
+	for class := range s3StorageClasses {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+
+	return classes
+}
+
+// S3Usage returns the usage kind of the usage type, or an empty string if it is not stored.
+func S3Usage(usageType string) string {
+	return s3UsageTypes[trimRegionPrefix(usageType)].usage
+}
+
+// product example:
+//
+//	"product": {
+//	  "productFamily": "Storage",
+//	  "attributes": {
+//	    "durability": "99.999999999%",
+//	    "usagetype": "APN1-TimedStorage-SIA-ByteHrs",
+//	    "locationType": "AWS Region",
+//	    "availability": "99.9%",
+//	    "storageClass": "Infrequent Access",
+//	    "regionCode": "ap-northeast-1",
+//	    "servicecode": "AmazonS3",
+//	    "volumeType": "Standard - Infrequent Access",
+//	    "location": "Asia Pacific (Tokyo)",
+//	    "servicename": "Amazon Simple Storage Service",
+//	    "operation": ""
+//	  },
+//	  "sku": "3PQ7MGSWTUC7X8AN"
+//	}
+func (p *Price) addS3Attributes(attr map[string]interface{}) *Price {
+	// StorageClass is the API name (e.g. STANDARD_IA) instead of the description in the price list.
+	p.Product.Attributes.StorageClass = s3UsageTypes[trimRegionPrefix(stringValue(attr, "usagetype"))].storageClass
+	p.Product.Attributes.VolumeType = stringValue(attr, "volumeType")
+	p.Product.Attributes.Group = stringValue(attr, "group")
+	p.Product.Attributes.GroupDescription = stringValue(attr, "groupDescription")
+	p.Product.Attributes.UsageType = stringValue(attr, "usagetype")
+	p.Product.Attributes.LocationType = stringValue(attr, "locationType")
+	p.Product.Attributes.RegionCode = stringValue(attr, "regionCode")
+	p.Product.Attributes.Servicecode = stringValue(attr, "servicecode")
+	p.Product.Attributes.Location = stringValue(attr, "location")
+	p.Product.Attributes.Servicename = stringValue(attr, "servicename")
+	p.Product.Attributes.Operation = stringValue(attr, "operation")
+
+	return p
+}
@@ -0,0 +1,65 @@
+package aws
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestParsePriceS3(t *testing.T) {
+	price, err := parsePrice("AmazonS3", loadFixture(t, "s3_standard_us_east_1.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if price.Product.Attributes.StorageClass != "STANDARD" {
+		t.Errorf("got storage class %q, want STANDARD", price.Product.Attributes.StorageClass)
+	}
+
+	want := []PriceTier{
+		{BeginRange: 0, EndRange: 51200, Unit: "GB-Mo", PricePerUnitUSD: 0.023},
+		{BeginRange: 51200, EndRange: 512000, Unit: "GB-Mo", PricePerUnitUSD: 0.022},
+		{BeginRange: 512000, EndRange: 0, Unit: "GB-Mo", PricePerUnitUSD: 0.021},
+	}
+	if !reflect.DeepEqual(price.OnDemandTiers, want) {
+		t.Fatalf("got tiers %+v, want %+v", price.OnDemandTiers, want)
+	}
+
+	tests := []struct {
+		gb   float64
+		want float64
+	}{
+		{100, 2.3},
+		{51200, 1177.6},
+		// 50 TB at 0.023 and 50 TB at 0.022
+		{102400, 1177.6 + 1126.4},
+		// 50 TB at 0.023, 450 TB at 0.022 and 100 TB at 0.021
+		{614400, 1177.6 + 10137.6 + 2150.4},
+	}
+
+	for _, tt := range tests {
+		if got := TieredCostUSD(price.OnDemandTiers, tt.gb); math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("TieredCostUSD(%v GB) = %v, want %v", tt.gb, got, tt.want)
+		}
+	}
+}
+
+func TestS3Usage(t *testing.T) {
+	tests := []struct {
+		usageType string
+		want      string
+	}{
+		{"TimedStorage-ByteHrs", S3UsageStorage},
+		{"APN1-TimedStorage-SIA-ByteHrs", S3UsageStorage},
+		{"USW2-Requests-GIR-Tier1", S3UsageRequestTier1},
+		{"EU-Retrieval-ZIA", S3UsageRetrieval},
+		{"TimedStorage-RRS-ByteHrs", ""},
+		{"APN1-DataTransfer-Out-Bytes", ""},
+	}
+
+	for _, tt := range tests {
+		if got := S3Usage(tt.usageType); got != tt.want {
+			t.Errorf("S3Usage(%q) = %q, want %q", tt.usageType, got, tt.want)
+		}
+	}
+}
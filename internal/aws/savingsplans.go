@@ -0,0 +1,177 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/savingsplans"
+	"github.com/aws/aws-sdk-go-v2/service/savingsplans/types"
+)
+
+// SavingsPlansServiceCode is the service code of the Savings Plans in the price list.
+const SavingsPlansServiceCode = "ComputeSavingsPlans"
+
+// Savings Plans types stored in PlanType.
+const (
+	SavingsPlanTypeCompute     = "Compute"
+	SavingsPlanTypeEc2Instance = "EC2Instance"
+)
+
+// savingsPlansEndpointRegion is the region of the Savings Plans API, which is a global service.
+const savingsPlansEndpointRegion = "us-east-1"
+
+// SavingsPlanRate is the rate of a usage type under a Savings Plans offering.
+// It is joined to the products by the region, the usage type and the operation.
+type SavingsPlanRate struct {
+	Region              string
+	ServiceCode         string
+	ProductType         string
+	UsageType           string
+	Operation           string
+	PlanType            string
+	LeaseContractLength string
+	PaymentOption       string
+	RateUSD             float64
+	Unit                string
+	InstanceType        string
+	ProductDescription  string
+	Tenancy             string
+	OfferingId          string
+}
+
+// leaseContractLengths are the lease contract lengths keyed by the duration of the offering in seconds.
+var leaseContractLengths = map[int64]string{
+	31536000: "1yr",
+	94608000: "3yr",
+}
+
+// GetSavingsPlanRates fetches the Savings Plans rates of EC2, Fargate and Lambda for each of the given region codes,
+// and passes the rates in USD to handle page by page.
+// If regions is empty or contains AllRegions, rates for every AWS Region are fetched.
+func GetSavingsPlanRates(cfg aws.Config, regions []string, handle func([]*SavingsPlanRate) error) error {
+	client := savingsplans.NewFromConfig(cfg, func(o *savingsplans.Options) {
+		o.Region = savingsPlansEndpointRegion
+	})
+
+	for _, region := range normalizeRegions(regions) {
+		if region == AllRegions {
+			log.Println("Fetching Savings Plans rates for all regions from Savings Plans API")
+		} else {
+			log.Printf("Fetching Savings Plans rates for %s from Savings Plans API\n", region)
+		}
+
+		input := getSavingsPlanRatesInput(region)
+
+		for {
+			output, err := client.DescribeSavingsPlansOfferingRates(context.Background(), input)
+			if err != nil {
+				return fmt.Errorf("Failed to describe Savings Plans rates: %w", err)
+			}
+
+			var rates []*SavingsPlanRate
+			for _, r := range output.SearchResults {
+				rate, err := parseSavingsPlanRate(r)
+				if err != nil {
+					// A single broken rate should not abort the whole fetch.
+					log.Printf("Skip Savings Plans rate of %s: %v\n", aws.ToString(r.UsageType), err)
+					continue
+				}
+
+				if rate != nil {
+					rates = append(rates, rate)
+				}
+			}
+
+			if err := handle(rates); err != nil {
+				return err
+			}
+
+			if aws.ToString(output.NextToken) == "" {
+				break
+			}
+			input.NextToken = output.NextToken
+		}
+	}
+
+	return nil
+}
+
+func getSavingsPlanRatesInput(region string) *savingsplans.DescribeSavingsPlansOfferingRatesInput {
+	input := &savingsplans.DescribeSavingsPlansOfferingRatesInput{
+		MaxResults: 1000,
+		Products: []types.SavingsPlanProductType{
+			types.SavingsPlanProductTypeEc2,
+			types.SavingsPlanProductTypeFargate,
+			types.SavingsPlanProductTypeLambda,
+		},
+		SavingsPlanTypes: []types.SavingsPlanType{
+			types.SavingsPlanTypeCompute,
+			types.SavingsPlanTypeEc2Instance,
+		},
+		// Fargate on EKS is not stored.
+		ServiceCodes: []types.SavingsPlanRateServiceCode{
+			types.SavingsPlanRateServiceCodeEc2,
+			types.SavingsPlanRateServiceCodeFargate,
+			types.SavingsPlanRateServiceCodeLambda,
+		},
+	}
+
+	if region != AllRegions {
+		input.Filters = []types.SavingsPlanOfferingRateFilterElement{
+			{Name: types.SavingsPlanRateFilterAttributeRegion, Values: []string{region}},
+		}
+	}
+
+	return input
+}
+
+// parseSavingsPlanRate returns nil without error if the rate is not in USD.
+func parseSavingsPlanRate(r types.SavingsPlanOfferingRate) (*SavingsPlanRate, error) {
+	offering := r.SavingsPlanOffering
+	if offering == nil {
+		return nil, fmt.Errorf("offering is missing")
+	}
+
+	if offering.Currency != types.CurrencyCodeUsd {
+		return nil, nil
+	}
+
+	lease, ok := leaseContractLengths[offering.DurationSeconds]
+	if !ok {
+		return nil, fmt.Errorf("Unknown duration: %d seconds", offering.DurationSeconds)
+	}
+
+	rate, err := strconv.ParseFloat(aws.ToString(r.Rate), 64)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid rate: %w", err)
+	}
+
+	properties := map[string]string{}
+	for _, p := range r.Properties {
+		properties[aws.ToString(p.Name)] = aws.ToString(p.Value)
+	}
+
+	if properties["region"] == "" {
+		return nil, fmt.Errorf("region is missing")
+	}
+
+	return &SavingsPlanRate{
+		Region:              properties["region"],
+		ServiceCode:         string(r.ServiceCode),
+		ProductType:         string(r.ProductType),
+		UsageType:           aws.ToString(r.UsageType),
+		Operation:           aws.ToString(r.Operation),
+		PlanType:            string(offering.PlanType),
+		LeaseContractLength: lease,
+		PaymentOption:       string(offering.PaymentOption),
+		RateUSD:             rate,
+		Unit:                string(r.Unit),
+		InstanceType:        properties["instanceType"],
+		ProductDescription:  properties["productDescription"],
+		Tenancy:             properties["tenancy"],
+		OfferingId:          aws.ToString(offering.OfferingId),
+	}, nil
+}
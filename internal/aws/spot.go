@@ -0,0 +1,158 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// SpotPrice is a Spot price history record of an instance type in an Availability Zone.
+type SpotPrice struct {
+	Timestamp time.Time
+	Meta      SpotMeta
+	PriceUSD  float64
+}
+
+// SpotMeta is the series of the Spot price.
+type SpotMeta struct {
+	Region             string
+	AvailabilityZone   string
+	InstanceType       string
+	ProductDescription string
+}
+
+// SpotPriceHistoryInput is the condition of the Spot price history.
+// Empty InstanceTypes, ProductDescriptions and AvailabilityZones match all.
+type SpotPriceHistoryInput struct {
+	StartTime           time.Time
+	EndTime             time.Time
+	InstanceTypes       []string
+	ProductDescriptions []string
+	AvailabilityZones   []string
+}
+
+// spotProductDescriptions are the product descriptions of the Spot prices keyed by the operating system of the price list.
+var spotProductDescriptions = map[string]string{
+	"Linux":   "Linux/UNIX",
+	"RHEL":    "Red Hat Enterprise Linux",
+	"SUSE":    "SUSE Linux",
+	"Windows": "Windows",
+}
+
+// SpotProductDescription returns the product description of the Spot prices for the operating system of the price list.
+func SpotProductDescription(osEngine string) (string, error) {
+	d, ok := spotProductDescriptions[osEngine]
+	if !ok {
+		return "", fmt.Errorf("Spot prices are not available for OS: %s", osEngine)
+	}
+
+	return d, nil
+}
+
+// SpotPriceHistoryAPIClient is the client of DescribeSpotPriceHistory. It can be replaced with a stub.
+type SpotPriceHistoryAPIClient interface {
+	DescribeSpotPriceHistory(ctx context.Context, params *ec2.DescribeSpotPriceHistoryInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSpotPriceHistoryOutput, error)
+}
+
+// NewSpotClient returns the EC2 client of the region to get the Spot price history.
+func NewSpotClient(cfg aws.Config, region string) *ec2.Client {
+	return ec2.NewFromConfig(cfg, func(o *ec2.Options) {
+		o.Region = region
+	})
+}
+
+// SpotRegions returns the region codes enabled for the account.
+func SpotRegions(cfg aws.Config) ([]string, error) {
+	output, err := ec2.NewFromConfig(cfg).DescribeRegions(context.Background(), &ec2.DescribeRegionsInput{})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to describe regions: %w", err)
+	}
+
+	var regions []string
+	for _, r := range output.Regions {
+		regions = append(regions, aws.ToString(r.RegionName))
+	}
+
+	return regions, nil
+}
+
+// GetSpotPriceHistory gets the Spot price history of the region, and passes the records to handle page by page.
+func GetSpotPriceHistory(ctx context.Context, client SpotPriceHistoryAPIClient, region string, input *SpotPriceHistoryInput, handle func([]*SpotPrice) error) error {
+	log.Printf("Fetching Spot price history for %s since %s\n", region, input.StartTime.UTC().Format(time.RFC3339))
+
+	paginator := ec2.NewDescribeSpotPriceHistoryPaginator(client, getSpotPriceHistoryInput(input))
+
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("Failed to describe Spot price history: %w", err)
+		}
+
+		var prices []*SpotPrice
+		for _, h := range output.SpotPriceHistory {
+			p, err := parseSpotPrice(region, h)
+			if err != nil {
+				// A single broken record should not abort the whole fetch.
+				log.Printf("Skip Spot price of %s: %v\n", aws.ToString(h.AvailabilityZone), err)
+				continue
+			}
+
+			prices = append(prices, p)
+		}
+
+		if err := handle(prices); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func getSpotPriceHistoryInput(input *SpotPriceHistoryInput) *ec2.DescribeSpotPriceHistoryInput {
+	i := &ec2.DescribeSpotPriceHistoryInput{
+		StartTime:           aws.Time(input.StartTime),
+		ProductDescriptions: input.ProductDescriptions,
+	}
+
+	if !input.EndTime.IsZero() {
+		i.EndTime = aws.Time(input.EndTime)
+	}
+
+	for _, t := range input.InstanceTypes {
+		i.InstanceTypes = append(i.InstanceTypes, types.InstanceType(t))
+	}
+
+	if len(input.AvailabilityZones) > 0 {
+		i.Filters = []types.Filter{{Name: aws.String("availability-zone"), Values: input.AvailabilityZones}}
+	}
+
+	return i
+}
+
+func parseSpotPrice(region string, h types.SpotPrice) (*SpotPrice, error) {
+	if h.Timestamp == nil {
+		return nil, fmt.Errorf("timestamp is missing")
+	}
+
+	price, err := strconv.ParseFloat(aws.ToString(h.SpotPrice), 64)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid Spot price: %w", err)
+	}
+
+	return &SpotPrice{
+		Timestamp: h.Timestamp.UTC(),
+		Meta: SpotMeta{
+			Region:             region,
+			AvailabilityZone:   aws.ToString(h.AvailabilityZone),
+			InstanceType:       string(h.InstanceType),
+			ProductDescription: string(h.ProductDescription),
+		},
+		PriceUSD: price,
+	}, nil
+}
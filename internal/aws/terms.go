@@ -0,0 +1,311 @@
+package aws
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// PriceDimension is a rate of an offer term.
+// Tiered rates have a dimension per range (e.g. beginRange "0", endRange "10240").
+type PriceDimension struct {
+	OfferTermCode string
+	EffectiveDate string
+	RateCode      string
+	Unit          string
+	Description   string
+	BeginRange    string
+	EndRange      string
+	Currency      string
+	PricePerUnit  string
+}
+
+// ReservedTerm is a Reserved Instance offer of a product.
+type ReservedTerm struct {
+	OfferTermCode       string
+	EffectiveDate       string
+	LeaseContractLength string
+	PurchaseOption      string
+	OfferingClass       string
+	UpfrontFeeUSD       string
+	HourlyPriceUSD      string
+}
+
+// terms example:
+//
+//	"OnDemand": {
+//	  "2223B6PCG6QAUYY6.JRTCKXETXF": {
+//	    "priceDimensions": {
+//	      "2223B6PCG6QAUYY6.JRTCKXETXF.6YS6EN2CT7": {
+//	        "unit": "Hrs",
+//	        "endRange": "Inf",
+//	        "description": "$0.248 per Dedicated Windows with SQL Web c6i.large Instance Hour",
+//	        "appliesTo": [],
+//	        "rateCode": "2223B6PCG6QAUYY6.JRTCKXETXF.6YS6EN2CT7",
+//	        "beginRange": "0",
+//	        "pricePerUnit": {"USD": "0.2480000000"}
+//	      }
+//	    },
+//	    "sku": "2223B6PCG6QAUYY6",
+//	    "effectiveDate": "2023-06-01T00:00:00Z",
+//	    "offerTermCode": "JRTCKXETXF",
+//	    "termAttributes": {}
+//	  }
+//	}
+func parseTerms(terms map[string]interface{}) ([]PriceDimension, error) {
+	var dimensions []PriceDimension
+
+	for code, t := range terms {
+		term, ok := t.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("term %s is not an object", code)
+		}
+
+		d, err := parseTermDimensions(term)
+		if err != nil {
+			return nil, fmt.Errorf("term %s: %w", code, err)
+		}
+
+		dimensions = append(dimensions, d...)
+	}
+
+	sort.Slice(dimensions, func(i, j int) bool {
+		if dimensions[i].RateCode != dimensions[j].RateCode {
+			return dimensions[i].RateCode < dimensions[j].RateCode
+		}
+		return dimensions[i].Currency < dimensions[j].Currency
+	})
+
+	return dimensions, nil
+}
+
+// parseTermDimensions returns a dimension per rate code and currency of the term.
+func parseTermDimensions(term map[string]interface{}) ([]PriceDimension, error) {
+	priceDimensions, ok := term["priceDimensions"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("priceDimensions is missing")
+	}
+
+	var dimensions []PriceDimension
+
+	for rateCode, d := range priceDimensions {
+		dimension, ok := d.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("price dimension %s is not an object", rateCode)
+		}
+
+		pricePerUnit, ok := dimension["pricePerUnit"].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("pricePerUnit of %s is missing", rateCode)
+		}
+
+		for currency, v := range pricePerUnit {
+			price, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("pricePerUnit %s of %s is not a string", currency, rateCode)
+			}
+
+			dimensions = append(dimensions, PriceDimension{
+				OfferTermCode: stringValue(term, "offerTermCode"),
+				EffectiveDate: stringValue(term, "effectiveDate"),
+				RateCode:      rateCode,
+				Unit:          stringValue(dimension, "unit"),
+				Description:   stringValue(dimension, "description"),
+				BeginRange:    stringValue(dimension, "beginRange"),
+				EndRange:      stringValue(dimension, "endRange"),
+				Currency:      currency,
+				PricePerUnit:  price,
+			})
+		}
+	}
+
+	return dimensions, nil
+}
+
+// hourlyPricePerUSD returns the hourly price in USD.
+// If there is no hourly dimension, the price of the first tier in USD is returned.
+func hourlyPricePerUSD(dimensions []PriceDimension) string {
+	var price string
+	for _, d := range dimensions {
+		if d.Currency != "USD" {
+			continue
+		}
+
+		if d.Unit == "Hrs" {
+			return d.PricePerUnit
+		}
+
+		if price == "" || d.BeginRange == "0" {
+			price = d.PricePerUnit
+		}
+	}
+
+	return price
+}
+
+// PriceTier is a typed price band of the tiered dimensions in USD (e.g. the first 50 TB of S3 storage).
+// EndRange is 0 if the tier has no limit.
+type PriceTier struct {
+	BeginRange      float64
+	EndRange        float64
+	Unit            string
+	PricePerUnitUSD float64
+}
+
+// ParseTiers returns the tiers of the dimensions in USD, sorted by the begin range.
+func ParseTiers(dimensions []PriceDimension) ([]PriceTier, error) {
+	var tiers []PriceTier
+	for _, d := range dimensions {
+		if d.Currency != "USD" {
+			continue
+		}
+
+		price, err := strconv.ParseFloat(d.PricePerUnit, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid price %q of %s: %w", d.PricePerUnit, d.RateCode, err)
+		}
+
+		begin, err := parseRange(d.BeginRange)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid begin range of %s: %w", d.RateCode, err)
+		}
+
+		end, err := parseRange(d.EndRange)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid end range of %s: %w", d.RateCode, err)
+		}
+
+		tiers = append(tiers, PriceTier{
+			BeginRange:      begin,
+			EndRange:        end,
+			Unit:            d.Unit,
+			PricePerUnitUSD: price,
+		})
+	}
+
+	sort.Slice(tiers, func(i, j int) bool {
+		return tiers[i].BeginRange < tiers[j].BeginRange
+	})
+
+	return tiers, nil
+}
+
+// TieredCostUSD returns the cost in USD of the quantity charged by the tiers.
+// Each tier charges the quantity between its begin range and end range.
+func TieredCostUSD(tiers []PriceTier, quantity float64) float64 {
+	var cost float64
+	for _, t := range tiers {
+		if quantity <= t.BeginRange {
+			continue
+		}
+
+		charged := quantity
+		if t.EndRange > 0 && t.EndRange < quantity {
+			charged = t.EndRange
+		}
+
+		cost += (charged - t.BeginRange) * t.PricePerUnitUSD
+	}
+
+	return cost
+}
+
+// parseRange parses beginRange or endRange, and returns 0 if the range is empty or Inf.
+func parseRange(r string) (float64, error) {
+	if r == "" || r == "Inf" {
+		return 0, nil
+	}
+
+	return strconv.ParseFloat(r, 64)
+}
+
+// terms example:
+//
+//	"Reserved": {
+//	  "22GTWH3M7MMRFZQ9.4NA7Y494T4": {
+//	    "priceDimensions": {
+//	      "22GTWH3M7MMRFZQ9.4NA7Y494T4.2TG2D8R56U": {
+//	        "unit": "Quantity",
+//	        "description": "Upfront Fee",
+//	        "pricePerUnit": {"USD": "9462"}
+//	      },
+//	      "22GTWH3M7MMRFZQ9.4NA7Y494T4.6YS6EN2CT7": {
+//	        "unit": "Hrs",
+//	        "description": "USD 1.08 per hour for db.m5d.8xlarge",
+//	        "pricePerUnit": {"USD": "1.0800000000"}
+//	      }
+//	    },
+//	    "sku": "22GTWH3M7MMRFZQ9",
+//	    "effectiveDate": "2023-05-01T00:00:00Z",
+//	    "offerTermCode": "4NA7Y494T4",
+//	    "termAttributes": {
+//	      "LeaseContractLength": "1yr",
+//	      "OfferingClass": "standard",
+//	      "PurchaseOption": "Partial Upfront"
+//	    }
+//	  }
+//	}
+func parseReservedTerms(terms map[string]interface{}) ([]ReservedTerm, error) {
+	var reserved []ReservedTerm
+
+	for code, t := range terms {
+		term, ok := t.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("term %s is not an object", code)
+		}
+
+		dimensions, err := parseTermDimensions(term)
+		if err != nil {
+			return nil, fmt.Errorf("term %s: %w", code, err)
+		}
+
+		r := ReservedTerm{
+			OfferTermCode: stringValue(term, "offerTermCode"),
+			EffectiveDate: stringValue(term, "effectiveDate"),
+			// Upfront fee and hourly price are 0 if the offer does not have the dimension.
+			UpfrontFeeUSD:  "0",
+			HourlyPriceUSD: "0",
+		}
+
+		if attrs, ok := term["termAttributes"].(map[string]interface{}); ok {
+			r.LeaseContractLength = stringValue(attrs, "LeaseContractLength")
+			r.PurchaseOption = stringValue(attrs, "PurchaseOption")
+			r.OfferingClass = stringValue(attrs, "OfferingClass")
+		}
+
+		for _, d := range dimensions {
+			if d.Currency != "USD" {
+				continue
+			}
+
+			switch d.Unit {
+			case "Quantity":
+				r.UpfrontFeeUSD = d.PricePerUnit
+			case "Hrs":
+				r.HourlyPriceUSD = d.PricePerUnit
+			}
+		}
+
+		reserved = append(reserved, r)
+	}
+
+	sort.Slice(reserved, func(i, j int) bool {
+		if reserved[i].LeaseContractLength != reserved[j].LeaseContractLength {
+			return reserved[i].LeaseContractLength < reserved[j].LeaseContractLength
+		}
+		if reserved[i].OfferingClass != reserved[j].OfferingClass {
+			return reserved[i].OfferingClass < reserved[j].OfferingClass
+		}
+		return reserved[i].PurchaseOption < reserved[j].PurchaseOption
+	})
+
+	return reserved, nil
+}
+
+func stringValue(m map[string]interface{}, key string) string {
+	s, ok := m[key].(string)
+	if !ok {
+		return ""
+	}
+	return s
+}
@@ -0,0 +1,344 @@
+package aws
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// loadFixture returns a product of the Price List API in testdata.
+func loadFixture(t *testing.T, name string) map[string]interface{} {
+	t.Helper()
+
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var p map[string]interface{}
+	if err := json.Unmarshal(b, &p); err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func fixtureTerms(t *testing.T, name, termType string) map[string]interface{} {
+	t.Helper()
+
+	terms, ok := loadFixture(t, name)["terms"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("terms are missing in %s", name)
+	}
+
+	term, ok := terms[termType].(map[string]interface{})
+	if !ok {
+		t.Fatalf("%s terms are missing in %s", termType, name)
+	}
+
+	return term
+}
+
+func TestParseReservedTerms(t *testing.T) {
+	reserved, err := parseReservedTerms(fixtureTerms(t, "ec2_m5_large_us_east_1.json", "Reserved"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Sorted by the lease contract length, the offering class and the purchase option.
+	want := []ReservedTerm{
+		{OfferTermCode: "7NE97W5U4E", LeaseContractLength: "1yr", OfferingClass: "convertible", PurchaseOption: "No Upfront", UpfrontFeeUSD: "0", HourlyPriceUSD: "0.0700000000"},
+		{OfferTermCode: "6QCMYABX3D", LeaseContractLength: "1yr", OfferingClass: "standard", PurchaseOption: "All Upfront", UpfrontFeeUSD: "480", HourlyPriceUSD: "0.0000000000"},
+		{OfferTermCode: "4NA7Y494T4", LeaseContractLength: "1yr", OfferingClass: "standard", PurchaseOption: "No Upfront", UpfrontFeeUSD: "0", HourlyPriceUSD: "0.0600000000"},
+		{OfferTermCode: "HU7G6KETJZ", LeaseContractLength: "1yr", OfferingClass: "standard", PurchaseOption: "Partial Upfront", UpfrontFeeUSD: "245", HourlyPriceUSD: "0.0280000000"},
+		{OfferTermCode: "NQ3QZPMQV9", LeaseContractLength: "3yr", OfferingClass: "standard", PurchaseOption: "All Upfront", UpfrontFeeUSD: "1003", HourlyPriceUSD: "0.0000000000"},
+		{OfferTermCode: "BPH4J8HBKS", LeaseContractLength: "3yr", OfferingClass: "standard", PurchaseOption: "No Upfront", UpfrontFeeUSD: "0", HourlyPriceUSD: "0.0410000000"},
+		{OfferTermCode: "38NPMPTW36", LeaseContractLength: "3yr", OfferingClass: "standard", PurchaseOption: "Partial Upfront", UpfrontFeeUSD: "532", HourlyPriceUSD: "0.0200000000"},
+	}
+
+	if len(reserved) != len(want) {
+		t.Fatalf("got %d terms, want %d", len(reserved), len(want))
+	}
+
+	for i, w := range want {
+		got := reserved[i]
+		got.EffectiveDate = ""
+		if got != w {
+			t.Errorf("terms[%d] = %+v, want %+v", i, got, w)
+		}
+	}
+}
+
+func TestParseReservedTermsError(t *testing.T) {
+	tests := []struct {
+		name  string
+		terms map[string]interface{}
+	}{
+		{"term is not an object", map[string]interface{}{"A.B": "x"}},
+		{"priceDimensions is missing", map[string]interface{}{"A.B": map[string]interface{}{}}},
+		{"pricePerUnit is missing", map[string]interface{}{"A.B": map[string]interface{}{
+			"priceDimensions": map[string]interface{}{"A.B.C": map[string]interface{}{"unit": "Hrs"}},
+		}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseReservedTerms(tt.terms); err == nil {
+				t.Error("got no error")
+			}
+		})
+	}
+}
+
+func TestParseTerms(t *testing.T) {
+	dimensions, err := parseTerms(fixtureTerms(t, "ec2_m5_large_us_east_1.json", "OnDemand"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := PriceDimension{
+		OfferTermCode: "JRTCKXETXF",
+		EffectiveDate: "2023-06-01T00:00:00Z",
+		RateCode:      "8VCNEHQMSCQS4P39.JRTCKXETXF.6YS6EN2CT7",
+		Unit:          "Hrs",
+		Description:   "$0.096 per On Demand Linux m5.large Instance Hour",
+		BeginRange:    "0",
+		EndRange:      "Inf",
+		Currency:      "USD",
+		PricePerUnit:  "0.0960000000",
+	}
+
+	if len(dimensions) != 1 || dimensions[0] != want {
+		t.Errorf("got %+v, want [%+v]", dimensions, want)
+	}
+}
+
+// dimensionTerm returns an OnDemand term of the dimensions keyed by the rate code.
+func dimensionTerm(dimensions map[string]map[string]interface{}) map[string]interface{} {
+	d := map[string]interface{}{}
+	for code, dimension := range dimensions {
+		d[code] = dimension
+	}
+
+	return map[string]interface{}{
+		"A.JRTCKXETXF": map[string]interface{}{
+			"priceDimensions": d,
+			"offerTermCode":   "JRTCKXETXF",
+			"effectiveDate":   "2023-06-01T00:00:00Z",
+		},
+	}
+}
+
+func TestParseTermsCurrencies(t *testing.T) {
+	dimensions, err := parseTerms(dimensionTerm(map[string]map[string]interface{}{
+		"A.JRTCKXETXF.B": {"unit": "Hrs", "pricePerUnit": map[string]interface{}{"USD": "0.1", "CNY": "0.7"}},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A dimension per currency, sorted by the currency.
+	if len(dimensions) != 2 || dimensions[0].Currency != "CNY" || dimensions[1].Currency != "USD" {
+		t.Errorf("got %+v", dimensions)
+	}
+}
+
+func TestParseTermsError(t *testing.T) {
+	tests := []struct {
+		name  string
+		terms map[string]interface{}
+	}{
+		{"term is not an object", map[string]interface{}{"A.B": nil}},
+		{"priceDimensions is missing", map[string]interface{}{"A.B": map[string]interface{}{"offerTermCode": "B"}}},
+		{"dimension is not an object", dimensionTerm(map[string]map[string]interface{}{"A.B.C": nil})},
+		{"price is not a string", dimensionTerm(map[string]map[string]interface{}{
+			"A.B.C": {"pricePerUnit": map[string]interface{}{"USD": 0.1}},
+		})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseTerms(tt.terms); err == nil {
+				t.Error("got no error")
+			}
+		})
+	}
+}
+
+func TestHourlyPricePerUSD(t *testing.T) {
+	tests := []struct {
+		name       string
+		dimensions []PriceDimension
+		want       string
+	}{
+		{
+			name:       "hourly",
+			dimensions: []PriceDimension{{Unit: "Hrs", Currency: "USD", PricePerUnit: "0.0960000000"}},
+			want:       "0.0960000000",
+		},
+		{
+			name: "first tier",
+			dimensions: []PriceDimension{
+				{Unit: "GB-Mo", BeginRange: "51200", EndRange: "512000", Currency: "USD", PricePerUnit: "0.0220000000"},
+				{Unit: "GB-Mo", BeginRange: "0", EndRange: "51200", Currency: "USD", PricePerUnit: "0.0230000000"},
+			},
+			want: "0.0230000000",
+		},
+		{
+			name: "other currencies",
+			dimensions: []PriceDimension{
+				{Unit: "Hrs", Currency: "CNY", PricePerUnit: "0.7"},
+				{Unit: "Hrs", Currency: "USD", PricePerUnit: "0.1"},
+			},
+			want: "0.1",
+		},
+		{
+			name:       "no USD",
+			dimensions: []PriceDimension{{Unit: "Hrs", Currency: "CNY", PricePerUnit: "0.7"}},
+			want:       "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hourlyPricePerUSD(tt.dimensions); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTiers(t *testing.T) {
+	dimensions, err := parseTerms(fixtureTerms(t, "lambda_duration_us_east_1.json", "OnDemand"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		dimensions []PriceDimension
+		want       []PriceTier
+	}{
+		{
+			name:       "flat",
+			dimensions: []PriceDimension{{Unit: "Hrs", BeginRange: "0", EndRange: "Inf", Currency: "USD", PricePerUnit: "0.0960000000"}},
+			want:       []PriceTier{{BeginRange: 0, EndRange: 0, Unit: "Hrs", PricePerUnitUSD: 0.096}},
+		},
+		{
+			name:       "sorted by the begin range",
+			dimensions: dimensions,
+			want: []PriceTier{
+				{BeginRange: 0, EndRange: 6e9, Unit: "Lambda-GB-Second", PricePerUnitUSD: 0.0000166667},
+				{BeginRange: 6e9, EndRange: 15e9, Unit: "Lambda-GB-Second", PricePerUnitUSD: 0.000015},
+				{BeginRange: 15e9, EndRange: 0, Unit: "Lambda-GB-Second", PricePerUnitUSD: 0.0000133334},
+			},
+		},
+		{
+			name: "other currencies",
+			dimensions: []PriceDimension{
+				{Unit: "Hrs", Currency: "CNY", PricePerUnit: "N/A"},
+				{Unit: "Hrs", Currency: "USD", PricePerUnit: "0.1"},
+			},
+			want: []PriceTier{{Unit: "Hrs", PricePerUnitUSD: 0.1}},
+		},
+		{
+			name:       "empty",
+			dimensions: nil,
+			want:       nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTiers(tt.dimensions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTiersError(t *testing.T) {
+	tests := []struct {
+		name      string
+		dimension PriceDimension
+	}{
+		{"invalid price", PriceDimension{Currency: "USD", PricePerUnit: "N/A"}},
+		{"invalid begin range", PriceDimension{Currency: "USD", PricePerUnit: "0.1", BeginRange: "first"}},
+		{"invalid end range", PriceDimension{Currency: "USD", PricePerUnit: "0.1", EndRange: "-"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseTiers([]PriceDimension{tt.dimension}); err == nil {
+				t.Error("got no error")
+			}
+		})
+	}
+}
+
+func TestTieredCostUSD(t *testing.T) {
+	lambda := []PriceTier{
+		{BeginRange: 0, EndRange: 6e9, PricePerUnitUSD: 0.0000166667},
+		{BeginRange: 6e9, EndRange: 15e9, PricePerUnitUSD: 0.000015},
+		{BeginRange: 15e9, EndRange: 0, PricePerUnitUSD: 0.0000133334},
+	}
+
+	tests := []struct {
+		name     string
+		tiers    []PriceTier
+		quantity float64
+		want     float64
+	}{
+		{"zero", lambda, 0, 0},
+		{"within the first tier", lambda, 1e6, 16.6667},
+		{"at the boundary", lambda, 6e9, 100000.2},
+		{"across the boundary", lambda, 7e9, 100000.2 + 15000},
+		{"beyond the last boundary", lambda, 16e9, 100000.2 + 135000 + 13333.4},
+		{"flat", []PriceTier{{PricePerUnitUSD: 0.096}}, 730, 70.08},
+		{"no tiers", nil, 730, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TieredCostUSD(tt.tiers, tt.quantity)
+			if math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		r    string
+		want float64
+	}{
+		{"0", 0},
+		{"51200", 51200},
+		{"15000000000", 15e9},
+		{"Inf", 0},
+		{"", 0},
+	}
+
+	for _, tt := range tests {
+		got, err := parseRange(tt.r)
+		if err != nil {
+			t.Errorf("parseRange(%q) error: %v", tt.r, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseRange(%q) = %v, want %v", tt.r, got, tt.want)
+		}
+	}
+
+	if _, err := parseRange("unlimited"); err == nil {
+		t.Error("parseRange(unlimited) got no error")
+	}
+}
@@ -0,0 +1,77 @@
+package aws
+
+// TransferInternet is the destination of the data transferred out to the internet.
+const TransferInternet = "internet"
+
+// transferTypes are the transfer types stored in the price list.
+// Inbound transfer is free, so it is not stored.
+var transferTypes = map[string]bool{
+	"AWS Outbound":         true,
+	"InterRegion Outbound": true,
+	"IntraRegion":          true,
+}
+
+// locationKeys returns the attribute keys of the location type and the region code of the service.
+// Data transfer products have the source and the destination instead of the location.
+func locationKeys(serviceCode string) (string, string) {
+	if serviceCode == "AWSDataTransfer" {
+		return "fromLocationType", "fromRegionCode"
+	}
+
+	return "locationType", "regionCode"
+}
+
+func isTransferProduct(productFamily string, attr map[string]interface{}) bool {
+	if productFamily != "Data Transfer" || !transferTypes[stringValue(attr, "transferType")] {
+		return false
+	}
+
+	// AWS Outbound also has the transfer to CloudFront and Direct Connect locations.
+	if stringValue(attr, "transferType") == "AWS Outbound" {
+		return stringValue(attr, "toLocation") == "External"
+	}
+
+	return stringValue(attr, "toRegionCode") != ""
+}
+
+// product example:
+//
+//	"product": {
+//	  "productFamily": "Data Transfer",
+//	  "attributes": {
+//	    "transferType": "InterRegion Outbound",
+//	    "fromLocation": "Asia Pacific (Tokyo)",
+//	    "fromLocationType": "AWS Region",
+//	    "fromRegionCode": "ap-northeast-1",
+//	    "toLocation": "US East (N. Virginia)",
+//	    "toLocationType": "AWS Region",
+//	    "toRegionCode": "us-east-1",
+//	    "usagetype": "APN1-USE1-AWS-Out-Bytes",
+//	    "operation": "",
+//	    "servicecode": "AWSDataTransfer",
+//	    "servicename": "AWS Data Transfer"
+//	  },
+//	  "sku": "24PWQPCHMRGCT6NP"
+//	}
+func (p *Price) addTransferAttributes(attr map[string]interface{}) *Price {
+	p.Product.Attributes.TransferType = stringValue(attr, "transferType")
+	p.Product.Attributes.UsageType = stringValue(attr, "usagetype")
+	p.Product.Attributes.LocationType = stringValue(attr, "fromLocationType")
+	p.Product.Attributes.RegionCode = stringValue(attr, "fromRegionCode")
+	p.Product.Attributes.Location = stringValue(attr, "fromLocation")
+	p.Product.Attributes.ToLocation = stringValue(attr, "toLocation")
+	p.Product.Attributes.ToRegionCode = stringValue(attr, "toRegionCode")
+	p.Product.Attributes.Servicecode = stringValue(attr, "servicecode")
+	p.Product.Attributes.Servicename = stringValue(attr, "servicename")
+	p.Product.Attributes.Operation = stringValue(attr, "operation")
+
+	// Destination is the region code, or internet for the transfer out to the internet.
+	// The transfer between the AZs of the region has the same region code as the source.
+	if p.Product.Attributes.ToLocation == "External" {
+		p.Product.Attributes.Destination = TransferInternet
+	} else {
+		p.Product.Attributes.Destination = p.Product.Attributes.ToRegionCode
+	}
+
+	return p
+}
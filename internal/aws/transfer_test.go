@@ -0,0 +1,47 @@
+package aws
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestParsePriceTransfer(t *testing.T) {
+	price, err := parsePrice("AWSDataTransfer", loadFixture(t, "transfer_internet_ap_northeast_1.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attr := price.Product.Attributes
+	if price.Region != "ap-northeast-1" || attr.Destination != TransferInternet {
+		t.Errorf("got %s to %s, want ap-northeast-1 to %s", price.Region, attr.Destination, TransferInternet)
+	}
+
+	want := []PriceTier{
+		{BeginRange: 0, EndRange: 1, Unit: "GB", PricePerUnitUSD: 0},
+		{BeginRange: 1, EndRange: 10240, Unit: "GB", PricePerUnitUSD: 0.114},
+		{BeginRange: 10240, EndRange: 51200, Unit: "GB", PricePerUnitUSD: 0.089},
+		{BeginRange: 51200, EndRange: 153600, Unit: "GB", PricePerUnitUSD: 0.086},
+		{BeginRange: 153600, EndRange: 0, Unit: "GB", PricePerUnitUSD: 0.084},
+	}
+	if !reflect.DeepEqual(price.OnDemandTiers, want) {
+		t.Fatalf("got tiers %+v, want %+v", price.OnDemandTiers, want)
+	}
+
+	tests := []struct {
+		gb   float64
+		want float64
+	}{
+		{1, 0},
+		// The first GB is free.
+		{101, 11.4},
+		// 1 GB free, 10239 GB at 0.114 and 9760 GB at 0.089
+		{20000, 1167.246 + 868.64},
+	}
+
+	for _, tt := range tests {
+		if got := TieredCostUSD(price.OnDemandTiers, tt.gb); math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("TieredCostUSD(%v GB) = %v, want %v", tt.gb, got, tt.want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package mongo
 
 import (
 	"context"
+	"errors"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -38,15 +39,124 @@ func DropCollection(coll *mongo.Collection, ctx context.Context) error {
 	return nil
 }
 
-func Find(coll *mongo.Collection, filter interface{}, opt *options.FindOptions) ([]primitive.M, error) {
-	cursor, err := coll.Find(context.Background(), filter, opt)
+// InsertMany inserts the documents with an unordered bulk write,
+// so the rest of the documents are inserted even if one of them fails.
+func InsertMany(coll *mongo.Collection, ctx context.Context, docs []interface{}) error {
+	if _, err := coll.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func DeleteMany(coll *mongo.Collection, ctx context.Context, filter interface{}) error {
+	if _, err := coll.DeleteMany(ctx, filter); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func CountDocuments(coll *mongo.Collection, ctx context.Context) (int64, error) {
+	return coll.CountDocuments(ctx, bson.M{})
+}
+
+// CreateIndexes creates an ascending index per key. Existing indexes are left as they are.
+func CreateIndexes(coll *mongo.Collection, ctx context.Context, keys []string) error {
+	models := make([]mongo.IndexModel, 0, len(keys))
+	for _, key := range keys {
+		models = append(models, mongo.IndexModel{Keys: bson.D{{Key: key, Value: 1}}})
+	}
+
+	if _, err := coll.Indexes().CreateMany(ctx, models); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// namespaceExists is the error code of creating a collection that already exists.
+const namespaceExists = 48
+
+// CreateTimeSeries creates the time series collection. An existing collection is left as it is.
+func CreateTimeSeries(client *mongo.Client, ctx context.Context, collName, timeField, metaField string) error {
+	opts := options.CreateCollection().SetTimeSeriesOptions(
+		options.TimeSeries().SetTimeField(timeField).SetMetaField(metaField).SetGranularity("hours"),
+	)
+
+	if err := client.Database(dbName).CreateCollection(ctx, collName, opts); err != nil {
+		var cmdErr mongo.CommandError
+		if errors.As(err, &cmdErr) && cmdErr.Code == namespaceExists {
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+// IndexKeys returns the keys of the single field indexes of the collection.
+func IndexKeys(coll *mongo.Collection, ctx context.Context) ([]string, error) {
+	cursor, err := coll.Indexes().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var indexes []struct {
+		Key bson.D `bson:"key"`
+	}
+	if err := cursor.All(ctx, &indexes); err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, index := range indexes {
+		if len(index.Key) == 1 {
+			keys = append(keys, index.Key[0].Key)
+		}
+	}
+
+	return keys, nil
+}
+
+// RenameCollection renames the source collection to the destination.
+// The destination is dropped in the same operation, so it is replaced atomically.
+func RenameCollection(client *mongo.Client, ctx context.Context, src, dst string) error {
+	cmd := bson.D{
+		{Key: "renameCollection", Value: dbName + "." + src},
+		{Key: "to", Value: dbName + "." + dst},
+		{Key: "dropTarget", Value: true},
+	}
+
+	return client.Database("admin").RunCommand(ctx, cmd).Err()
+}
+
+// CopyCollection copies the documents of the source collection into the destination collection.
+// $out writes into a temporary collection and renames it over the destination atomically.
+func CopyCollection(src *mongo.Collection, ctx context.Context, dst string) error {
+	cursor, err := src.Aggregate(ctx, mongo.Pipeline{{{Key: "$out", Value: dst}}})
+	if err != nil {
+		return err
+	}
+
+	return cursor.Close(ctx)
+}
+
+// ListCollectionNames returns the names of the collections that match the regular expression.
+func ListCollectionNames(client *mongo.Client, ctx context.Context, pattern string) ([]string, error) {
+	return client.Database(dbName).ListCollectionNames(ctx, bson.M{"name": bson.M{"$regex": pattern}})
+}
+
+func Find(coll *mongo.Collection, ctx context.Context, filter interface{}, opt *options.FindOptions) ([]primitive.M, error) {
+	cursor, err := coll.Find(ctx, filter, opt)
 	if err != nil {
 		return nil, err
 	}
-	defer cursor.Close(context.Background())
+	defer cursor.Close(ctx)
 
 	var results []bson.M
-	if err := cursor.All(context.Background(), &results); err != nil {
+	if err := cursor.All(ctx, &results); err != nil {
 		return nil, err
 	}
 
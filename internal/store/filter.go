@@ -0,0 +1,417 @@
+package store
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// match reports whether the document matches the filter.
+// It supports the subset of the MongoDB query language used by apf:
+// equality, $eq, $ne, $gt, $gte, $lt, $lte, $in, $nin, $exists, $regex, $elemMatch, $and and $or.
+func match(doc bson.M, filter bson.M) (bool, error) {
+	for key, cond := range filter {
+		var ok bool
+		var err error
+
+		switch key {
+		case "$and", "$or":
+			ok, err = matchLogical(doc, key, cond)
+		default:
+			ok, err = matchCondition(lookup(doc, strings.Split(key, ".")), cond)
+		}
+
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", key, err)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func matchLogical(doc bson.M, op string, cond interface{}) (bool, error) {
+	filters, ok := asArray(cond)
+	if !ok {
+		return false, fmt.Errorf("%s needs an array", op)
+	}
+
+	for _, f := range filters {
+		m, ok := asMap(f)
+		if !ok {
+			return false, fmt.Errorf("%s needs an array of documents", op)
+		}
+
+		ok, err := match(doc, m)
+		if err != nil {
+			return false, err
+		}
+
+		if op == "$or" && ok {
+			return true, nil
+		}
+		if op == "$and" && !ok {
+			return false, nil
+		}
+	}
+
+	return op == "$and", nil
+}
+
+// lookup returns the values at the dotted path. Arrays on the path are expanded like MongoDB.
+func lookup(v interface{}, path []string) []interface{} {
+	if len(path) == 0 {
+		return []interface{}{v}
+	}
+
+	if m, ok := asMap(v); ok {
+		child, ok := m[path[0]]
+		if !ok {
+			return nil
+		}
+		return lookup(child, path[1:])
+	}
+
+	if a, ok := asArray(v); ok {
+		if i, err := strconv.Atoi(path[0]); err == nil {
+			if i < 0 || i >= len(a) {
+				return nil
+			}
+			return lookup(a[i], path[1:])
+		}
+
+		var values []interface{}
+		for _, e := range a {
+			values = append(values, lookup(e, path)...)
+		}
+		return values
+	}
+
+	return nil
+}
+
+func matchCondition(values []interface{}, cond interface{}) (bool, error) {
+	ops, ok := asMap(cond)
+	if !ok || !isOperators(ops) {
+		return matchEq(values, cond), nil
+	}
+
+	for op, arg := range ops {
+		ok, err := matchOperator(values, op, arg)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func isOperators(m map[string]interface{}) bool {
+	if len(m) == 0 {
+		return false
+	}
+
+	for key := range m {
+		if !strings.HasPrefix(key, "$") {
+			return false
+		}
+	}
+
+	return true
+}
+
+func matchOperator(values []interface{}, op string, arg interface{}) (bool, error) {
+	switch op {
+	case "$eq":
+		return matchEq(values, arg), nil
+	case "$ne":
+		return !matchEq(values, arg), nil
+	case "$gt", "$gte", "$lt", "$lte":
+		for _, v := range flatten(values) {
+			c, ok := compare(v, arg)
+			if !ok {
+				continue
+			}
+
+			if (op == "$gt" && c > 0) || (op == "$gte" && c >= 0) || (op == "$lt" && c < 0) || (op == "$lte" && c <= 0) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "$in", "$nin":
+		list, ok := asArray(arg)
+		if !ok {
+			return false, fmt.Errorf("%s needs an array", op)
+		}
+
+		in := false
+		for _, e := range list {
+			if matchEq(values, e) {
+				in = true
+				break
+			}
+		}
+		return in == (op == "$in"), nil
+	case "$exists":
+		want, ok := arg.(bool)
+		if !ok {
+			return false, fmt.Errorf("$exists needs a boolean")
+		}
+		return (len(values) > 0) == want, nil
+	case "$regex":
+		pattern, ok := arg.(string)
+		if !ok {
+			return false, fmt.Errorf("$regex needs a string")
+		}
+
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return false, err
+		}
+
+		for _, v := range flatten(values) {
+			if s, ok := v.(string); ok && re.MatchString(s) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "$elemMatch":
+		sub, ok := asMap(arg)
+		if !ok {
+			return false, fmt.Errorf("$elemMatch needs a document")
+		}
+
+		for _, v := range values {
+			a, ok := asArray(v)
+			if !ok {
+				continue
+			}
+
+			for _, e := range a {
+				var ok bool
+				var err error
+				if m, isDoc := asMap(e); isDoc && !isOperators(sub) {
+					ok, err = match(m, sub)
+				} else {
+					ok, err = matchCondition([]interface{}{e}, sub)
+				}
+				if err != nil {
+					return false, err
+				}
+				if ok {
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+	default:
+		return false, fmt.Errorf("Unsupported operator: %s", op)
+	}
+}
+
+// matchEq reports whether any of the values, or any element of them, equals to the target.
+// A missing value equals to null.
+func matchEq(values []interface{}, target interface{}) bool {
+	if len(values) == 0 {
+		return target == nil
+	}
+
+	for _, v := range values {
+		if equal(v, target) {
+			return true
+		}
+
+		if a, ok := asArray(v); ok {
+			for _, e := range a {
+				if equal(e, target) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+func flatten(values []interface{}) []interface{} {
+	var flat []interface{}
+	for _, v := range values {
+		if a, ok := asArray(v); ok {
+			flat = append(flat, a...)
+			continue
+		}
+		flat = append(flat, v)
+	}
+
+	return flat
+}
+
+func equal(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	if c, ok := compare(a, b); ok {
+		return c == 0
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// compare compares the values of the same kind, numbers, strings, times or booleans.
+func compare(a, b interface{}) (int, bool) {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		if !ok {
+			return 0, false
+		}
+		return compareOrdered(x, y), true
+	}
+
+	if x, ok := a.(string); ok {
+		y, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(x, y), true
+	}
+
+	if x, ok := toTime(a); ok {
+		y, ok := toTime(b)
+		if !ok {
+			return 0, false
+		}
+		return x.Compare(y), true
+	}
+
+	if x, ok := a.(bool); ok {
+		y, ok := b.(bool)
+		if !ok || x == y {
+			return 0, ok
+		}
+		if x {
+			return 1, true
+		}
+		return -1, true
+	}
+
+	return 0, false
+}
+
+func compareOrdered(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+func toTime(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case primitive.DateTime:
+		return t.Time(), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+func asMap(v interface{}) (map[string]interface{}, bool) {
+	switch m := v.(type) {
+	case primitive.M:
+		return m, true
+	case map[string]interface{}:
+		return m, true
+	default:
+		return nil, false
+	}
+}
+
+func asArray(v interface{}) ([]interface{}, bool) {
+	switch a := v.(type) {
+	case primitive.A:
+		return a, true
+	case []interface{}:
+		return a, true
+	case []string:
+		r := make([]interface{}, len(a))
+		for i, e := range a {
+			r[i] = e
+		}
+		return r, true
+	default:
+		return nil, false
+	}
+}
+
+// project returns the document with only the keys. Arrays on the path are returned as a whole.
+func project(doc bson.M, keys []string) bson.M {
+	projected := bson.M{}
+
+	for _, key := range keys {
+		path := strings.Split(key, ".")
+
+		var src interface{} = doc
+		dst := projected
+		for i, p := range path {
+			m, ok := asMap(src)
+			if !ok {
+				break
+			}
+
+			v, ok := m[p]
+			if !ok {
+				break
+			}
+
+			child, isMap := asMap(v)
+			if i == len(path)-1 || !isMap {
+				dst[p] = v
+				break
+			}
+
+			next, ok := dst[p].(bson.M)
+			if !ok {
+				next = bson.M{}
+				dst[p] = next
+			}
+
+			src = child
+			dst = next
+		}
+	}
+
+	return projected
+}
@@ -0,0 +1,188 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var fetchedAt = time.Date(2023, 6, 1, 9, 30, 0, 0, time.UTC)
+
+// testDoc is a price with the types decoded from the stores.
+func testDoc() bson.M {
+	return bson.M{
+		"sku":               "8VCNEHQMSCQS4P39",
+		"region":            "us-east-1",
+		"vcpucount":         int32(2),
+		"memorygib":         8.0,
+		"ondemandhourlyusd": 0.096,
+		"fetchedat":         primitive.NewDateTimeFromTime(fetchedAt),
+		"product": bson.M{
+			"attributes": bson.M{"instancetype": "m5.large", "tenancy": "Shared"},
+		},
+		"ondemand": primitive.A{
+			bson.M{"unit": "Hrs", "effectivedate": "2023-06-01T00:00:00Z"},
+		},
+		"reserved": primitive.A{
+			bson.M{"leasecontractlength": "1yr", "purchaseoption": "Partial Upfront"},
+			bson.M{"leasecontractlength": "3yr", "purchaseoption": "All Upfront"},
+		},
+		"tags": primitive.A{"general", "intel"},
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter bson.M
+		want   bool
+	}{
+		{"empty", bson.M{}, true},
+		{"equality", bson.M{"region": "us-east-1"}, true},
+		{"equality mismatch", bson.M{"region": "ap-northeast-1"}, false},
+		{"dotted path", bson.M{"product.attributes.instancetype": "m5.large"}, true},
+		{"missing equals null", bson.M{"product.attributes.gpu": nil}, true},
+		{"$eq", bson.M{"sku": bson.M{"$eq": "8VCNEHQMSCQS4P39"}}, true},
+		{"$ne", bson.M{"product.attributes.tenancy": bson.M{"$ne": "Dedicated"}}, true},
+		{"$ne mismatch", bson.M{"product.attributes.tenancy": bson.M{"$ne": "Shared"}}, false},
+		{"$ne missing", bson.M{"product.attributes.gpu": bson.M{"$ne": ""}}, true},
+		{"$in", bson.M{"region": bson.M{"$in": []string{"ap-northeast-1", "us-east-1"}}}, true},
+		{"$in mismatch", bson.M{"region": bson.M{"$in": bson.A{"ap-northeast-1"}}}, false},
+		{"$nin", bson.M{"region": bson.M{"$nin": bson.A{"ap-northeast-1"}}}, true},
+		{"$nin mismatch", bson.M{"region": bson.M{"$nin": bson.A{"us-east-1"}}}, false},
+		{"$gte int and float", bson.M{"vcpucount": bson.M{"$gte": 2.0}}, true},
+		{"$gt int and float", bson.M{"vcpucount": bson.M{"$gt": 2.0}}, false},
+		{"$lte", bson.M{"ondemandhourlyusd": bson.M{"$lte": 0.096}}, true},
+		{"$lt", bson.M{"ondemandhourlyusd": bson.M{"$lt": 0.096}}, false},
+		{"range", bson.M{"memorygib": bson.M{"$gte": int64(4), "$lte": int64(16)}}, true},
+		{"range mismatch", bson.M{"memorygib": bson.M{"$gte": 16, "$lte": 32}}, false},
+		{"number and string", bson.M{"vcpucount": bson.M{"$gte": "1"}}, false},
+		{"string date", bson.M{"ondemand.effectivedate": bson.M{"$lte": "2023-06-01T00:00:00Z"}}, true},
+		{"string date mismatch", bson.M{"ondemand.effectivedate": bson.M{"$lte": "2023-05-31T00:00:00Z"}}, false},
+		{"time and date time", bson.M{"fetchedat": bson.M{"$gte": fetchedAt, "$lt": fetchedAt.Add(time.Second)}}, true},
+		{"time mismatch", bson.M{"fetchedat": bson.M{"$gt": fetchedAt}}, false},
+		{"array element", bson.M{"tags": "intel"}, true},
+		{"array element mismatch", bson.M{"tags": "amd"}, false},
+		{"array $in", bson.M{"tags": bson.M{"$in": bson.A{"amd", "general"}}}, true},
+		{"array $nin", bson.M{"tags": bson.M{"$nin": bson.A{"general"}}}, false},
+		{"array of documents", bson.M{"reserved.leasecontractlength": "3yr"}, true},
+		{"array index", bson.M{"reserved.0.leasecontractlength": "3yr"}, false},
+		{"array index out of range", bson.M{"reserved.2.leasecontractlength": bson.M{"$exists": true}}, false},
+		{"$elemMatch", bson.M{"reserved": bson.M{"$elemMatch": bson.M{"leasecontractlength": "1yr", "purchaseoption": "Partial Upfront"}}}, true},
+		// Each key matches a different element.
+		{"$elemMatch across elements", bson.M{"reserved": bson.M{"$elemMatch": bson.M{"leasecontractlength": "1yr", "purchaseoption": "All Upfront"}}}, false},
+		{"$elemMatch operators", bson.M{"tags": bson.M{"$elemMatch": bson.M{"$regex": "^in"}}}, true},
+		{"$exists", bson.M{"product.attributes.tenancy": bson.M{"$exists": true}}, true},
+		{"$exists false", bson.M{"product.attributes.gpu": bson.M{"$exists": false}}, true},
+		{"$regex", bson.M{"product.attributes.instancetype": bson.M{"$regex": "^m5\\."}}, true},
+		{"$or", bson.M{"$or": bson.A{bson.M{"region": "ap-northeast-1"}, bson.M{"vcpucount": 2}}}, true},
+		{"$or mismatch", bson.M{"$or": bson.A{bson.M{"region": "ap-northeast-1"}, bson.M{"vcpucount": 4}}}, false},
+		{"$and", bson.M{"$and": bson.A{bson.M{"region": "us-east-1"}, bson.M{"vcpucount": 2}}}, true},
+		{"$and mismatch", bson.M{"$and": bson.A{bson.M{"region": "us-east-1"}, bson.M{"vcpucount": 4}}}, false},
+		{"document equality", bson.M{"product.attributes": bson.M{"instancetype": "m5.large", "tenancy": "Shared"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := match(testDoc(), tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchError(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter bson.M
+	}{
+		{"$in needs an array", bson.M{"region": bson.M{"$in": "us-east-1"}}},
+		{"$exists needs a boolean", bson.M{"region": bson.M{"$exists": 1}}},
+		{"$regex needs a string", bson.M{"region": bson.M{"$regex": 1}}},
+		{"invalid $regex", bson.M{"region": bson.M{"$regex": "("}}},
+		{"$or needs an array", bson.M{"$or": bson.M{"region": "us-east-1"}}},
+		{"$and needs documents", bson.M{"$and": bson.A{"us-east-1"}}},
+		{"unsupported operator", bson.M{"region": bson.M{"$size": 1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := match(testDoc(), tt.filter); err == nil {
+				t.Error("got no error")
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b interface{}
+		want int
+		ok   bool
+	}{
+		{"int and float", int32(2), 2.0, 0, true},
+		{"int64 and int", int64(1), 2, -1, true},
+		{"float", 0.096, 0.0272, 1, true},
+		{"string", "2023-03-01", "2023-06-01", -1, true},
+		{"time and date time", fetchedAt, primitive.NewDateTimeFromTime(fetchedAt.Add(-time.Hour)), 1, true},
+		{"bool", false, true, -1, true},
+		{"number and string", 1, "1", 0, false},
+		{"string and time", "2023-06-01", fetchedAt, 0, false},
+		{"documents", bson.M{}, bson.M{}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := compare(tt.a, tt.b)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("got (%d, %v), want (%d, %v)", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		path []string
+		want []interface{}
+	}{
+		{[]string{"region"}, []interface{}{"us-east-1"}},
+		{[]string{"product", "attributes", "tenancy"}, []interface{}{"Shared"}},
+		{[]string{"reserved", "leasecontractlength"}, []interface{}{"1yr", "3yr"}},
+		{[]string{"reserved", "1", "purchaseoption"}, []interface{}{"All Upfront"}},
+		{[]string{"tags"}, []interface{}{primitive.A{"general", "intel"}}},
+		{[]string{"product", "attributes", "gpu"}, nil},
+		{[]string{"region", "code"}, nil},
+	}
+
+	for _, tt := range tests {
+		if got := lookup(testDoc(), tt.path); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("lookup(%v) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestProject(t *testing.T) {
+	got := project(testDoc(), []string{"sku", "product.attributes.instancetype", "reserved.leasecontractlength", "missing"})
+
+	want := bson.M{
+		"sku": "8VCNEHQMSCQS4P39",
+		"product": bson.M{
+			"attributes": bson.M{"instancetype": "m5.large"},
+		},
+		// Arrays are returned as a whole.
+		"reserved": testDoc()["reserved"],
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
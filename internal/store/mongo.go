@@ -0,0 +1,121 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sfuruya0612/apf/internal/mongo"
+	"go.mongodb.org/mongo-driver/bson"
+	driver "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoStore struct {
+	client *driver.Client
+}
+
+func openMongo(uri string) (Store, error) {
+	client, err := mongo.Connect(uri)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to connect to MongoDB: %w", err)
+	}
+
+	return &mongoStore{client: client}, nil
+}
+
+func (s *mongoStore) collection(name string) *driver.Collection {
+	return mongo.Collection(s.client, name)
+}
+
+func (s *mongoStore) Insert(ctx context.Context, collection string, docs []interface{}) error {
+	return mongo.InsertMany(s.collection(collection), ctx, docs)
+}
+
+func (s *mongoStore) Find(ctx context.Context, collection string, filter bson.M, opt *FindOptions) ([]bson.M, error) {
+	findOptions := options.Find()
+
+	if opt != nil && len(opt.Projection) > 0 {
+		projection := bson.M{}
+		for _, key := range opt.Projection {
+			projection[key] = 1
+		}
+		findOptions.SetProjection(projection)
+	}
+
+	if opt != nil && len(opt.Sort) > 0 {
+		sort := bson.D{}
+		for _, key := range opt.Sort {
+			order := 1
+			if key.Descending {
+				order = -1
+			}
+			sort = append(sort, bson.E{Key: key.Key, Value: order})
+		}
+		findOptions.SetSort(sort)
+	}
+
+	if opt != nil && opt.Limit > 0 {
+		findOptions.SetLimit(opt.Limit)
+	}
+
+	return mongo.Find(s.collection(collection), ctx, filter, findOptions)
+}
+
+func (s *mongoStore) Count(ctx context.Context, collection string) (int64, error) {
+	return mongo.CountDocuments(s.collection(collection), ctx)
+}
+
+func (s *mongoStore) Delete(ctx context.Context, collection string, filter bson.M) error {
+	return mongo.DeleteMany(s.collection(collection), ctx, filter)
+}
+
+func (s *mongoStore) Drop(ctx context.Context, collection string) error {
+	return mongo.DropCollection(s.collection(collection), ctx)
+}
+
+// Replace renames the source collection over the destination, and copies it back into the source.
+// The renamed collection keeps the indexes of the source, and the copy gets them again.
+func (s *mongoStore) Replace(ctx context.Context, src, dst string) error {
+	if err := mongo.RenameCollection(s.client, ctx, src, dst); err != nil {
+		return err
+	}
+
+	keys, err := mongo.IndexKeys(s.collection(dst), ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := mongo.CopyCollection(s.collection(dst), ctx, src); err != nil {
+		return fmt.Errorf("Failed to copy %s collection back to %s: %w", dst, src, err)
+	}
+
+	var indexes []string
+	for _, key := range keys {
+		// The _id index is created with the collection.
+		if key != "_id" {
+			indexes = append(indexes, key)
+		}
+	}
+
+	return mongo.CreateIndexes(s.collection(src), ctx, indexes)
+}
+
+func (s *mongoStore) CreateIndexes(ctx context.Context, collection string, keys []string) error {
+	return mongo.CreateIndexes(s.collection(collection), ctx, keys)
+}
+
+func (s *mongoStore) CreateTimeSeries(ctx context.Context, collection, timeField, metaField string) error {
+	return mongo.CreateTimeSeries(s.client, ctx, collection, timeField, metaField)
+}
+
+func (s *mongoStore) IndexKeys(ctx context.Context, collection string) ([]string, error) {
+	return mongo.IndexKeys(s.collection(collection), ctx)
+}
+
+func (s *mongoStore) ListCollections(ctx context.Context, pattern string) ([]string, error) {
+	return mongo.ListCollectionNames(s.client, ctx, pattern)
+}
+
+func (s *mongoStore) Close() error {
+	return mongo.Disconnect(s.client)
+}
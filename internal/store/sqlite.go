@@ -0,0 +1,373 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteStore stores documents as extended JSON in a table per collection,
+// and evaluates the filters in Go. Only the string equality of indexed keys (e.g. region: us-east-1)
+// is pushed down to SQLite, so the other filters (operators, numbers, $or and keys without an index)
+// read every document of the collection, or of the rows narrowed by the pushed down keys.
+type sqliteStore struct {
+	db *sql.DB
+}
+
+var keyPattern = regexp.MustCompile(`^[A-Za-z0-9_]+(\.[A-Za-z0-9_]+)*$`)
+
+func openSqlite(path string) (Store, error) {
+	if path == "" {
+		return nil, fmt.Errorf("SQLite database path is missing (e.g. sqlite:///path/to/apf.db)")
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open SQLite database: %w", err)
+	}
+
+	// SQLite allows a single writer, so the connection is shared to serialize the writes.
+	db.SetMaxOpenConns(1)
+
+	// Wait for the lock held by another process (e.g. apf price during apf fetch).
+	for _, pragma := range []string{"PRAGMA journal_mode = WAL", "PRAGMA busy_timeout = 10000"} {
+		if _, err := db.Exec(pragma); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("Failed to open SQLite database: %w", err)
+		}
+	}
+
+	return &sqliteStore{db: db}, nil
+}
+
+func quote(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func jsonPath(key string) (string, error) {
+	if !keyPattern.MatchString(key) {
+		return "", fmt.Errorf("Invalid key: %s", key)
+	}
+
+	return "'$." + key + "'", nil
+}
+
+func indexName(collection, key string) string {
+	return collection + ":" + key
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func createTable(ctx context.Context, e execer, collection string) error {
+	_, err := e.ExecContext(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id INTEGER PRIMARY KEY, doc TEXT NOT NULL)", quote(collection)))
+	return err
+}
+
+func createIndex(ctx context.Context, e execer, collection, key string) error {
+	path, err := jsonPath(key)
+	if err != nil {
+		return err
+	}
+
+	_, err = e.ExecContext(ctx, fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (json_extract(doc, %s))",
+		quote(indexName(collection, key)), quote(collection), path))
+	return err
+}
+
+func (s *sqliteStore) exists(ctx context.Context, collection string) (bool, error) {
+	var n int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", collection).Scan(&n); err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+func (s *sqliteStore) Insert(ctx context.Context, collection string, docs []interface{}) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := createTable(ctx, tx, collection); err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf("INSERT INTO %s (doc) VALUES (?)", quote(collection)))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, doc := range docs {
+		b, err := bson.MarshalExtJSON(doc, false, false)
+		if err != nil {
+			return fmt.Errorf("Failed to encode document: %w", err)
+		}
+
+		if _, err := stmt.ExecContext(ctx, string(b)); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// row is a decoded document with its row ID.
+type row struct {
+	id  int64
+	doc bson.M
+}
+
+// scan returns the rows of the collection that match the filter.
+func (s *sqliteStore) scan(ctx context.Context, collection string, filter bson.M) ([]row, error) {
+	ok, err := s.exists(ctx, collection)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	indexed, err := s.IndexKeys(ctx, collection)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("SELECT id, doc FROM %s", quote(collection))
+
+	var conditions []string
+	var args []interface{}
+	for _, key := range indexed {
+		// Only the equality of strings is pushed down, and the rest is evaluated by match.
+		// Operators like $in and $gte, and numbers whose JSON type may differ from the filter, are not.
+		v, ok := filter[key].(string)
+		if !ok {
+			continue
+		}
+
+		path, err := jsonPath(key)
+		if err != nil {
+			return nil, err
+		}
+
+		conditions = append(conditions, fmt.Sprintf("json_extract(doc, %s) = ?", path))
+		args = append(args, v)
+	}
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []row
+	for rows.Next() {
+		var r row
+		var doc string
+		if err := rows.Scan(&r.id, &doc); err != nil {
+			return nil, err
+		}
+
+		if err := bson.UnmarshalExtJSON([]byte(doc), false, &r.doc); err != nil {
+			return nil, fmt.Errorf("Failed to decode document: %w", err)
+		}
+
+		ok, err := match(r.doc, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			results = append(results, r)
+		}
+	}
+
+	return results, rows.Err()
+}
+
+func (s *sqliteStore) Find(ctx context.Context, collection string, filter bson.M, opt *FindOptions) ([]bson.M, error) {
+	rows, err := s.scan(ctx, collection, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]bson.M, 0, len(rows))
+	for _, r := range rows {
+		results = append(results, r.doc)
+	}
+
+	results = SortAndLimit(results, opt)
+
+	if opt != nil && len(opt.Projection) > 0 {
+		for i, doc := range results {
+			results[i] = project(doc, opt.Projection)
+		}
+	}
+
+	return results, nil
+}
+
+func (s *sqliteStore) Count(ctx context.Context, collection string) (int64, error) {
+	ok, err := s.exists(ctx, collection)
+	if err != nil || !ok {
+		return 0, err
+	}
+
+	var n int64
+	if err := s.db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", quote(collection))).Scan(&n); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+func (s *sqliteStore) Delete(ctx context.Context, collection string, filter bson.M) error {
+	rows, err := s.scan(ctx, collection, filter)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, r := range rows {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = ?", quote(collection)), r.id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *sqliteStore) Drop(ctx context.Context, collection string) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", quote(collection)))
+	return err
+}
+
+func (s *sqliteStore) Replace(ctx context.Context, src, dst string) error {
+	ok, err := s.exists(ctx, src)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("Collection %s is not found", src)
+	}
+
+	// The indexes of the destination are kept.
+	keys, err := s.IndexKeys(ctx, dst)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", quote(dst))); err != nil {
+		return err
+	}
+
+	if err := createTable(ctx, tx, dst); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (doc) SELECT doc FROM %s ORDER BY id", quote(dst), quote(src))); err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if err := createIndex(ctx, tx, dst, key); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *sqliteStore) CreateIndexes(ctx context.Context, collection string, keys []string) error {
+	if err := createTable(ctx, s.db, collection); err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if err := createIndex(ctx, s.db, collection, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CreateTimeSeries creates the table with an index of timeField. SQLite has no time series, so metaField is not used.
+func (s *sqliteStore) CreateTimeSeries(ctx context.Context, collection, timeField, metaField string) error {
+	return s.CreateIndexes(ctx, collection, []string{timeField})
+}
+
+func (s *sqliteStore) IndexKeys(ctx context.Context, collection string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT name FROM sqlite_master WHERE type = 'index' AND tbl_name = ?", collection)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+
+		if key := strings.TrimPrefix(name, collection+":"); key != name {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, rows.Err()
+}
+
+func (s *sqliteStore) ListCollections(ctx context.Context, pattern string) ([]string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT name FROM sqlite_master WHERE type = 'table'")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+
+		if re.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+
+	return names, rows.Err()
+}
+
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,172 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func openTestSqlite(t *testing.T) Store {
+	t.Helper()
+
+	st, err := Open("sqlite://" + filepath.Join(t.TempDir(), "apf.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+
+	return st
+}
+
+func insertTestDocs(t *testing.T, st Store, collection string) {
+	t.Helper()
+
+	docs := []interface{}{
+		bson.M{"sku": "A", "region": "us-east-1", "vcpucount": 2, "product": bson.M{"attributes": bson.M{"instancetype": "m5.large"}}},
+		bson.M{"sku": "B", "region": "ap-northeast-1", "vcpucount": 2, "product": bson.M{"attributes": bson.M{"instancetype": "m5.large"}}},
+		bson.M{"sku": "C", "region": "us-east-1", "vcpucount": 4, "product": bson.M{"attributes": bson.M{"instancetype": "m5.xlarge"}}},
+	}
+	if err := st.Insert(context.Background(), collection, docs); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSqliteFind(t *testing.T) {
+	st := openTestSqlite(t)
+	ctx := context.Background()
+
+	if err := st.CreateIndexes(ctx, "ec2", []string{"region", "product.attributes.instancetype"}); err != nil {
+		t.Fatal(err)
+	}
+	insertTestDocs(t, st, "ec2")
+
+	tests := []struct {
+		name   string
+		filter bson.M
+		opt    *FindOptions
+		want   []string
+	}{
+		{"all", bson.M{}, nil, []string{"A", "B", "C"}},
+		// Pushed down to SQLite.
+		{"indexed string", bson.M{"region": "us-east-1"}, nil, []string{"A", "C"}},
+		{"indexed strings", bson.M{"region": "us-east-1", "product.attributes.instancetype": "m5.large"}, nil, []string{"A"}},
+		// Evaluated in Go.
+		{"indexed operator", bson.M{"region": bson.M{"$in": bson.A{"ap-northeast-1"}}}, nil, []string{"B"}},
+		{"not indexed", bson.M{"vcpucount": bson.M{"$gte": 4}}, nil, []string{"C"}},
+		{"sort and limit", bson.M{}, &FindOptions{Sort: []SortKey{{Key: "vcpucount", Descending: true}, {Key: "sku"}}, Limit: 2}, []string{"C", "A"}},
+		{"no documents", bson.M{"region": "eu-west-1"}, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := st.Find(ctx, "ec2", tt.filter, tt.opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := skus(results); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	results, err := st.Find(ctx, "ec2", bson.M{"sku": "A"}, &FindOptions{Projection: []string{"product.attributes.instancetype"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []bson.M{{"product": bson.M{"attributes": bson.M{"instancetype": "m5.large"}}}}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("got projection %v, want %v", results, want)
+	}
+
+	// Missing collections have no documents.
+	if results, err := st.Find(ctx, "rds", bson.M{}, nil); err != nil || len(results) != 0 {
+		t.Errorf("got %v and %v from a missing collection", results, err)
+	}
+}
+
+func TestSqliteReplace(t *testing.T) {
+	st := openTestSqlite(t)
+	ctx := context.Background()
+
+	if err := st.CreateIndexes(ctx, "ec2", []string{"region"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Insert(ctx, "ec2", []interface{}{bson.M{"sku": "OLD"}}); err != nil {
+		t.Fatal(err)
+	}
+	insertTestDocs(t, st, "ec2_20230601093000")
+
+	if err := st.Replace(ctx, "ec2_20230601093000", "ec2"); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := st.Find(ctx, "ec2", bson.M{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := skus(results); !reflect.DeepEqual(got, []string{"A", "B", "C"}) {
+		t.Errorf("got %v after replace", got)
+	}
+
+	// The indexes of the destination are kept.
+	keys, err := st.IndexKeys(ctx, "ec2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(keys, []string{"region"}) {
+		t.Errorf("got index keys %v, want [region]", keys)
+	}
+
+	// The source is left as it is.
+	if n, err := st.Count(ctx, "ec2_20230601093000"); err != nil || n != 3 {
+		t.Errorf("got %d and %v from the source", n, err)
+	}
+
+	if err := st.Replace(ctx, "ec2_20230701093000", "ec2"); err == nil {
+		t.Error("got no error replacing with a missing collection")
+	}
+}
+
+func TestSqliteIndexKeys(t *testing.T) {
+	st := openTestSqlite(t)
+	ctx := context.Background()
+
+	keys := []string{"sku", "region", "product.attributes.instancetype"}
+	if err := st.CreateIndexes(ctx, "ec2", keys); err != nil {
+		t.Fatal(err)
+	}
+	// Existing indexes are left as they are.
+	if err := st.CreateIndexes(ctx, "ec2", []string{"sku"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.CreateIndexes(ctx, "ec2_20230601093000", []string{"sku"}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := st.IndexKeys(ctx, "ec2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(got)
+	sort.Strings(keys)
+
+	if !reflect.DeepEqual(got, keys) {
+		t.Errorf("got %v, want %v", got, keys)
+	}
+
+	if err := st.CreateIndexes(ctx, "ec2", []string{"product.attributes['x']"}); err == nil {
+		t.Error("got no error with an invalid key")
+	}
+
+	names, err := st.ListCollections(ctx, "^ec2_[0-9]{14}$")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"ec2_20230601093000"}) {
+		t.Errorf("got collections %v", names)
+	}
+}
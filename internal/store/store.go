@@ -0,0 +1,126 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Store is a storage of the price list.
+// Documents are stored per collection, and filters are written in the MongoDB query language.
+type Store interface {
+	// Insert inserts the documents into the collection.
+	Insert(ctx context.Context, collection string, docs []interface{}) error
+	// Find returns the documents of the collection that match the filter.
+	Find(ctx context.Context, collection string, filter bson.M, opt *FindOptions) ([]bson.M, error)
+	// Count returns the number of documents of the collection.
+	Count(ctx context.Context, collection string) (int64, error)
+	// Delete removes the documents of the collection that match the filter.
+	Delete(ctx context.Context, collection string, filter bson.M) error
+	// Drop removes the collection.
+	Drop(ctx context.Context, collection string) error
+	// Replace atomically replaces the destination collection with the documents of the source collection.
+	// The destination is left untouched if it fails. The source is kept with the same documents.
+	Replace(ctx context.Context, src, dst string) error
+	// CreateIndexes creates an ascending index per key. Existing indexes are left as they are.
+	CreateIndexes(ctx context.Context, collection string, keys []string) error
+	// CreateTimeSeries creates the collection of the documents measured at timeField, grouped by metaField.
+	// An existing collection is left as it is.
+	CreateTimeSeries(ctx context.Context, collection, timeField, metaField string) error
+	// IndexKeys returns the keys of the single field indexes of the collection.
+	IndexKeys(ctx context.Context, collection string) ([]string, error)
+	// ListCollections returns the names of the collections that match the regular expression.
+	ListCollections(ctx context.Context, pattern string) ([]string, error)
+	Close() error
+}
+
+// FindOptions are the options of Find.
+type FindOptions struct {
+	// Projection is the keys returned. All keys are returned if empty.
+	Projection []string
+	// Sort is the keys to sort by, in order of precedence.
+	Sort []SortKey
+	// Limit is the maximum number of documents returned. All documents are returned if 0.
+	Limit int64
+}
+
+type SortKey struct {
+	Key        string
+	Descending bool
+}
+
+// SortAndLimit sorts and limits the documents in memory the same as Find with the options.
+// Missing values are sorted before any other values, the same as MongoDB.
+func SortAndLimit(docs []bson.M, opt *FindOptions) []bson.M {
+	if opt == nil {
+		return docs
+	}
+
+	if len(opt.Sort) > 0 {
+		sort.SliceStable(docs, func(i, j int) bool {
+			for _, key := range opt.Sort {
+				c := compareValues(docs[i], docs[j], key.Key)
+				if c == 0 {
+					continue
+				}
+
+				if key.Descending {
+					return c > 0
+				}
+				return c < 0
+			}
+
+			return false
+		})
+	}
+
+	if opt.Limit > 0 && int64(len(docs)) > opt.Limit {
+		docs = docs[:opt.Limit]
+	}
+
+	return docs
+}
+
+func compareValues(a, b bson.M, key string) int {
+	path := strings.Split(key, ".")
+
+	va := lookup(a, path)
+	vb := lookup(b, path)
+
+	switch {
+	case len(va) == 0 && len(vb) == 0:
+		return 0
+	case len(va) == 0:
+		return -1
+	case len(vb) == 0:
+		return 1
+	}
+
+	c, _ := compare(va[0], vb[0])
+	return c
+}
+
+// Open connects to the store of the URI.
+//
+//	mongodb://localhost:27017
+//	mongodb+srv://cluster0.example.mongodb.net
+//	sqlite:///path/to/apf.db
+func Open(uri string) (Store, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid store URI: %w", err)
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "mongodb", "mongodb+srv":
+		return openMongo(uri)
+	case "sqlite":
+		return openSqlite(u.Host + u.Path)
+	default:
+		return nil, fmt.Errorf("Unsupported store URI scheme: %s (e.g. mongodb, sqlite)", u.Scheme)
+	}
+}
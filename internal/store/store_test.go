@@ -0,0 +1,50 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func skus(docs []bson.M) []string {
+	var s []string
+	for _, doc := range docs {
+		s = append(s, doc["sku"].(string))
+	}
+
+	return s
+}
+
+func TestSortAndLimit(t *testing.T) {
+	docs := func() []bson.M {
+		return []bson.M{
+			{"sku": "A", "region": "us-east-1", "ondemandhourlyusd": 0.096},
+			{"sku": "B", "region": "ap-northeast-1", "ondemandhourlyusd": int32(1)},
+			{"sku": "C", "region": "us-east-1"},
+			{"sku": "D", "region": "ap-northeast-1", "ondemandhourlyusd": 0.0272},
+		}
+	}
+
+	tests := []struct {
+		name string
+		opt  *FindOptions
+		want []string
+	}{
+		{"no options", nil, []string{"A", "B", "C", "D"}},
+		// Missing values first.
+		{"ascending", &FindOptions{Sort: []SortKey{{Key: "ondemandhourlyusd"}}}, []string{"C", "D", "A", "B"}},
+		{"descending", &FindOptions{Sort: []SortKey{{Key: "ondemandhourlyusd", Descending: true}}}, []string{"B", "A", "D", "C"}},
+		{"keys in order", &FindOptions{Sort: []SortKey{{Key: "region"}, {Key: "ondemandhourlyusd", Descending: true}}}, []string{"B", "D", "A", "C"}},
+		{"limit", &FindOptions{Sort: []SortKey{{Key: "ondemandhourlyusd"}}, Limit: 2}, []string{"C", "D"}},
+		{"limit over the documents", &FindOptions{Limit: 10}, []string{"A", "B", "C", "D"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := skus(SortAndLimit(docs(), tt.opt)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
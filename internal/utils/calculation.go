@@ -5,12 +5,34 @@ import (
 	"strconv"
 )
 
-func ConvertHourlyToMonthly(hourlyCostStr string) string {
+func ConvertHourlyToMonthly(hourlyCostStr string) (string, error) {
 	hourlyCost, err := strconv.ParseFloat(hourlyCostStr, 64)
 	if err != nil {
-		panic(err)
+		return "", fmt.Errorf("Invalid hourly cost %q: %w", hourlyCostStr, err)
 	}
 
 	// 730 hours in a month
-	return fmt.Sprintf("%.2f", hourlyCost*730)
+	return fmt.Sprintf("%.2f", hourlyCost*730), nil
+}
+
+// ConvertReservedToHourly amortizes the upfront fee over the lease contract length
+// (e.g. 1yr, 3yr) and returns the effective hourly cost.
+func ConvertReservedToHourly(upfrontFeeStr, hourlyCostStr, leaseContractLength string) (string, error) {
+	upfrontFee, err := strconv.ParseFloat(upfrontFeeStr, 64)
+	if err != nil {
+		return "", fmt.Errorf("Invalid upfront fee %q: %w", upfrontFeeStr, err)
+	}
+
+	hourlyCost, err := strconv.ParseFloat(hourlyCostStr, 64)
+	if err != nil {
+		return "", fmt.Errorf("Invalid hourly cost %q: %w", hourlyCostStr, err)
+	}
+
+	var years int
+	if _, err := fmt.Sscanf(leaseContractLength, "%dyr", &years); err != nil || years <= 0 {
+		return "", fmt.Errorf("Invalid lease contract length %q", leaseContractLength)
+	}
+
+	// 8760 hours in a year
+	return fmt.Sprintf("%.10f", hourlyCost+upfrontFee/float64(years*8760)), nil
 }
@@ -0,0 +1,82 @@
+package utils
+
+import "testing"
+
+func TestConvertHourlyToMonthly(t *testing.T) {
+	tests := []struct {
+		hourly string
+		want   string
+	}{
+		{"0.0960000000", "70.08"},
+		{"0.0272000000", "19.86"},
+		{"0", "0.00"},
+	}
+
+	for _, tt := range tests {
+		got, err := ConvertHourlyToMonthly(tt.hourly)
+		if err != nil {
+			t.Errorf("ConvertHourlyToMonthly(%q) error: %v", tt.hourly, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ConvertHourlyToMonthly(%q) = %s, want %s", tt.hourly, got, tt.want)
+		}
+	}
+
+	if _, err := ConvertHourlyToMonthly("N/A"); err == nil {
+		t.Error("ConvertHourlyToMonthly(N/A) got no error")
+	}
+}
+
+// The offers are m5.large Linux in us-east-1.
+func TestConvertReservedToHourly(t *testing.T) {
+	tests := []struct {
+		name       string
+		upfrontFee string
+		hourly     string
+		lease      string
+		want       string
+	}{
+		{"1yr no upfront", "0", "0.0600000000", "1yr", "0.0600000000"},
+		{"1yr partial upfront", "245", "0.0280000000", "1yr", "0.0559680365"},
+		{"1yr all upfront", "480", "0.0000000000", "1yr", "0.0547945205"},
+		{"3yr no upfront", "0", "0.0410000000", "3yr", "0.0410000000"},
+		{"3yr partial upfront", "532", "0.0200000000", "3yr", "0.0402435312"},
+		{"3yr all upfront", "1003", "0.0000000000", "3yr", "0.0381659056"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ConvertReservedToHourly(tt.upfrontFee, tt.hourly, tt.lease)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertReservedToHourlyError(t *testing.T) {
+	tests := []struct {
+		name       string
+		upfrontFee string
+		hourly     string
+		lease      string
+	}{
+		{"invalid upfront fee", "N/A", "0.06", "1yr"},
+		{"invalid hourly", "0", "", "1yr"},
+		{"unknown lease", "0", "0.06", ""},
+		{"zero lease", "0", "0.06", "0yr"},
+		{"lease in months", "0", "0.06", "12mo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ConvertReservedToHourly(tt.upfrontFee, tt.hourly, tt.lease); err == nil {
+				t.Error("got no error")
+			}
+		})
+	}
+}
@@ -16,7 +16,13 @@ var (
 
 var Commands = []*cli.Command{
 	cmd.FetchCommand,
+	cmd.ImportCommand,
 	cmd.PriceCommand,
+	cmd.SnapshotCommand,
+	cmd.DiffCommand,
+	cmd.ServeCommand,
+	cmd.ExporterCommand,
+	cmd.EstimateCommand,
 }
 
 func main() {
@@ -34,6 +40,17 @@ func main() {
 			Value:   "mongodb://localhost:27017",
 			Usage:   "Specify a valid MongoDB URI",
 		},
+		&cli.StringFlag{
+			Name:    "store",
+			Aliases: []string{"s"},
+			EnvVars: []string{"APF_STORE"},
+			Usage:   "Specify a store URI (e.g. mongodb://localhost:27017, sqlite:///path/to/apf.db). Overrides --mongo-uri",
+		},
+		&cli.StringFlag{
+			Name:  "output",
+			Value: "table",
+			Usage: "Specify an output format (e.g. table, json, jsonl, csv, yaml, markdown)",
+		},
 	}
 
 	app.Commands = Commands